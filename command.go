@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// runCommand 执行命令行子命令，如：
+//
+//	bookshare import -user 1 [-format csv] [-dry-run] [-merge] books.csv
+//	bookshare import -user 1 -merge "/path/to/Calibre Library"
+func runCommand(args []string) error {
+	switch args[0] {
+	case "import":
+		return runImportCommand(args[1:])
+	}
+	return fmt.Errorf("unknown command: %s", args[0])
+}
+
+// runImportCommand 在前台导入书籍，完成后输出每一行的结果
+func runImportCommand(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	userID := flags.Uint("user", 0, "导入的书籍归属的用户ID")
+	source := flags.String("format", "", "文件格式 (csv, jsonl, calibre)，默认按扩展名判断，目录按 Calibre 书库导入")
+	dryRun := flags.Bool("dry-run", false, "只校验不写入")
+	merge := flags.Bool("merge", false, "与已有书籍重复时合并而不是跳过")
+	flags.Parse(args)
+	if flags.NArg() != 1 || *userID == 0 {
+		return fmt.Errorf("usage: bookshare import -user ID [-format csv|jsonl|calibre] [-dry-run] [-merge] FILE|LIBRARY")
+	}
+
+	path := flags.Arg(0)
+	records, cleanup, err := readImportRecords(path, source)
+	if err != nil {
+		return err
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+
+	job := models.ImportJob{UserID: *userID, Source: *source, FileName: filepath.Base(path), DryRun: *dryRun, Merge: *merge, Status: models.ImportPending}
+	if err := config.DB.Create(&job).Error; err != nil {
+		return err
+	}
+	services.RunImportJob(config.DB, &job, records)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(job.Report); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "import job %d %s: %d rows, %d created, %d duplicates, %d merged, %d failed\n",
+		job.ID, job.Status, job.Total, job.Created, job.Duplicates, job.Merged, job.Failed)
+	return nil
+}
+
+// readImportRecords 解析导入文件；目录直接作为 Calibre 书库读取，书籍文件从原位置保存。
+// Calibre 压缩包解压到临时目录，返回的 cleanup 在导入结束后删除临时目录
+func readImportRecords(path string, source *string) ([]services.ImportRecord, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		*source = services.ImportSourceCalibre
+		records, err := services.ParseCalibreLibrary(path)
+		return records, nil, err
+	}
+	if *source == "" {
+		*source = services.ImportSourceFromName(path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	if *source != services.ImportSourceCalibre {
+		records, err := services.ParseImportFile(*source, file)
+		return records, nil, err
+	}
+
+	root, library, err := services.ExtractCalibreLibrary(file, info.Size())
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(root) }
+	records, err := services.ParseCalibreLibrary(library)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return records, cleanup, nil
+}
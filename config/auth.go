@@ -0,0 +1,27 @@
+package config
+
+import (
+	"crypto/rand"
+	"log"
+	"os"
+	"time"
+)
+
+// AuthSecret 签发和校验访问令牌的密钥，由 InitAuth 初始化
+var AuthSecret []byte
+
+// TokenTTL 访问令牌的有效期
+const TokenTTL = 7 * 24 * time.Hour
+
+func InitAuth() {
+	if secret := os.Getenv("AUTH_SECRET"); secret != "" {
+		AuthSecret = []byte(secret)
+		return
+	}
+	// 未配置时使用随机密钥，重启后之前签发的令牌全部失效
+	AuthSecret = make([]byte, 32)
+	if _, err := rand.Read(AuthSecret); err != nil {
+		log.Fatalf("Failed to generate auth secret: %v", err)
+	}
+	log.Println("AUTH_SECRET is not set, tokens will be invalidated on restart")
+}
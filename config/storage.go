@@ -0,0 +1,25 @@
+package config
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// UploadDir 上传文件的存储根目录
+var UploadDir string
+
+func InitStorage() {
+	UploadDir = os.Getenv("UPLOAD_DIR")
+	if UploadDir == "" {
+		UploadDir = "./uploads" // 默认存储目录
+	}
+
+	// blobs 存放按内容哈希寻址的文件，tmp 存放上传过程中的临时文件
+	for _, dir := range []string{"blobs", "tmp"} {
+		if err := os.MkdirAll(filepath.Join(UploadDir, dir), 0755); err != nil {
+			log.Fatalf("Failed to create upload directory: %v", err)
+		}
+	}
+	log.Println("File storage ready:", UploadDir)
+}
@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"bookshare/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateAuthor godoc
+// @Summary 创建作者
+// @Description 创建作者，可同时提供别名（译名、原名、笔名）
+// @Tags 作者
+// @Accept json
+// @Produce json
+// @Param author body models.Author true "作者信息"
+// @Success 201 {object} models.Author
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 409 {object} gin.H "作者已存在"
+// @Router /authors [post]
+func CreateAuthor(c *gin.Context) {
+	var author models.Author
+	if err := c.ShouldBindJSON(&author); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	author.Name = utils.CleanAuthorName(author.Name)
+	if author.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Author name is required"})
+		return
+	}
+
+	names := []string{author.Name}
+	for i := range author.AlternateNames {
+		author.AlternateNames[i].Name = utils.CleanAuthorName(author.AlternateNames[i].Name)
+		names = append(names, author.AlternateNames[i].Name)
+	}
+	if existing := findAuthorByNames(names, 0); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Author name already exists", "existing_author_id": existing.ID})
+		return
+	}
+
+	if result := config.DB.Create(&author); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
+		return
+	}
+	c.JSON(http.StatusCreated, author)
+}
+
+// GetAuthors godoc
+// @Summary 获取作者列表
+// @Description 按名称或别名搜索作者
+// @Tags 作者
+// @Produce json
+// @Param keyword query string false "名称关键词"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param sort query string false "排序字段 (name, created_at)" default(name)
+// @Param order query string false "排序方向 (asc, desc)" default(asc)
+// @Param cursor query string false "上一页返回的 next_cursor，用于深翻页"
+// @Success 200 {object} gin.H "分页结果 {data, total, page, page_size, next_cursor, links}"
+// @Failure 400 {object} gin.H "分页参数错误"
+// @Router /authors [get]
+func GetAuthors(c *gin.Context) {
+	p, err := parseListPage(c, authorSortColumns, "name", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := config.DB.Model(&models.Author{})
+	if keyword := c.Query("keyword"); keyword != "" {
+		search := "%" + keyword + "%"
+		query = query.Where("name LIKE ? OR id IN (?)", search,
+			config.DB.Model(&models.AuthorAlias{}).Select("author_id").Where("name LIKE ?", search))
+	}
+	var authors []models.Author
+	p.respond(c, query, &authors, "AlternateNames")
+}
+
+// authorSortColumns 作者列表允许排序的字段
+var authorSortColumns = map[string]services.SortColumn{
+	"name":       {Column: "authors.name", Field: "name"},
+	"created_at": {Column: "authors.created_at", Field: "created_at", Time: true},
+}
+
+// GetAuthorByID godoc
+// @Summary 获取作者详情
+// @Tags 作者
+// @Produce json
+// @Param id path int true "作者ID"
+// @Success 200 {object} models.Author
+// @Failure 404 {object} gin.H "作者未找到"
+// @Router /authors/{id} [get]
+func GetAuthorByID(c *gin.Context) {
+	var author models.Author
+	if err := config.DB.Preload("AlternateNames").First(&author, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+	c.JSON(http.StatusOK, author)
+}
+
+// UpdateAuthor godoc
+// @Summary 更新作者信息
+// @Tags 作者
+// @Accept json
+// @Produce json
+// @Param id path int true "作者ID"
+// @Param author body models.Author true "作者信息"
+// @Success 200 {object} models.Author
+// @Failure 404 {object} gin.H "作者未找到"
+// @Failure 409 {object} gin.H "名称已被使用"
+// @Router /authors/{id} [put]
+func UpdateAuthor(c *gin.Context) {
+	var author models.Author
+	if err := config.DB.First(&author, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	var updatedAuthor models.Author
+	if err := c.ShouldBindJSON(&updatedAuthor); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updatedAuthor.AlternateNames = nil // 别名通过单独的接口维护
+	updatedAuthor.Name = utils.CleanAuthorName(updatedAuthor.Name)
+	if updatedAuthor.Name != "" {
+		if existing := findAuthorByNames([]string{updatedAuthor.Name}, author.ID); existing != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Author name already exists", "existing_author_id": existing.ID})
+			return
+		}
+	}
+
+	var changedBooks []uint
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&author).Updates(updatedAuthor).Error; err != nil {
+			return err
+		}
+		if updatedAuthor.Name == "" {
+			return nil
+		}
+		// 书籍的作者署名字符串中包含作者名称，改名后需要重新生成
+		var err error
+		changedBooks, err = services.RefreshAuthorBookCredits(tx, author.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+	invalidateBookDetails(changedBooks)
+	if updatedAuthor.Name != "" {
+		services.SyncAuthorBooksIndex(author.ID)
+		services.SuggestAuthor(config.DB, author.ID)
+	}
+	c.JSON(http.StatusOK, author)
+}
+
+// AddAuthorAlias godoc
+// @Summary 添加作者别名
+// @Description 为作者添加别名，例如为 "鲁迅" 添加 "Lu Xun"
+// @Tags 作者
+// @Accept json
+// @Produce json
+// @Param id path int true "作者ID"
+// @Param alias body models.AuthorAlias true "别名"
+// @Success 201 {object} models.AuthorAlias
+// @Failure 404 {object} gin.H "作者未找到"
+// @Failure 409 {object} gin.H "名称已被使用"
+// @Router /authors/{id}/aliases [post]
+func AddAuthorAlias(c *gin.Context) {
+	var author models.Author
+	if err := config.DB.First(&author, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	var alias models.AuthorAlias
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	alias.Name = utils.CleanAuthorName(alias.Name)
+	if alias.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alias name is required"})
+		return
+	}
+	if existing := findAuthorByNames([]string{alias.Name}, 0); existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name already used by an author", "existing_author_id": existing.ID})
+		return
+	}
+
+	alias.AuthorID = author.ID
+	if result := config.DB.Create(&alias); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add alias"})
+		return
+	}
+	services.SyncAuthorBooksIndex(author.ID)
+	services.SuggestAuthor(config.DB, author.ID)
+	c.JSON(http.StatusCreated, alias)
+}
+
+// GetAuthorBooks godoc
+// @Summary 获取作者的书籍
+// @Description 获取作者参与的所有书籍及其角色，可按角色筛选
+// @Tags 作者
+// @Produce json
+// @Param id path int true "作者ID"
+// @Param role query string false "角色 (author, translator, editor, illustrator)"
+// @Success 200 {array} models.BookAuthor
+// @Router /authors/{id}/books [get]
+func GetAuthorBooks(c *gin.Context) {
+	// 只返回未被删除、请求者可以看到的书籍
+	query := config.DB.Preload("Book").
+		Joins("JOIN books ON books.id = book_authors.book_id AND books.deleted_at IS NULL").
+		Scopes(visibleBooks(c)).
+		Where("book_authors.author_id = ?", c.Param("id"))
+	if role := c.Query("role"); role != "" {
+		query = query.Where("book_authors.role = ?", role)
+	}
+
+	var links []models.BookAuthor
+	if result := query.Order("book_authors.book_id desc").Find(&links); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve author books"})
+		return
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// SetBookContributors godoc
+// @Summary 设置书籍的作者
+// @Description 替换书籍的全部作者、译者、编者、绘者，可以指定 author_id 或 author.name
+// @Tags 作者
+// @Accept json
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param contributors body []models.BookAuthor true "署名列表"
+// @Success 200 {array} models.BookAuthor
+// @Failure 400 {object} gin.H "请求参数错误，或 author_id 对应的作者不存在"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/contributors [put]
+func SetBookContributors(c *gin.Context) {
+	editorID := viewerID(c) // 记录在书籍的修订历史中
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	var contributors []models.BookAuthor
+	if err := c.ShouldBindJSON(&contributors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ValidateContributors(contributors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	before := book
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.SetBookContributors(tx, &book, contributors); err != nil {
+			return err
+		}
+		if err := services.RecordBookRevision(tx, &before, models.BookRevision{BookID: book.ID, UserID: editorID, Action: models.RevisionContributors}); err != nil {
+			return err
+		}
+		return services.ResubmitEditedBook(tx, &book, &before, editorID)
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrAuthorNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set book contributors"})
+		return
+	}
+	config.RDB.Del(config.Ctx, "book:"+c.Param("id"))
+	if book.Status != before.Status {
+		services.InvalidateSeriesBooks(book.SeriesID)
+	}
+	services.SyncSearchIndex(book.ID)
+	services.SuggestBookRelated(config.DB, book.ID)
+	c.JSON(http.StatusOK, book.Contributors)
+}
+
+// MergeAuthors godoc
+// @Summary 合并作者
+// @Description 将重复的作者（如 "Lu Xun" 和 "鲁迅"）合并到目标作者，原名称保留为别名
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "被合并的作者ID"
+// @Param request body object true "{\"into_id\": 1}"
+// @Success 200 {object} models.Author
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "作者未找到"
+// @Router /admin/authors/{id}/merge [post]
+func MergeAuthors(c *gin.Context) {
+	var request struct {
+		IntoID uint `json:"into_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var source, target models.Author
+	if err := config.DB.First(&source, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+	if err := config.DB.First(&target, request.IntoID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target author not found"})
+		return
+	}
+	if source.ID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge an author into itself"})
+		return
+	}
+
+	var changedBooks []uint
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.MergeAuthors(tx, &source, &target); err != nil {
+			return err
+		}
+		var err error
+		changedBooks, err = services.RefreshAuthorBookCredits(tx, target.ID)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge authors"})
+		return
+	}
+	invalidateBookDetails(changedBooks)
+	services.SyncAuthorBooksIndex(target.ID)
+	services.RemoveSuggestion(services.SuggestTypeAuthor, source.ID)
+	services.SuggestAuthor(config.DB, target.ID)
+
+	config.DB.Preload("AlternateNames").First(&target, target.ID)
+	c.JSON(http.StatusOK, target)
+}
+
+// invalidateBookDetails 清除书籍的详情缓存
+func invalidateBookDetails(bookIDs []uint) {
+	for _, id := range bookIDs {
+		config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", id))
+	}
+}
+
+// findAuthorByNames 查找名称或别名与给定名称冲突的作者，excludeID 用于排除自身
+func findAuthorByNames(names []string, excludeID uint) *models.Author {
+	var author models.Author
+	query := config.DB.Where("name IN ? OR id IN (?)", names,
+		config.DB.Model(&models.AuthorAlias{}).Select("author_id").Where("name IN ?", names))
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if query.First(&author).Error != nil {
+		return nil
+	}
+	return &author
+}
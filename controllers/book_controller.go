@@ -3,12 +3,17 @@ package controllers
 import (
 	"bookshare/config"
 	"bookshare/models"
+	"bookshare/services"
+	"bookshare/utils"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // CreateBook godoc
@@ -21,10 +26,77 @@ func CreateBook(c *gin.Context) {
 		return
 	}
 
-	if result := config.DB.Create(&book); result.Error != nil {
+	if err := services.NormalizeBookISBN(&book); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if existing := services.FindISBNConflict(config.DB, book.ISBN13, 0); existing != nil {
+		respondISBNConflict(c, existing)
+		return
+	}
+	contributors := book.Contributors
+	book.Contributors = nil
+	if err := services.ValidateContributors(contributors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := services.ApplyBookCategory(config.DB, &book); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !services.ValidateBookSeries(config.DB, &book) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Series not found"})
+		return
+	}
+	if err := services.NormalizeBookMetadata(&book); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 新书默认为草稿，也可以直接提交审核，审核通过后才对所有人可见
+	switch book.Status {
+	case "":
+		book.Status = models.BookDraft
+	case models.BookDraft, models.BookPending:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status, expected draft or pending"})
+		return
+	}
+	book.StatusReason, book.SubmittedAt = "", nil
+	if book.Visibility != "" && !services.IsValidBookVisibility(book.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidVisibility.Error()})
+		return
+	}
+	if book.Status == models.BookPending {
+		now := time.Now()
+		book.SubmittedAt = &now
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.CreateBook(tx, &book, contributors); err != nil {
+			return err
+		}
+		if err := services.RecordInitialBookStatus(tx, &book); err != nil {
+			return err
+		}
+		return services.RecordBookRevision(tx, nil, models.BookRevision{BookID: book.ID, UserID: book.UserID, Action: models.RevisionCreate})
+	})
+	if err != nil {
+		// 并发创建时可能在唯一索引上冲突
+		if existing := services.FindISBNConflict(config.DB, book.ISBN13, 0); existing != nil {
+			respondISBNConflict(c, existing)
+			return
+		}
+		if errors.Is(err, services.ErrAuthorNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
 		return
 	}
+	services.InvalidateSeriesBooks(book.SeriesID)
+	services.SyncSearchIndex(book.ID)
+	services.SuggestBookRelated(config.DB, book.ID)
+	services.TriggerSavedSearches()
 	c.JSON(http.StatusCreated, book)
 }
 
@@ -43,52 +115,141 @@ func GetBookByID(c *gin.Context) {
 	val, err := config.RDB.Get(config.Ctx, cacheKey).Result()
 	if err == nil {
 		var book models.Book
-		if err := json.Unmarshal([]byte(val), &book); err == nil {
-			c.JSON(http.StatusOK, book)
+		// 加入可见范围之前缓存的详情没有 visibility 字段，按未缓存处理
+		if err := json.Unmarshal([]byte(val), &book); err == nil && book.Visibility != "" {
+			respondVisibleBook(c, &book)
 			return
 		}
 	}
 
 	var book models.Book
-	if err := config.DB.Preload("User").First(&book, bookID).Error; err != nil {
+	if err := config.DB.Preload("User").Preload("Contributors", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Contributors.Author").Preload("Series").First(&book, bookID).Error; err != nil {
+		// 已合并到其他书籍的ID重定向到保留的书籍
+		if targetID, ok := services.ResolveBookRedirect(config.DB, uint(bookID)); ok {
+			c.Redirect(http.StatusMovedPermanently, fmt.Sprintf("/books/%d", targetID))
+			return
+		}
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	book.PrevInSeries, book.NextInSeries = services.SeriesNeighbors(config.DB, &book)
 
 	bookJSON, err := json.Marshal(book)
 	if err == nil {
 		config.RDB.Set(config.Ctx, cacheKey, bookJSON, 1*time.Hour)
 	}
 
+	respondVisibleBook(c, &book)
+}
+
+// respondVisibleBook 返回书籍详情，请求者无权查看时返回 404，不暴露书籍是否存在。
+// 详情缓存为所有人共用，因此每次都要检查
+func respondVisibleBook(c *gin.Context, book *models.Book) {
+	if !bookVisible(c, book) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
 	c.JSON(http.StatusOK, book)
 }
 
+// bookVisible 当前登录的用户能否查看书籍，持有分享链接时通过 ?share_token 传入
+func bookVisible(c *gin.Context, book *models.Book) bool {
+	return services.CanViewBook(config.DB, book, viewerID(c), c.Query("share_token"))
+}
+
+// findVisibleBook 读取路径中的书籍，不存在或请求者无权查看时返回 404
+func findVisibleBook(c *gin.Context) (*models.Book, bool) {
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil || !bookVisible(c, &book) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return nil, false
+	}
+	return &book, true
+}
+
+// findOwnedBook 读取路径中的书籍，并检查当前登录的用户 userID 是否为上传者。
+// 对无权查看的书籍返回 404，不暴露书籍是否存在
+func findOwnedBook(c *gin.Context, userID uint) (*models.Book, bool) {
+	book, ok := findVisibleBook(c)
+	if !ok {
+		return nil, false
+	}
+	if book.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can manage this book"})
+		return nil, false
+	}
+	return book, true
+}
+
+// visibleBooks 按当前登录用户的可见范围筛选列表中的书籍，未登录时只包含公开的书籍
+func visibleBooks(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return services.VisibleBooks(config.DB, viewerID(c))
+}
+
+// viewerID 当前登录的用户ID，由认证中间件根据 Authorization 请求头中的令牌设置，未登录时为 0。
+// 查看和修改权限都只认这里的用户ID，不能使用请求参数中的 user_id，否则任何人都可以冒充上传者或关注者
+func viewerID(c *gin.Context) uint {
+	return c.GetUint("user_id")
+}
+
+// requireViewer 需要登录的接口读取当前用户ID，未登录时返回 401
+func requireViewer(c *gin.Context) (uint, bool) {
+	userID := viewerID(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// requirePathViewer 检查路径 /users/{id}/... 中的用户是当前登录的用户，用户的通知、关注等数据只能由本人查看和修改
+func requirePathViewer(c *gin.Context) (uint, bool) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return 0, false
+	}
+	if c.Param("id") != strconv.FormatUint(uint64(userID), 10) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot access data of another user"})
+		return 0, false
+	}
+	return userID, true
+}
+
 // GetAllBooks godoc
 // @Summary 获取所有书籍
 // ... (完整的 GetAllBooks 函数)
 func GetAllBooks(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
 	keyword := c.Query("keyword")
-	category := c.Query("category")
 
-	offset := (page - 1) * pageSize
-	var books []models.Book
-	query := config.DB.Model(&models.Book{}).Preload("User")
-
-	if keyword != "" {
-		search := "%" + keyword + "%"
-		query = query.Where("title LIKE ? OR author LIKE ? OR description LIKE ?", search, search, search)
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if category != "" {
-		query = query.Where("category = ?", category)
+	p, err := parseListPage(c, services.BookSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	if result := query.Limit(pageSize).Offset(offset).Find(&books); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books"})
-		return
+	var books []models.Book
+	query := config.DB.Model(&models.Book{}).Scopes(visibleBooks(c))
+
+	if keyword != "" {
+		search, err := services.ParseBookQuery(config.DB, keyword)
+		if err != nil {
+			respondQueryError(c, err)
+			return
+		}
+		query = query.Scopes(search.Scope)
+		// 有关键词且未指定排序时按相关度排序
+		if !p.sorted {
+			p.orderBy = search.OrderByRelevance
+		}
 	}
-	c.JSON(http.StatusOK, books)
+	p.respond(c, query.Scopes(filter.Scope(config.DB)), &books, "User")
 }
 
 // UpdateBook godoc
@@ -96,6 +257,7 @@ func GetAllBooks(c *gin.Context) {
 // ... (完整的 UpdateBook 函数)
 func UpdateBook(c *gin.Context) {
 	id := c.Param("id")
+	editorID := viewerID(c)
 	var book models.Book
 	if err := config.DB.First(&book, id).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
@@ -108,10 +270,96 @@ func UpdateBook(c *gin.Context) {
 		return
 	}
 
-	if result := config.DB.Model(&book).Updates(updatedBook); result.Error != nil {
+	// "publish_date": "" 清除出版日期，null 或不提供时保持不变
+	clearPublishDate := updatedBook.PublishDate != nil && updatedBook.PublishDate.IsZero()
+	isbnProvided := updatedBook.ISBN10 != nil || updatedBook.ISBN13 != nil
+	if isbnProvided {
+		if err := services.NormalizeBookISBN(&updatedBook); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if existing := services.FindISBNConflict(config.DB, updatedBook.ISBN13, book.ID); existing != nil {
+			respondISBNConflict(c, existing)
+			return
+		}
+	}
+
+	contributors := updatedBook.Contributors
+	updatedBook.Contributors = nil
+	if err := services.ValidateContributors(contributors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedBook.CategoryID != nil || updatedBook.Category != "" {
+		if err := services.ApplyBookCategory(config.DB, &updatedBook); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !services.ValidateBookSeries(config.DB, &updatedBook) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Series not found"})
+		return
+	}
+	if err := services.NormalizeBookMetadata(&updatedBook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedBook.Visibility != "" && !services.IsValidBookVisibility(updatedBook.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidVisibility.Error()})
+		return
+	}
+
+	before := book
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// 发布状态只能通过审核流程修改
+		if err := tx.Model(&book).Omit("CategoryInfo", "Status", "StatusReason", "SubmittedAt").Updates(updatedBook).Error; err != nil {
+			return err
+		}
+		// 结构体更新会跳过 nil 字段，ISBN 和要清除的出版日期需要显式写入
+		var fields []string
+		if isbnProvided {
+			book.ISBN10, book.ISBN13 = updatedBook.ISBN10, updatedBook.ISBN13
+			fields = append(fields, "ISBN10", "ISBN13")
+		}
+		if clearPublishDate {
+			book.PublishDate = nil
+			fields = append(fields, "PublishDate")
+		}
+		if len(fields) > 0 {
+			if err := tx.Model(&book).Select(fields).Updates(&book).Error; err != nil {
+				return err
+			}
+		}
+		if contributors != nil {
+			if err := services.SetBookContributors(tx, &book, contributors); err != nil {
+				return err
+			}
+		} else if updatedBook.Author != "" && updatedBook.Author != before.Author {
+			if err := services.SyncBookAuthorsFromString(tx, &book); err != nil {
+				return err
+			}
+		}
+		if err := services.RecordBookRevision(tx, &before, models.BookRevision{BookID: book.ID, UserID: editorID, Action: models.RevisionUpdate}); err != nil {
+			return err
+		}
+		return services.ResubmitEditedBook(tx, &book, &before, editorID)
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrAuthorNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
 		return
 	}
+	config.RDB.Del(config.Ctx, "book:"+id)
+	if updatedBook.SeriesID != nil || updatedBook.SeriesIndex != nil || book.Visibility != before.Visibility || book.Status != before.Status {
+		// 卷的顺序、可见范围或发布状态变化会影响同系列其他书籍的上一卷/下一卷
+		services.InvalidateSeriesBooks(before.SeriesID)
+		services.InvalidateSeriesBooks(book.SeriesID)
+	}
+	services.SyncSearchIndex(book.ID)
+	services.SuggestBookRelated(config.DB, book.ID)
 	c.JSON(http.StatusOK, book)
 }
 
@@ -125,7 +373,19 @@ func DeleteBook(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
-	config.DB.Delete(&book)
+
+	// 书籍连同文件、评论和阅读关系移入回收站，可以恢复，彻底删除时才释放文件
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.TrashBook(tx, &book)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete book"})
+		return
+	}
+	config.RDB.Del(config.Ctx, "book:"+id)
+	services.InvalidateSeriesBooks(book.SeriesID)
+	services.InvalidateSearchFacets()
+	services.SuggestBookRelated(config.DB, book.ID)
 	c.Status(http.StatusNoContent)
 }
 
@@ -134,24 +394,318 @@ func DeleteBook(c *gin.Context) {
 // ... (完整的 GetBooksByUser 函数)
 func GetBooksByUser(c *gin.Context) {
 	// Router uses :id as the path parameter
-	userID := c.Param("id")
-	var books []models.Book
-	if result := config.DB.Where("user_id = ?", userID).Find(&books); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user books"})
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	p, err := parseListPage(c, services.BookSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// 默认只列出已发布的书籍。上传者本人（当前登录的用户与路径中的ID相同）可以看到自己任何可见范围的书籍，
+	// 并按状态查看草稿、待审核和被驳回的书籍；其他人只能看到其可见范围内已发布的书籍
+	query := config.DB.Model(&models.Book{}).Where("user_id = ?", userID)
+	if viewerID(c) != uint(userID) {
+		query = query.Scopes(visibleBooks(c))
+	}
+	switch status := c.DefaultQuery("status", models.BookPublished); {
+	case status == "all":
+	case services.IsValidBookStatus(status):
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
 		return
 	}
-	c.JSON(http.StatusOK, books)
+	var books []models.Book
+	p.respond(c, query, &books)
 }
 
 // GetBooksByCategory godoc
 // @Summary 获取指定分类的书籍
+// @Description 分类可以是ID、slug、名称或别名，默认包含所有子分类下的书籍
 // ... (完整的 GetBooksByCategory 函数)
 func GetBooksByCategory(c *gin.Context) {
 	category := c.Param("category")
+	includeDescendants := c.DefaultQuery("include_descendants", "true") == "true"
+	p, err := parseListPage(c, services.BookSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	var books []models.Book
-	if result := config.DB.Where("category = ?", category).Find(&books); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books by category"})
+	p.respond(c, config.DB.Model(&models.Book{}).Scopes(visibleBooks(c), services.ScopeCategoryKey(config.DB, category, includeDescendants)), &books)
+}
+
+// GetBookByISBN godoc
+// @Summary 根据ISBN获取书籍
+// @Description 支持 ISBN-10 和 ISBN-13，允许包含连字符
+// @Tags 书籍
+// @Produce json
+// @Param isbn path string true "ISBN"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} gin.H "ISBN格式错误"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/isbn/{isbn} [get]
+func GetBookByISBN(c *gin.Context) {
+	_, isbn13, err := utils.ParseISBN(c.Param("isbn"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
+
+	var book models.Book
+	if err := config.DB.Preload("User").Where("isbn13 = ?", isbn13).First(&book).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+	respondVisibleBook(c, &book)
+}
+
+// parseBookFilter 从查询参数解析书籍筛选条件
+func parseBookFilter(c *gin.Context) (services.BookFilter, error) {
+	return services.ParseBookFilter(c.Request.URL.Query())
+}
+
+// respondISBNConflict 返回 409，并指向已存在的书籍；书籍在回收站中时提示恢复该书籍
+func respondISBNConflict(c *gin.Context, existing *models.Book) {
+	if existing.DeletedAt.Valid {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "A book with this ISBN is in the trash, restore it instead",
+			"existing_book_id": existing.ID,
+			"in_trash":         true,
+		})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":            "A book with this ISBN already exists",
+		"existing_book_id": existing.ID,
+		"existing_book":    existing,
+		"location":         fmt.Sprintf("/books/%d", existing.ID),
+	})
+}
+
+// GetDuplicateBooks godoc
+// @Summary 获取可能重复的书籍
+// @Description 按 ISBN（ISBN-10 与 ISBN-13 指向同一本书）、正题名和作者的相似度、封面文件内容检测可能重复的书籍，
+// @Description 按可信度从高到低返回书籍对及判断依据（isbn、title_author、cover）。已确认不是重复的书籍对不会出现
+// @Tags 后台管理
+// @Produce json
+// @Param limit query int false "最多返回的数量" default(50)
+// @Success 200 {array} services.DuplicateCandidate
+// @Router /admin/books/duplicates [get]
+func GetDuplicateBooks(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+	candidates, err := services.FindDuplicateBooks(config.DB, min(limit, 500))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to detect duplicate books"})
+		return
+	}
+	c.JSON(http.StatusOK, candidates)
+}
+
+// DismissDuplicateBooks godoc
+// @Summary 标记不是重复的书籍
+// @Description 确认两本书不是重复（例如同名的不同作品），之后的重复检测不再报告这一对
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param request body object true "{\"book_id\": 1, \"other_id\": 2}"
+// @Success 204 "标记成功"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Router /admin/books/duplicates/dismiss [post]
+func DismissDuplicateBooks(c *gin.Context) {
+	var request struct {
+		BookID  uint `json:"book_id" binding:"required"`
+		OtherID uint `json:"other_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.BookID == request.OtherID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "book_id and other_id must be different"})
+		return
+	}
+	if err := services.DismissDuplicate(config.DB, request.BookID, request.OtherID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to dismiss duplicate"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MergeBooks godoc
+// @Summary 合并重复的书籍
+// @Description 在一个事务中将书籍的评论、阅读关系、标签、文件、作者署名和外部标识转移到目标书籍，
+// @Description 目标书籍为空的字段用被合并书籍的信息补充。被合并的书籍删除，其ID之后访问时 301 重定向到目标书籍
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "被合并的书籍ID"
+// @Param request body object true "{\"into_id\": 1}"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /admin/books/{id}/merge [post]
+func MergeBooks(c *gin.Context) {
+	var request struct {
+		IntoID uint `json:"into_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var source, target models.Book
+	if err := config.DB.First(&source, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+	if err := config.DB.First(&target, request.IntoID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target book not found"})
+		return
+	}
+	if source.ID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a book into itself"})
+		return
+	}
+
+	sourceSeriesID := source.SeriesID
+	var orphaned []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		hashes, err := services.MergeBooks(tx, &source, &target)
+		orphaned = hashes
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge books"})
+		return
+	}
+	services.RemoveBlobFiles(orphaned)
+	config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", source.ID), fmt.Sprintf("book:%d", target.ID))
+	services.InvalidateSeriesBooks(sourceSeriesID)
+	services.InvalidateSeriesBooks(target.SeriesID)
+	services.InvalidateSearchFacets()
+	services.SyncSearchIndex(target.ID)
+	services.SuggestBook(config.DB, source.ID)
+	services.SuggestBookRelated(config.DB, target.ID)
+
+	config.DB.Preload("Contributors.Author").Preload("Series").First(&target, target.ID)
+	c.JSON(http.StatusOK, target)
+}
+
+// GetBookRevisions godoc
+// @Summary 书籍的修订历史
+// @Description 按时间倒序返回书籍的创建和每次修改：修改者、时间、操作类型，以及每个变化字段的修改前后的值
+// @Tags 书籍
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的修订列表"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/revisions [get]
+func GetBookRevisions(c *gin.Context) {
+	book, ok := findVisibleBook(c)
+	if !ok {
+		return
+	}
+	p, err := parseListPage(c, revisionSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var revisions []models.BookRevision
+	p.respond(c, config.DB.Model(&models.BookRevision{}).Where("book_id = ?", book.ID), &revisions)
+}
+
+// revisionSortColumns 修订列表允许排序的字段
+var revisionSortColumns = map[string]services.SortColumn{
+	"created_at": {Column: "book_revisions.created_at", Field: "created_at", Time: true},
+}
+
+// RevertBook godoc
+// @Summary 回滚书籍到指定修订
+// @Description 书籍的上传者（当前登录的用户）可以将书籍恢复为某次修订时的内容，回滚本身也记录为一次修订
+// @Tags 书籍
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param revision_id path int true "修订ID"
+// @Success 200 {object} models.Book
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍的上传者"
+// @Failure 404 {object} gin.H "书籍或修订未找到"
+// @Failure 409 {object} gin.H "书籍已与该修订一致，或修订中的 ISBN、系列已不可用"
+// @Router /books/{id}/revisions/{revision_id}/revert [post]
+func RevertBook(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	revertBook(c, userID, false)
+}
+
+// AdminRevertBook godoc
+// @Summary 管理员回滚书籍到指定修订
+// @Description 与 POST /books/{id}/revisions/{revision_id}/revert 相同，但不要求是书籍的上传者
+// @Tags 后台管理
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param revision_id path int true "修订ID"
+// @Success 200 {object} models.Book
+// @Failure 404 {object} gin.H "书籍或修订未找到"
+// @Failure 409 {object} gin.H "书籍已与该修订一致，或修订中的 ISBN、系列已不可用"
+// @Router /admin/books/{id}/revisions/{revision_id}/revert [post]
+func AdminRevertBook(c *gin.Context) {
+	revertBook(c, 0, true)
+}
+
+// revertBook 回滚书籍，asAdmin 为 false 时只允许书籍的上传者操作
+func revertBook(c *gin.Context, userID uint, asAdmin bool) {
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil || !(asAdmin || bookVisible(c, &book)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
-	c.JSON(http.StatusOK, books)
+	if !asAdmin && book.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner or an administrator can revert this book"})
+		return
+	}
+	var revision models.BookRevision
+	if err := config.DB.Where("id = ? AND book_id = ?", c.Param("revision_id"), book.ID).First(&revision).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	before := book
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := services.RevertBook(tx, &book, &revision, userID); err != nil {
+			return err
+		}
+		if asAdmin {
+			return nil
+		}
+		return services.ResubmitEditedBook(tx, &book, &before, userID)
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrNothingToRevert) || errors.Is(err, services.ErrRevertConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revert book"})
+		return
+	}
+	config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", book.ID))
+	services.InvalidateSeriesBooks(before.SeriesID)
+	services.InvalidateSeriesBooks(book.SeriesID)
+	services.InvalidateSearchFacets()
+	services.SyncSearchIndex(book.ID)
+	services.SuggestBookRelated(config.DB, book.ID)
+	c.JSON(http.StatusOK, book)
 }
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChangeBookStatus godoc
+// @Summary 提交或撤回审核
+// @Description 书籍的上传者（当前登录的用户）将草稿、被驳回或被下架的书籍提交审核（submit），或将待审核的书籍撤回为草稿（withdraw）
+// @Tags 书籍
+// @Accept json
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param request body object true "{\"action\": \"submit\"}"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍的上传者，或该操作需要管理员执行"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Failure 409 {object} gin.H "书籍当前的状态不允许该操作"
+// @Router /books/{id}/status [post]
+func ChangeBookStatus(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	var request struct {
+		Action string `json:"action" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	transitionBook(c, request.Action, userID, false, "")
+}
+
+// ModerateBook godoc
+// @Summary 审核书籍
+// @Description 管理员审核通过（approve）或驳回（reject）待审核的书籍，或下架（unpublish）已发布的书籍。
+// @Description 驳回和下架时必须填写原因，审核结果以站内通知发送给上传者
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param request body object true "{\"action\": \"reject\", \"reason\": \"封面与书籍不符\"}"
+// @Success 200 {object} models.Book
+// @Failure 400 {object} gin.H "请求参数错误或缺少原因"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Failure 409 {object} gin.H "书籍当前的状态不允许该操作"
+// @Router /admin/books/{id}/status [post]
+func ModerateBook(c *gin.Context) {
+	var request struct {
+		Action string `json:"action" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	transitionBook(c, request.Action, 0, true, request.Reason)
+}
+
+// GetModerationQueue godoc
+// @Summary 待审核的书籍
+// @Description 默认按提交时间从早到晚列出待审核的书籍，也可以按状态查看被驳回或被下架的书籍
+// @Tags 后台管理
+// @Produce json
+// @Param status query string false "状态 (pending, rejected, unpublished, draft, published)" default(pending)
+// @Param sort query string false "排序字段 (submitted_at, created_at, updated_at)" default(submitted_at)
+// @Param order query string false "排序方式 (asc, desc)" default(asc)
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的书籍列表"
+// @Failure 400 {object} gin.H "状态或分页参数错误"
+// @Router /admin/books/moderation [get]
+func GetModerationQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.BookPending)
+	if !services.IsValidBookStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	p, err := parseListPage(c, moderationSortColumns, "submitted_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var books []models.Book
+	p.respond(c, config.DB.Model(&models.Book{}).Where("books.status = ?", status), &books, "User")
+}
+
+// moderationSortColumns 审核列表允许排序的字段
+var moderationSortColumns = map[string]services.SortColumn{
+	"submitted_at": {Column: "books.submitted_at", Field: "submitted_at", Nullable: true, Time: true},
+	"created_at":   {Column: "books.created_at", Field: "created_at", Time: true},
+	"updated_at":   {Column: "books.updated_at", Field: "updated_at", Time: true},
+}
+
+// GetBookStatusHistory godoc
+// @Summary 书籍的审核记录
+// @Description 按时间倒序返回书籍发布状态的每次变化：操作者、操作、变化前后的状态，以及驳回或下架的原因
+// @Tags 书籍
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的状态变化列表"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/status-history [get]
+func GetBookStatusHistory(c *gin.Context) {
+	book, ok := findVisibleBook(c)
+	if !ok {
+		return
+	}
+	p, err := parseListPage(c, statusChangeSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var changes []models.BookStatusChange
+	p.respond(c, config.DB.Model(&models.BookStatusChange{}).Where("book_id = ?", book.ID), &changes)
+}
+
+// statusChangeSortColumns 审核记录允许排序的字段
+var statusChangeSortColumns = map[string]services.SortColumn{
+	"created_at": {Column: "book_status_changes.created_at", Field: "created_at", Time: true},
+}
+
+// transitionBook 执行发布流程中的操作，并在状态变化后更新书籍在公开列表、检索和补全中的可见性
+func transitionBook(c *gin.Context, action string, userID uint, asModerator bool, reason string) {
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.TransitionBook(tx, &book, action, userID, asModerator, reason)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBookAction), errors.Is(err, services.ErrReasonRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBookActionDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBookStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book status"})
+		}
+		return
+	}
+
+	refreshBookStatus(&book)
+	if book.Status == models.BookPublished {
+		if err := services.NotifyPublishedBook(config.DB, &book); err != nil {
+			log.Printf("Failed to match saved searches for book %d: %v", book.ID, err)
+		}
+		services.TriggerSavedSearches()
+	}
+	c.JSON(http.StatusOK, book)
+}
+
+// refreshBookStatus 书籍的发布状态变化后，清理详情、系列和标签云缓存，并更新搜索索引和补全
+func refreshBookStatus(book *models.Book) {
+	config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", book.ID))
+	services.InvalidateSeriesBooks(book.SeriesID)
+	services.InvalidateTagCloud()
+	services.SyncSearchIndex(book.ID)
+	services.SuggestBookRelated(config.DB, book.ID)
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"bookshare/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetCategoryTree godoc
+// @Summary 获取分类树
+// @Description 获取完整的分类树，可通过 lang 参数指定显示名称的语言
+// @Tags 分类
+// @Produce json
+// @Param lang query string false "显示语言 (e.g., zh, en)"
+// @Success 200 {array} models.Category
+// @Router /categories [get]
+func GetCategoryTree(c *gin.Context) {
+	categories, err := services.LoadCategories(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+		return
+	}
+	c.JSON(http.StatusOK, services.BuildCategoryTree(categories, c.Query("lang")))
+}
+
+// GetCategory godoc
+// @Summary 获取分类详情
+// @Description 根据ID、slug、名称或别名获取分类及其子分类
+// @Tags 分类
+// @Produce json
+// @Param category path string true "分类ID、slug、名称或别名"
+// @Param lang query string false "显示语言 (e.g., zh, en)"
+// @Success 200 {object} models.Category
+// @Failure 404 {object} gin.H "分类未找到"
+// @Router /categories/{category} [get]
+func GetCategory(c *gin.Context) {
+	category, err := services.ResolveCategory(config.DB, c.Param("category"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	categories, err := services.LoadCategories(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+		return
+	}
+	services.BuildCategoryTree(categories, c.Query("lang"))
+	for i := range categories {
+		if categories[i].ID == category.ID {
+			c.JSON(http.StatusOK, categories[i])
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+}
+
+// CreateCategory godoc
+// @Summary 创建分类
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param category body models.Category true "分类信息"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 409 {object} gin.H "slug已存在"
+// @Router /admin/categories [post]
+func CreateCategory(c *gin.Context) {
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category name is required"})
+		return
+	}
+	if category.Slug == "" {
+		category.Slug = services.UniqueCategorySlug(config.DB, utils.Slugify(category.Name), 0)
+	} else {
+		category.Slug = utils.Slugify(category.Slug)
+		if !isCategorySlugAvailable(category.Slug, 0) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Category slug already exists"})
+			return
+		}
+	}
+	if category.ParentID != nil && !categoryExists(*category.ParentID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+		return
+	}
+	category.Children = nil
+
+	if result := config.DB.Create(&category); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary 更新分类
+// @Description 更新分类信息，可修改父分类（不能移动到自身或子孙分类下），parent_id 为 0 表示移动到顶级。
+// @Description 未提供的字段保持不变，sort_order 可以设为 0
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "分类ID"
+// @Param category body models.Category true "分类信息"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "分类未找到"
+// @Router /admin/categories/{id} [put]
+func UpdateCategory(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var updatedCategory struct {
+		models.Category
+		SortOrder *int `json:"sort_order"` // 区分未提供和设为 0
+	}
+	if err := c.ShouldBindJSON(&updatedCategory); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if name := strings.TrimSpace(updatedCategory.Name); name != "" {
+		updates["name"] = name
+	}
+	if updatedCategory.Slug != "" {
+		slug := utils.Slugify(updatedCategory.Slug)
+		if !isCategorySlugAvailable(slug, category.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Category slug already exists"})
+			return
+		}
+		updates["slug"] = slug
+	}
+	if updatedCategory.DisplayNames != nil {
+		// 使用 map 更新时不会经过字段的 JSON 序列化器，需要手动序列化
+		displayNames, _ := json.Marshal(updatedCategory.DisplayNames)
+		updates["display_names"] = string(displayNames)
+	}
+	if updatedCategory.SortOrder != nil {
+		updates["sort_order"] = *updatedCategory.SortOrder
+	}
+	if updatedCategory.ParentID != nil {
+		if *updatedCategory.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			if !categoryExists(*updatedCategory.ParentID) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Parent category not found"})
+				return
+			}
+			if err := services.CheckCategoryParent(config.DB, category.ID, updatedCategory.ParentID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			updates["parent_id"] = *updatedCategory.ParentID
+		}
+	}
+
+	var changedBooks []uint
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&category).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 同步书籍上冗余保存的分类名称
+		if name, ok := updates["name"]; ok && name != category.Name {
+			if err := tx.Model(&models.Book{}).Where("category_id = ?", category.ID).Pluck("id", &changedBooks).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.Book{}).Where("category_id = ?", category.ID).Update("category", name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
+		return
+	}
+	// 书籍详情和检索文档中的分类名称需要更新，分面中的分类名称和 slug 也随之变化
+	invalidateBookDetails(changedBooks)
+	services.SyncSearchIndex(changedBooks...)
+	if updates["name"] != nil || updates["slug"] != nil {
+		services.InvalidateSearchFacets()
+	}
+	config.DB.First(&category, category.ID)
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary 删除分类
+// @Description 删除没有子分类的分类，原属于该分类的书籍保留分类名称但解除关联
+// @Tags 后台管理
+// @Produce json
+// @Param id path int true "分类ID"
+// @Success 204 "删除成功"
+// @Failure 404 {object} gin.H "分类未找到"
+// @Failure 409 {object} gin.H "存在子分类"
+// @Router /admin/categories/{id} [delete]
+func DeleteCategory(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var childCount int64
+	config.DB.Model(&models.Category{}).Where("parent_id = ?", category.ID).Count(&childCount)
+	if childCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category has subcategories, move or delete them first"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Book{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("category_id = ?", category.ID).Delete(&models.CategoryAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&category).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddCategoryAlias godoc
+// @Summary 添加分类别名
+// @Description 让其他写法（如 "Sci-Fi"、"科幻小说"）映射到该分类
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "分类ID"
+// @Param alias body models.CategoryAlias true "别名"
+// @Success 201 {object} models.CategoryAlias
+// @Failure 404 {object} gin.H "分类未找到"
+// @Failure 409 {object} gin.H "别名已被使用"
+// @Router /admin/categories/{id}/aliases [post]
+func AddCategoryAlias(c *gin.Context) {
+	var category models.Category
+	if err := config.DB.First(&category, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var alias models.CategoryAlias
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	alias.Name = strings.TrimSpace(alias.Name)
+	if alias.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Alias name is required"})
+		return
+	}
+	if existing, err := services.ResolveCategory(config.DB, alias.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Name already maps to a category", "existing_category_id": existing.ID})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add alias"})
+		return
+	}
+
+	alias.CategoryID = category.ID
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&alias).Error; err != nil {
+			return err
+		}
+		// 之前无法匹配的书籍现在可以关联到该分类
+		return tx.Model(&models.Book{}).Where("category_id IS NULL AND category = ?", alias.Name).
+			Updates(map[string]interface{}{"category_id": category.ID, "category": category.Name}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add alias"})
+		return
+	}
+	c.JSON(http.StatusCreated, alias)
+}
+
+func isCategorySlugAvailable(slug string, excludeID uint) bool {
+	var count int64
+	config.DB.Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count)
+	return slug != "" && count == 0
+}
+
+func categoryExists(id uint) bool {
+	var count int64
+	config.DB.Model(&models.Category{}).Where("id = ?", id).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CiteBook godoc
+// @Summary 生成书籍引文
+// @Description 返回 BibTeX、RIS、CSL-JSON 或格式化好的 GB/T 7714、APA、MLA 引文；不指定 format 时以 JSON 返回全部格式化样式
+// @Tags 引文
+// @Produce plain
+// @Param id path int true "书籍ID"
+// @Param format query string false "引文格式 (bibtex, ris, csl-json, gbt7714, apa, mla)"
+// @Param share_token query string false "分享链接中的 token"
+// @Success 200 {string} string "引文"
+// @Failure 400 {object} gin.H "不支持的格式"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/cite [get]
+func CiteBook(c *gin.Context) {
+	format := c.Query("format")
+	citationFormat, ok := services.CitationFormats[format]
+	if format != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid citation format, expected one of bibtex, ris, csl-json, gbt7714, apa, mla"})
+		return
+	}
+
+	book, ok := findVisibleBook(c)
+	if !ok {
+		return
+	}
+	books, err := services.LoadCitationBooks(config.DB, []uint{book.ID})
+	if err != nil || len(books) == 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate citation"})
+		return
+	}
+
+	if format == "" {
+		c.JSON(http.StatusOK, gin.H{"book_id": book.ID, "citations": services.FormatCitationStyles(&books[0])})
+		return
+	}
+	citation, err := services.FormatCitations(books, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate citation"})
+		return
+	}
+	c.Data(http.StatusOK, citationFormat.ContentType, []byte(citation))
+}
+
+// GetUserBibliography godoc
+// @Summary 导出用户收藏的参考文献
+// @Description 将用户收藏（collected）的书籍按收藏顺序导出为参考文献文件，APA 和 MLA 按作者排序
+// @Tags 引文
+// @Produce plain
+// @Param id path int true "用户ID"
+// @Param format query string false "引文格式 (bibtex, ris, csl-json, gbt7714, apa, mla)" default(bibtex)
+// @Success 200 {file} file "参考文献文件"
+// @Failure 400 {object} gin.H "不支持的格式"
+// @Router /users/{id}/bibliography [get]
+func GetUserBibliography(c *gin.Context) {
+	format := c.DefaultQuery("format", "bibtex")
+	citationFormat, ok := services.CitationFormats[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid citation format, expected one of bibtex, ris, csl-json, gbt7714, apa, mla"})
+		return
+	}
+
+	var bookIDs []uint
+	if err := config.DB.Model(&models.UserBookRelation{}).
+		Where("user_id = ? AND relation_type = ?", c.Param("id"), "collected").
+		Order("created_at, id").Pluck("book_id", &bookIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collected books"})
+		return
+	}
+	books, err := services.LoadCitationBooks(config.DB, bookIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve collected books"})
+		return
+	}
+	bibliography, err := services.FormatCitations(books, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate bibliography"})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="bibliography.%s"`, citationFormat.Extension))
+	c.Data(http.StatusOK, citationFormat.ContentType, []byte(bibliography))
+}
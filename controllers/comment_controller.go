@@ -3,6 +3,7 @@ package controllers
 import (
 	"bookshare/config"
 	"bookshare/models"
+	"bookshare/services"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -39,21 +40,37 @@ func AddComment(c *gin.Context) {
 // @Tags 评论
 // @Produce json
 // @Param book_id path int true "书籍ID"
-// @Success 200 {array} models.Comment
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param sort query string false "排序字段 (created_at)" default(created_at)
+// @Param order query string false "排序方向 (asc, desc)" default(asc)
+// @Param cursor query string false "上一页返回的 next_cursor，用于深翻页"
+// @Success 200 {object} gin.H "分页结果 {data, total, page, page_size, next_cursor, links}"
+// @Failure 404 {object} gin.H "书籍未找到"
 // @Router /books/{book_id}/comments [get]
 func GetCommentsByBookID(c *gin.Context) {
-	bookID := c.Param("book_id")
-	var comments []models.Comment
-	if result := config.DB.Preload("User").Where("book_id = ?", bookID).Find(&comments); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve comments"})
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("book_id")).Error; err != nil || !bookVisible(c, &book) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+	p, err := parseListPage(c, commentSortColumns, "created_at", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, comments)
+	var comments []models.Comment
+	p.respond(c, config.DB.Model(&models.Comment{}).Where("book_id = ?", book.ID), &comments, "User")
+}
+
+// commentSortColumns 评论列表允许排序的字段
+var commentSortColumns = map[string]services.SortColumn{
+	"created_at": {Column: "comments.created_at", Field: "created_at", Time: true},
 }
 
 // DeleteComment godoc
 // @Summary 删除评论
-// @Description 根据评论ID删除评论
+// @Description 根据评论ID删除评论，删除的评论移入回收站，可以恢复
 // @Tags 评论
 // @Produce json
 // @Param id path int true "评论ID"
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UploadBookFile godoc
+// @Summary 上传书籍文件
+// @Description 书籍的上传者（当前登录的用户）为书籍上传电子书文件，相同内容的文件只存储一份。已发布的书籍上传文件后重新进入待审核状态
+// @Tags 文件
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param file formData file true "书籍文件"
+// @Success 201 {object} models.BookFile
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍的上传者"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Failure 500 {object} gin.H "上传失败"
+// @Router /books/{id}/files [post]
+func UploadBookFile(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	book, ok := findOwnedBook(c, userID)
+	if !ok {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	bookFile := models.BookFile{BookID: book.ID, UserID: userID, FileName: header.Filename}
+	status := book.Status
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		blob, err := services.SaveBlob(tx, file, header.Header.Get("Content-Type"))
+		if err != nil {
+			return err
+		}
+		bookFile.BlobID = blob.ID
+		bookFile.Blob = *blob
+		if err := tx.Omit("Book", "Blob").Create(&bookFile).Error; err != nil {
+			return err
+		}
+		return services.ResubmitBook(tx, book, userID)
+	})
+	if err != nil {
+		// 事务回滚后新写入的文件没有记录引用，需要清理
+		services.RemoveBlobFiles([]string{bookFile.Blob.Hash})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
+		return
+	}
+	if book.Status != status {
+		refreshBookStatus(book)
+	}
+	c.JSON(http.StatusCreated, bookFile)
+}
+
+// GetBookFiles godoc
+// @Summary 获取书籍文件列表
+// @Description 获取指定书籍的所有文件
+// @Tags 文件
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param share_token query string false "分享链接中的 token"
+// @Success 200 {array} models.BookFile
+// @Failure 404 {object} gin.H "书籍未找到或无权查看"
+// @Router /books/{id}/files [get]
+func GetBookFiles(c *gin.Context) {
+	if _, ok := findVisibleBook(c); !ok {
+		return
+	}
+	var files []models.BookFile
+	if result := config.DB.Preload("Blob").Where("book_id = ?", c.Param("id")).Find(&files); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve book files"})
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// DownloadBookFile godoc
+// @Summary 下载书籍文件
+// @Description 下载指定书籍的文件
+// @Tags 文件
+// @Produce octet-stream
+// @Param id path int true "书籍ID"
+// @Param file_id path int true "文件ID"
+// @Param share_token query string false "分享链接中的 token"
+// @Success 200 {file} file
+// @Failure 404 {object} gin.H "文件未找到，或无权查看书籍"
+// @Router /books/{id}/files/{file_id} [get]
+func DownloadBookFile(c *gin.Context) {
+	if _, ok := findVisibleBook(c); !ok {
+		return
+	}
+	var file models.BookFile
+	if err := config.DB.Preload("Blob").Where("book_id = ?", c.Param("id")).First(&file, c.Param("file_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if file.Blob.MimeType != "" {
+		c.Header("Content-Type", file.Blob.MimeType)
+	}
+	c.FileAttachment(services.BlobPath(file.Blob.Hash), file.FileName)
+}
+
+// DeleteBookFile godoc
+// @Summary 删除书籍文件
+// @Description 书籍或文件的上传者（当前登录的用户）删除书籍文件，仅减少共享内容的引用计数，无引用时才删除实际文件
+// @Tags 文件
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param file_id path int true "文件ID"
+// @Success 204 "删除成功"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍或文件的上传者"
+// @Failure 404 {object} gin.H "文件未找到，或无权查看书籍"
+// @Router /books/{id}/files/{file_id} [delete]
+func DeleteBookFile(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	book, ok := findVisibleBook(c)
+	if !ok {
+		return
+	}
+	var file models.BookFile
+	if err := config.DB.Where("book_id = ?", book.ID).First(&file, c.Param("file_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if book.UserID != userID && file.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader of the book or the file can delete it"})
+		return
+	}
+
+	var orphaned string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&file).Error; err != nil {
+			return err
+		}
+		hash, err := services.ReleaseBlob(tx, file.BlobID)
+		orphaned = hash
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete file"})
+		return
+	}
+	services.RemoveBlobFiles([]string{orphaned})
+	c.Status(http.StatusNoContent)
+}
+
+// GetDuplicateFiles godoc
+// @Summary 获取重复上传报告
+// @Description 列出被多本书籍共享的文件内容，以及共享该文件的书籍
+// @Tags 后台管理
+// @Produce json
+// @Success 200 {array} gin.H "重复文件列表"
+// @Router /admin/files/duplicates [get]
+func GetDuplicateFiles(c *gin.Context) {
+	var blobs []models.FileBlob
+	if result := config.DB.Where("ref_count > 1").Order("ref_count desc").Find(&blobs); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve duplicate files"})
+		return
+	}
+
+	blobIDs := make([]uint, 0, len(blobs))
+	for _, blob := range blobs {
+		blobIDs = append(blobIDs, blob.ID)
+	}
+	var files []models.BookFile
+	if len(blobIDs) > 0 {
+		if result := config.DB.Preload("Book").Where("blob_id IN ?", blobIDs).Order("created_at").Find(&files); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve duplicate files"})
+			return
+		}
+	}
+
+	filesByBlob := make(map[uint][]gin.H)
+	for _, file := range files {
+		filesByBlob[file.BlobID] = append(filesByBlob[file.BlobID], gin.H{
+			"book_file_id": file.ID,
+			"book_id":      file.BookID,
+			"title":        file.Book.Title,
+			"user_id":      file.UserID,
+			"file_name":    file.FileName,
+			"uploaded_at":  file.CreatedAt,
+		})
+	}
+
+	report := make([]gin.H, 0, len(blobs))
+	for _, blob := range blobs {
+		report = append(report, gin.H{
+			"blob":  blob,
+			"books": filesByBlob[blob.ID],
+		})
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// FollowUser godoc
+// @Summary 关注用户
+// @Description 关注其他用户后，可以看到对方仅对关注者可见的书籍。重复关注不会报错。路径中的用户必须是当前登录的用户
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param request body object true "要关注的用户 {\"user_id\": 2}"
+// @Success 201 {object} models.Follow
+// @Failure 400 {object} gin.H "请求参数错误，或关注自己"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能替其他用户关注"
+// @Failure 404 {object} gin.H "要关注的用户不存在"
+// @Router /users/{id}/following [post]
+func FollowUser(c *gin.Context) {
+	followerID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	var request struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.UserID == followerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot follow yourself"})
+		return
+	}
+	var followee models.User
+	if err := config.DB.First(&followee, request.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	follow := models.Follow{FollowerID: followerID, FolloweeID: request.UserID}
+	if err := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+	services.InvalidateSearchFacets() // 分面统计按查看者缓存，关注变化后可见的书籍随之变化
+	c.JSON(http.StatusCreated, follow)
+}
+
+// UnfollowUser godoc
+// @Summary 取消关注
+// @Tags 用户
+// @Param id path int true "用户ID"
+// @Param followee_id path int true "已关注的用户ID"
+// @Success 204 "取消成功"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能替其他用户取消关注"
+// @Failure 404 {object} gin.H "没有关注该用户"
+// @Router /users/{id}/following/{followee_id} [delete]
+func UnfollowUser(c *gin.Context) {
+	followerID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	result := config.DB.Where("follower_id = ? AND followee_id = ?", followerID, c.Param("followee_id")).Delete(&models.Follow{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not following this user"})
+		return
+	}
+	services.InvalidateSearchFacets()
+	c.Status(http.StatusNoContent)
+}
+
+// GetFollowing godoc
+// @Summary 关注的用户
+// @Tags 用户
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的关注列表"
+// @Router /users/{id}/following [get]
+func GetFollowing(c *gin.Context) {
+	listFollows(c, "follower_id", "Followee")
+}
+
+// GetFollowers godoc
+// @Summary 关注者
+// @Tags 用户
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的关注者列表"
+// @Router /users/{id}/followers [get]
+func GetFollowers(c *gin.Context) {
+	listFollows(c, "followee_id", "Follower")
+}
+
+// followSortColumns 关注列表允许排序的字段
+var followSortColumns = map[string]services.SortColumn{
+	"created_at": {Column: "follows.created_at", Field: "created_at", Time: true},
+}
+
+// listFollows 分页返回路径中用户的关注关系，column 为该用户所在的列，preload 为关系另一方
+func listFollows(c *gin.Context, column, preload string) {
+	p, err := parseListPage(c, followSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var follows []models.Follow
+	p.respond(c, config.DB.Model(&models.Follow{}).Where(column+" = ?", c.Param("id")), &follows, preload)
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxImportFileSize 导入文件的大小上限
+	maxImportFileSize = 20 << 20
+	// maxCalibreImportSize Calibre 书库压缩包的大小上限，包含书籍文件和封面
+	maxCalibreImportSize = 2 << 30
+)
+
+// ImportBooks godoc
+// @Summary 批量导入书籍
+// @Description 上传 CSV 或 JSON Lines 文件批量创建书籍，在后台执行。每一行按创建书籍的规则校验，
+// @Description 与已有书籍或文件中前面的行 ISBN 相同、或书名和作者相同的记为重复并跳过。
+// @Description 也可以上传 Calibre 书库（整个书库文件夹的 zip 压缩包，或只有 metadata.db），导入作者、系列、标签、
+// @Description 出版社、语言、简介、标识符，以及封面和书籍文件；不存在的系列会自动创建。
+// @Description 开启合并时，重复的书籍不跳过，而是补充已有书籍缺失的字段、标签、标识符和文件。
+// @Description 试运行只校验不写入。返回的任务可通过 GET /books/import/{job_id} 查看进度和逐行结果
+// @Tags 书籍
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV（带表头，列名同书籍字段，另支持 isbn、series、tags）、JSON Lines 文件或 Calibre 书库压缩包"
+// @Param user_id formData int true "导入的书籍归属的用户ID"
+// @Param format formData string false "文件格式 (csv, jsonl, calibre)，默认按扩展名判断"
+// @Param dry_run formData bool false "只校验不写入" default(false)
+// @Param merge formData bool false "与已有书籍重复时合并而不是跳过" default(false)
+// @Success 202 {object} models.ImportJob
+// @Failure 400 {object} gin.H "请求参数错误或文件无法解析"
+// @Router /books/import [post]
+func ImportBooks(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.PostForm("user_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	source := c.PostForm("format")
+	if source == "" {
+		source = services.ImportSourceFromName(header.Filename)
+	}
+	maxSize := int64(maxImportFileSize)
+	if source == services.ImportSourceCalibre {
+		maxSize = maxCalibreImportSize
+	}
+	if header.Size > maxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import file is too large"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+	var (
+		records []services.ImportRecord
+		cleanup func()
+	)
+	if source == services.ImportSourceCalibre {
+		// 书籍文件在后台任务中保存，先解压到临时目录，任务结束后删除
+		root, library, err := services.ExtractCalibreLibrary(file, header.Size)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		cleanup = func() { os.RemoveAll(root) }
+		if records, err = services.ParseCalibreLibrary(library); err != nil {
+			cleanup()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if records, err = services.ParseImportFile(source, file); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job := models.ImportJob{
+		UserID:   uint(userID),
+		Source:   source,
+		FileName: header.Filename,
+		DryRun:   c.PostForm("dry_run") == "true",
+		Merge:    c.PostForm("merge") == "true",
+	}
+	if err := services.StartImportJob(config.DB, &job, records, cleanup); err != nil {
+		if cleanup != nil {
+			cleanup()
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetImportJob godoc
+// @Summary 查看导入任务
+// @Description 返回导入进度，任务结束后包含每一行的结果（created、valid、duplicate、merged、error）
+// @Tags 书籍
+// @Produce json
+// @Param job_id path int true "导入任务ID"
+// @Success 200 {object} models.ImportJob
+// @Failure 404 {object} gin.H "导入任务未找到"
+// @Router /books/import/{job_id} [get]
+func GetImportJob(c *gin.Context) {
+	var job models.ImportJob
+	if err := config.DB.First(&job, c.Param("job_id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"bookshare/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBookMetadataByISBN godoc
+// @Summary 查询ISBN书目信息
+// @Description 从外部书目数据源（Open Library、豆瓣等）查询书籍信息，结果缓存在Redis中
+// @Tags 书籍
+// @Produce json
+// @Param isbn path string true "ISBN"
+// @Success 200 {object} services.BookMetadata
+// @Failure 400 {object} gin.H "ISBN格式错误"
+// @Failure 404 {object} gin.H "未找到书目信息"
+// @Failure 502 {object} gin.H "数据源请求失败"
+// @Router /books/isbn/{isbn}/metadata [get]
+func GetBookMetadataByISBN(c *gin.Context) {
+	_, isbn13, err := utils.ParseISBN(c.Param("isbn"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
+
+	metadata, err := services.LookupBookMetadata(c.Request.Context(), isbn13)
+	if err != nil {
+		respondMetadataError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, metadata)
+}
+
+// FillBookFromISBN godoc
+// @Summary 根据ISBN填充书籍信息
+// @Description 根据ISBN查询书目信息并生成书籍草稿（不保存），客户端确认后提交到 POST /books
+// @Tags 书籍
+// @Accept json
+// @Produce json
+// @Param request body object true "{\"isbn\": \"9787536692930\"}"
+// @Success 200 {object} gin.H "书籍草稿及原始书目信息"
+// @Failure 400 {object} gin.H "ISBN格式错误"
+// @Failure 404 {object} gin.H "未找到书目信息"
+// @Router /books/fill-from-isbn [post]
+func FillBookFromISBN(c *gin.Context) {
+	var request struct {
+		ISBN string `json:"isbn" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	isbn10, isbn13, err := utils.ParseISBN(request.ISBN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
+
+	metadata, err := services.LookupBookMetadata(c.Request.Context(), isbn13)
+	if err != nil {
+		respondMetadataError(c, err)
+		return
+	}
+
+	draft := models.Book{
+		Title:       metadata.Title,
+		Author:      strings.Join(metadata.Authors, " / "), // 与署名的显示格式一致，半角逗号会被当作 "Last, First" 的写法
+		Description: metadata.Description,
+		CoverImage:  metadata.CoverImage,
+		Publisher:   metadata.Publisher,
+		PublishDate: metadata.ParsedPublishDate(),
+		PageCount:   metadata.PageCount,
+		Language:    utils.LanguageFromISBN(isbn13),
+		ISBN13:      &isbn13,
+	}
+	if isbn10 != "" {
+		draft.ISBN10 = &isbn10
+	}
+
+	response := gin.H{"book": draft, "metadata": metadata}
+	// 提示书籍已存在，避免重复创建
+	if existing := services.FindISBNConflict(config.DB, &isbn13, 0); existing != nil {
+		response["existing_book_id"] = existing.ID
+		response["existing_book_in_trash"] = existing.DeletedAt.Valid
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func respondMetadataError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrMetadataNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No metadata found for this ISBN"})
+		return
+	}
+	c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to query metadata provider"})
+}
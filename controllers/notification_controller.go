@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateSavedSearch godoc
+// @Summary 保存搜索
+// @Description 保存搜索语句和筛选条件，之后有匹配的新书上架时发送站内通知，开启 email_digest 时还会汇总到邮件摘要
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param search body models.SavedSearch true "{\"name\": \"新出的中文科幻\", \"query\": \"category:科幻\", \"filters\": {\"language\": \"zh\"}, \"email_digest\": true}"
+// @Success 201 {object} models.SavedSearch
+// @Failure 400 {object} gin.H "搜索语句或筛选条件有误"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/saved-searches [post]
+func CreateSavedSearch(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+
+	var search models.SavedSearch
+	if err := c.ShouldBindJSON(&search); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	search.Name = strings.TrimSpace(search.Name)
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Saved search name is required"})
+		return
+	}
+	if search.Query == "" && len(search.Filters) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A query or at least one filter is required"})
+		return
+	}
+	if _, err := services.CompileSavedSearch(config.DB, &search); err != nil {
+		respondSavedSearchError(c, err)
+		return
+	}
+
+	search.ID = 0
+	search.UserID = userID
+	search.LastBookID = services.LatestBookID(config.DB) // 只通知之后上架的书籍
+	if result := config.DB.Create(&search); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save search"})
+		return
+	}
+	c.JSON(http.StatusCreated, search)
+}
+
+// GetSavedSearches godoc
+// @Summary 获取用户保存的搜索
+// @Tags 通知
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {array} models.SavedSearch
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/saved-searches [get]
+func GetSavedSearches(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	var searches []models.SavedSearch
+	if result := config.DB.Where("user_id = ?", userID).Order("id desc").Find(&searches); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve saved searches"})
+		return
+	}
+	c.JSON(http.StatusOK, searches)
+}
+
+// DeleteSavedSearch godoc
+// @Summary 删除保存的搜索
+// @Tags 通知
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param search_id path int true "保存的搜索ID"
+// @Success 204 "删除成功"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Failure 404 {object} gin.H "保存的搜索未找到"
+// @Router /users/{id}/saved-searches/{search_id} [delete]
+func DeleteSavedSearch(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	result := config.DB.Where("id = ? AND user_id = ?", c.Param("search_id"), userID).Delete(&models.SavedSearch{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete saved search"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetNotifications godoc
+// @Summary 获取用户的通知
+// @Tags 通知
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param unread query bool false "只返回未读通知"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor，用于深翻页"
+// @Success 200 {object} gin.H "分页结果 {data, unread, total, page, page_size, next_cursor, links}，unread 为未读数量"
+// @Failure 400 {object} gin.H "分页参数错误"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/notifications [get]
+func GetNotifications(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	p, err := parseListPage(c, notificationSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var unread int64
+	config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+	p.extra = gin.H{"unread": unread}
+
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.Notification
+	p.respond(c, query, &notifications, "Book")
+}
+
+// notificationSortColumns 通知列表允许排序的字段
+var notificationSortColumns = map[string]services.SortColumn{
+	"created_at": {Column: "notifications.created_at", Field: "created_at", Time: true},
+}
+
+// MarkNotificationRead godoc
+// @Summary 标记通知已读
+// @Tags 通知
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param notification_id path int true "通知ID"
+// @Success 204 "标记成功"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Failure 404 {object} gin.H "通知未找到"
+// @Router /users/{id}/notifications/{notification_id}/read [post]
+func MarkNotificationRead(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	var notification models.Notification
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("notification_id"), userID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		if result := config.DB.Model(&notification).Update("read_at", time.Now()); result.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary 全部标记为已读
+// @Tags 通知
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {object} gin.H "标记的数量"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/notifications/read-all [post]
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	result := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
+
+// respondSavedSearchError 搜索语句错误时指出出错位置，筛选条件错误时返回错误信息
+func respondSavedSearchError(c *gin.Context, err error) {
+	if isQueryError(err) {
+		respondQueryError(c, err)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
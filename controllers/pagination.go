@@ -0,0 +1,196 @@
+package controllers
+
+import (
+	"bookshare/services"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// listPage 列表接口的分页参数：page/pageSize 翻页，或使用上一页返回的 cursor 继续往后翻
+type listPage struct {
+	Page     int
+	PageSize int
+	Sort     string
+	Desc     bool
+	sorted   bool // 请求中明确指定了排序（sort 参数或游标）
+	column   services.SortColumn
+	cursor   *services.PageCursor
+	orderBy  func(*gorm.DB) *gorm.DB // 覆盖字段排序（如按相关度），此时不提供游标
+	extra    gin.H                   // 与分页结构一起返回的其他字段，如标签列表返回的标签
+}
+
+// parseListPage 解析并校验分页参数，sorts 为允许排序的字段
+func parseListPage(c *gin.Context, sorts map[string]services.SortColumn, defaultSort, defaultOrder string) (*listPage, error) {
+	p := &listPage{Page: 1, PageSize: services.DefaultPageSize}
+	if s := c.Query("page"); s != "" {
+		page, err := strconv.Atoi(s)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("invalid page: %s", s)
+		}
+		p.Page = page
+	}
+	if s := c.Query("pageSize"); s != "" {
+		pageSize, err := strconv.Atoi(s)
+		if err != nil || pageSize < 1 {
+			return nil, fmt.Errorf("invalid pageSize: %s", s)
+		}
+		p.PageSize = min(pageSize, services.MaxPageSize)
+	}
+
+	sort, order := c.Query("sort"), c.Query("order")
+	p.sorted = sort != ""
+	if sort == "" {
+		sort = defaultSort
+	}
+	if order == "" {
+		order = defaultOrder
+	}
+	switch strings.ToLower(order) {
+	case "asc":
+	case "desc":
+		p.Desc = true
+	default:
+		return nil, fmt.Errorf("invalid sort order: %s", order)
+	}
+
+	if s := c.Query("cursor"); s != "" {
+		cursor, err := services.DecodeCursor(s)
+		if err != nil {
+			return nil, err
+		}
+		// 游标中已包含排序方式，显式指定的排序必须与之一致
+		if (c.Query("sort") != "" && c.Query("sort") != cursor.Sort) || (c.Query("order") != "" && p.Desc != cursor.Desc) {
+			return nil, errors.New("cursor does not match the requested sort")
+		}
+		p.cursor, p.sorted = cursor, true
+		sort, p.Desc = cursor.Sort, cursor.Desc
+	}
+
+	column, ok := sorts[sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort field: %s", sort)
+	}
+	p.Sort, p.column = sort, column
+	return p, nil
+}
+
+// respond 查询一页数据并返回统一的分页结构：
+// {"data": [...], "total": 100, "page": 1, "page_size": 10, "next_cursor": "...", "links": {"next": "...", "prev": "..."}}
+// query 中只包含筛选条件，preloads 只作用于数据查询，不影响计数
+func (p *listPage) respond(c *gin.Context, query *gorm.DB, dest interface{}, preloads ...string) {
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count records"})
+		return
+	}
+
+	find := query.Session(&gorm.Session{})
+	for _, preload := range preloads {
+		find = find.Preload(preload)
+	}
+	if p.orderBy != nil {
+		find = find.Scopes(p.orderBy)
+	} else {
+		find = find.Scopes(p.column.Order(p.Desc))
+	}
+	if p.cursor != nil {
+		after, err := p.column.After(p.cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		find = find.Scopes(after)
+	} else {
+		find = find.Offset((p.Page - 1) * p.PageSize)
+	}
+
+	// 多取一条用于判断是否还有下一页
+	result := find.Limit(p.PageSize + 1).Find(dest)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve records"})
+		return
+	}
+	rows := reflect.ValueOf(dest).Elem()
+	hasMore := rows.Len() > p.PageSize
+	if hasMore {
+		rows.Set(rows.Slice(0, p.PageSize))
+	}
+	cursor := ""
+	if hasMore {
+		cursor = p.nextCursor(c, result, rows.Index(rows.Len()-1))
+	}
+	c.JSON(http.StatusOK, p.envelope(c, rows.Interface(), total, hasMore, cursor))
+}
+
+// envelope 生成统一的分页结构，用于不能直接由 respond 查询的列表（如全文搜索）。
+// hasMore 表示还有下一页，cursor 为空时不提供游标
+func (p *listPage) envelope(c *gin.Context, data interface{}, total int64, hasMore bool, cursor string) gin.H {
+	response := gin.H{
+		"data":        data,
+		"total":       total,
+		"page_size":   p.PageSize,
+		"next_cursor": nil,
+	}
+	for key, value := range p.extra {
+		response[key] = value
+	}
+	links := gin.H{"next": nil, "prev": nil}
+	if p.cursor == nil {
+		response["page"] = p.Page
+		if p.Page > 1 {
+			links["prev"] = p.link(c, "page", strconv.Itoa(p.Page-1))
+		}
+	}
+	if hasMore {
+		if cursor != "" {
+			response["next_cursor"] = cursor
+			if p.cursor != nil {
+				links["next"] = p.link(c, "cursor", cursor)
+			}
+		}
+		if p.cursor == nil {
+			links["next"] = p.link(c, "page", strconv.Itoa(p.Page+1))
+		}
+	}
+	response["links"] = links
+	return response
+}
+
+// nextCursor 根据本页最后一条记录生成下一页的游标，按相关度等非字段排序时返回空
+func (p *listPage) nextCursor(c *gin.Context, result *gorm.DB, last reflect.Value) string {
+	if p.orderBy != nil || result.Statement.Schema == nil {
+		return ""
+	}
+	sortField := result.Statement.Schema.LookUpField(p.column.Field)
+	idField := result.Statement.Schema.LookUpField("id")
+	if sortField == nil || idField == nil {
+		return ""
+	}
+	value, _ := sortField.ValueOf(c.Request.Context(), last)
+	cursorValue, err := p.column.CursorValue(value)
+	if err != nil {
+		return ""
+	}
+	id, _ := idField.ValueOf(c.Request.Context(), last)
+	idValue, ok := id.(uint)
+	if !ok {
+		return ""
+	}
+	return services.EncodeCursor(services.PageCursor{Sort: p.Sort, Desc: p.Desc, Value: cursorValue, ID: idValue})
+}
+
+// link 在当前请求地址的基础上替换翻页参数
+func (p *listPage) link(c *gin.Context, key, value string) string {
+	values := c.Request.URL.Query()
+	values.Del("page")
+	values.Del("cursor")
+	values.Set(key, value)
+	return c.Request.URL.Path + "?" + values.Encode()
+}
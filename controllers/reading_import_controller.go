@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportReadingHistory godoc
+// @Summary 导入阅读记录
+// @Description 上传 Goodreads（My Books 导出的 CSV）或豆瓣（读书记录导出的 CSV）文件，在后台将想读、在读、读过、评分和日期
+// @Description 导入为用户的阅读记录。依次按原站点的条目编号、ISBN、书名和作者匹配已有书籍；模糊匹配不够可信的条目
+// @Description 保存候选书籍，任务状态为 review，由用户通过 resolve 接口确认。开启 create_missing 时，找不到的书籍会自动创建。
+// @Description 已读的书不会被导入文件中较早的想读、在读状态覆盖
+// @Tags 关系
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param file formData file true "导出的 CSV 文件"
+// @Param source formData string false "来源 (goodreads, douban)，默认按表头判断"
+// @Param create_missing formData bool false "找不到的书籍自动创建" default(false)
+// @Success 202 {object} models.ReadingImport
+// @Failure 400 {object} gin.H "请求参数错误或文件无法解析"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/reading-imports [post]
+func ImportReadingHistory(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	if header.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import file is too large"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	source, entries, err := services.ParseReadingHistory(c.PostForm("source"), file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	job := models.ReadingImport{
+		UserID:        userID,
+		Source:        source,
+		FileName:      header.Filename,
+		CreateMissing: c.PostForm("create_missing") == "true",
+	}
+	if err := services.StartReadingImport(config.DB, &job, entries); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start import"})
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetReadingImport godoc
+// @Summary 查看阅读记录导入任务
+// @Description 返回导入进度和各状态的条目数量，review 表示还有等待确认的条目
+// @Tags 关系
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param import_id path int true "导入任务ID"
+// @Success 200 {object} models.ReadingImport
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Failure 404 {object} gin.H "导入任务未找到"
+// @Router /users/{id}/reading-imports/{import_id} [get]
+func GetReadingImport(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	var job models.ReadingImport
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("import_id"), userID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// GetReadingImportEntries godoc
+// @Summary 阅读记录导入的条目
+// @Description 分页返回导入文件中的条目及匹配结果，等待确认的条目包含候选书籍和可信度
+// @Tags 关系
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param import_id path int true "导入任务ID"
+// @Param status query string false "按状态筛选 (imported, review, unmatched, skipped, error)"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的条目列表"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Failure 404 {object} gin.H "导入任务未找到"
+// @Router /users/{id}/reading-imports/{import_id}/entries [get]
+func GetReadingImportEntries(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	var job models.ReadingImport
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("import_id"), userID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	p, err := parseListPage(c, readingEntrySortColumns, "row", "asc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := config.DB.Model(&models.ReadingImportEntry{}).Where("import_id = ?", job.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var entries []models.ReadingImportEntry
+	p.respond(c, query, &entries)
+}
+
+// readingEntrySortColumns 导入条目列表允许排序的字段
+var readingEntrySortColumns = map[string]services.SortColumn{
+	"row":   {Column: "reading_import_entries.row", Field: "row"},
+	"score": {Column: "reading_import_entries.score", Field: "score"},
+}
+
+// ResolveReadingEntry godoc
+// @Summary 确认阅读记录的匹配
+// @Description 处理等待确认或未匹配的条目：match 使用指定的书籍（通常是候选书籍之一），create 按条目中的书目信息创建书籍，
+// @Description skip 跳过。全部等待确认的条目处理完后任务完成
+// @Tags 关系
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param import_id path int true "导入任务ID"
+// @Param entry_id path int true "条目ID"
+// @Param resolve body object true "{\"action\": \"match\", \"book_id\": 12}"
+// @Success 200 {object} models.ReadingImportEntry
+// @Failure 400 {object} gin.H "请求参数错误或书籍信息无效"
+// @Failure 404 {object} gin.H "导入任务或条目未找到"
+// @Failure 409 {object} gin.H "导入仍在进行或条目已处理"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/reading-imports/{import_id}/entries/{entry_id}/resolve [post]
+func ResolveReadingEntry(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	var job models.ReadingImport
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("import_id"), userID).First(&job).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	if job.Status == models.ImportPending || job.Status == models.ImportRunning {
+		c.JSON(http.StatusConflict, gin.H{"error": "Import is still running"})
+		return
+	}
+	var entry models.ReadingImportEntry
+	if err := config.DB.Where("id = ? AND import_id = ?", c.Param("entry_id"), job.ID).First(&entry).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Entry not found"})
+		return
+	}
+
+	var input struct {
+		Action string `json:"action" binding:"required,oneof=match create skip"`
+		BookID uint   `json:"book_id"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Action == "match" && input.BookID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "book_id is required"})
+		return
+	}
+
+	if err := services.ResolveReadingEntry(config.DB, &job, &entry, input.Action, input.BookID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrReadingEntryResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidReadingBook):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve entry"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
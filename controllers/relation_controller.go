@@ -3,16 +3,17 @@ package controllers
 import (
 	"bookshare/config"
 	"bookshare/models"
+	"bookshare/services"
 	"net/http"
 	//"strconv"
 
 	"github.com/gin-gonic/gin"
-	//"gorm.io/gorm"
+	"gorm.io/gorm"
 )
 
 // AddUserBookRelation godoc
 // @Summary 添加用户书籍关系
-// @Description 收藏或标记书籍已读
+// @Description 收藏、标记书籍已读、在读或想读，已读可以附带评分和读完日期
 // @Tags 关系
 // @Accept json
 // @Produce json
@@ -28,6 +29,14 @@ func AddUserBookRelation(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !models.IsValidRelationType(relation.RelationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid relation type"})
+		return
+	}
+	if relation.Rating != nil && (*relation.Rating < 1 || *relation.Rating > 5) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
+		return
+	}
 
 	// 检查是否已存在相同的关系
 	var existingRelation models.UserBookRelation
@@ -40,52 +49,79 @@ func AddUserBookRelation(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add relation"})
 		return
 	}
+	services.SuggestBook(config.DB, relation.BookID) // 收藏、阅读数量影响补全排序
 	c.JSON(http.StatusCreated, relation)
 }
 
 // GetUserRelations godoc
 // @Summary 获取用户的所有书籍关系
-// @Description 根据用户ID获取其所有收藏或已读的书籍
+// @Description 根据用户ID获取其所有收藏或已读的书籍，只包含当前登录的用户可以看到的书籍
 // @Tags 关系
 // @Produce json
 // @Param user_id path int true "用户ID"
-// @Success 200 {array} models.UserBookRelation
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param sort query string false "排序字段 (created_at)" default(created_at)
+// @Param order query string false "排序方向 (asc, desc)" default(desc)
+// @Param cursor query string false "上一页返回的 next_cursor，用于深翻页"
+// @Success 200 {object} gin.H "分页结果 {data, total, page, page_size, next_cursor, links}"
 // @Router /users/{user_id}/relations [get]
 func GetUserRelations(c *gin.Context) {
 	// Router uses :id as the path parameter
 	userID := c.Param("id")
-	var relations []models.UserBookRelation
-	if result := config.DB.Preload("Book").Preload("User").Where("user_id = ?", userID).Find(&relations); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user relations"})
+	p, err := parseListPage(c, relationSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, relations)
+	var relations []models.UserBookRelation
+	query := config.DB.Model(&models.UserBookRelation{}).Where("user_id = ?", userID).Scopes(visibleRelationBooks(c))
+	p.respond(c, query, &relations, "Book", "User")
 }
 
 // GetUserRelationByType godoc
 // @Summary 获取用户特定类型的书籍关系
-// @Description 根据用户ID和关系类型获取书籍（收藏或已读）
+// @Description 根据用户ID和关系类型获取书籍（收藏或已读），只包含当前登录的用户可以看到的书籍
 // @Tags 关系
 // @Produce json
 // @Param user_id path int true "用户ID"
-// @Param type path string true "关系类型 (e.g., collected, read)"
-// @Success 200 {array} models.UserBookRelation
+// @Param type path string true "关系类型 (collected, read, reading, want_to_read)"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param sort query string false "排序字段 (created_at)" default(created_at)
+// @Param order query string false "排序方向 (asc, desc)" default(desc)
+// @Param cursor query string false "上一页返回的 next_cursor，用于深翻页"
+// @Success 200 {object} gin.H "分页结果 {data, total, page, page_size, next_cursor, links}"
 // @Router /users/{user_id}/relations/{type} [get]
 func GetUserRelationByType(c *gin.Context) {
 	// Router uses :id as the path parameter
 	userID := c.Param("id")
 	relationType := c.Param("type")
-	var relations []models.UserBookRelation
-	if result := config.DB.Preload("Book").Preload("User").Where("user_id = ? AND relation_type = ?", userID, relationType).Find(&relations); result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user relations by type"})
+	p, err := parseListPage(c, relationSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, relations)
+	var relations []models.UserBookRelation
+	query := config.DB.Model(&models.UserBookRelation{}).Where("user_id = ? AND relation_type = ?", userID, relationType).Scopes(visibleRelationBooks(c))
+	p.respond(c, query, &relations, "Book", "User")
+}
+
+// visibleRelationBooks 只保留书籍对当前登录的用户可见的关系，避免通过关系中的书籍泄露不公开或未发布的书籍
+func visibleRelationBooks(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		return query.Where("user_book_relations.book_id IN (?)", config.DB.Model(&models.Book{}).Select("books.id").Scopes(visibleBooks(c)))
+	}
+}
+
+// relationSortColumns 书籍关系列表允许排序的字段
+var relationSortColumns = map[string]services.SortColumn{
+	"created_at": {Column: "user_book_relations.created_at", Field: "created_at", Time: true},
 }
 
 // DeleteUserBookRelation godoc
 // @Summary 删除用户书籍关系
-// @Description 根据关系ID删除用户书籍关系
+// @Description 根据关系ID删除用户书籍关系，删除的关系移入回收站，可以恢复
 // @Tags 关系
 // @Produce json
 // @Param id path int true "关系ID"
@@ -100,5 +136,6 @@ func DeleteUserBookRelation(c *gin.Context) {
 		return
 	}
 	config.DB.Delete(&relation) // 软删除
+	services.SuggestBook(config.DB, relation.BookID)
 	c.Status(http.StatusNoContent)
 }
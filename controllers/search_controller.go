@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/services"
+	"bookshare/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchBooks godoc
+// @Summary 全文搜索书籍
+// @Description 在标题、作者（含别名）、标签和描述中搜索，按字段加权的相关度排序并返回高亮片段；支持与书籍列表相同的筛选参数。
+// @Description 搜索语句支持字段条件（title、author、category、tag、language、publisher、year、pages、format、isbn、series）、
+// @Description 引号短语、"-" 或 NOT 取反、范围（year:>2000、pages:100..300）、OR 以及括号分组，语句有误时返回 400 并指出出错位置。
+// @Description 支持拼音全拼或首字母（如 "santi"、"st"）、简繁体互通（"三體" 可搜到 "三体"），以及拉丁字母单词的拼写纠错。
+// @Description 同时返回分类、作者、语言、标签和出版年代的分面统计，每个分面的数量应用除自身以外的全部筛选条件
+// @Tags 搜索
+// @Produce json
+// @Param q query string true "搜索语句，如 author:\"刘慈欣\" category:科幻 year:>2000 -tag:translated"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param category query string false "分类"
+// @Param tags query string false "标签，逗号分隔"
+// @Param language query string false "语言，逗号分隔"
+// @Param author_id query string false "作者ID，逗号分隔"
+// @Param decade query string false "出版年代，如 1990，逗号分隔"
+// @Param facets query bool false "是否返回分面统计" default(true)
+// @Success 200 {object} gin.H "分页结果 {query, data, total, page, page_size, links, facets}，data 中每一项包含书籍、相关度和高亮片段"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Router /search [get]
+func SearchBooks(c *gin.Context) {
+	keyword := strings.TrimSpace(c.Query("q"))
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+	// 搜索结果只按相关度排序，没有可用作游标的字段，只支持按页码翻页
+	p, err := parseListPage(c, searchSortColumns, "relevance", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if p.cursor != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor is not supported for search"})
+		return
+	}
+
+	filter, err := parseBookFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	viewer := viewerID(c)
+	results, total, err := services.SearchBooks(config.DB, keyword, filter, viewer, p.Page, p.PageSize)
+	if err != nil {
+		respondQueryError(c, err)
+		return
+	}
+	response := p.envelope(c, results, total, int64(p.Page*p.PageSize) < total, "")
+	response["query"] = keyword
+	if c.DefaultQuery("facets", "true") != "false" {
+		facets, err := services.ComputeFacets(config.DB, keyword, filter, viewer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute facets"})
+			return
+		}
+		response["facets"] = facets
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// searchSortColumns 搜索结果的排序方式，只有相关度
+var searchSortColumns = map[string]services.SortColumn{
+	"relevance": {},
+}
+
+// respondQueryError 搜索语句有误时返回 400 并指出出错的位置和词，其他错误返回 500
+func respondQueryError(c *gin.Context, err error) {
+	var queryErr *utils.QueryError
+	if errors.As(err, &queryErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "Invalid search query",
+			"message":  queryErr.Message,
+			"position": queryErr.Position,
+			"token":    queryErr.Token,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+}
+
+func isQueryError(err error) bool {
+	var queryErr *utils.QueryError
+	return errors.As(err, &queryErr)
+}
+
+// SuggestSearch godoc
+// @Summary 搜索自动补全
+// @Description 根据输入的前缀返回书名、作者和标签的补全建议，按热度排序；支持拼音全拼和首字母
+// @Tags 搜索
+// @Produce json
+// @Param q query string true "已输入的文本"
+// @Param limit query int false "限制数量" default(10)
+// @Success 200 {array} services.Suggestion
+// @Router /search/suggest [get]
+func SuggestSearch(c *gin.Context) {
+	limit := toInt(c.DefaultQuery("limit", "10"))
+	if limit <= 0 || limit > 20 {
+		limit = 10
+	}
+	suggestions, err := services.Suggest(c.Query("q"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve suggestions"})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// RebuildSearchIndex godoc
+// @Summary 重建搜索索引
+// @Description 清空并重建全部书籍的全文检索文档及自动补全索引
+// @Tags 后台管理
+// @Produce json
+// @Success 200 {object} gin.H "已索引的书籍数量"
+// @Router /admin/search/reindex [post]
+func RebuildSearchIndex(c *gin.Context) {
+	indexed, err := services.RebuildSearchIndex(config.DB)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild search index"})
+		return
+	}
+	if err := services.RebuildSuggestions(config.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild search suggestions"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"indexed": indexed})
+}
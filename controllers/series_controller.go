@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateSeries godoc
+// @Summary 创建系列
+// @Tags 系列
+// @Accept json
+// @Produce json
+// @Param series body models.Series true "系列信息"
+// @Success 201 {object} models.Series
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Router /series [post]
+func CreateSeries(c *gin.Context) {
+	var series models.Series
+	if err := c.ShouldBindJSON(&series); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	series.Title = strings.TrimSpace(series.Title)
+	if series.Title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Series title is required"})
+		return
+	}
+	series.Books = nil // 书籍通过 series_id 加入系列
+
+	if result := config.DB.Create(&series); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create series"})
+		return
+	}
+	c.JSON(http.StatusCreated, series)
+}
+
+// GetSeriesByID godoc
+// @Summary 获取系列详情
+// @Description 获取系列信息及按卷序号排列的书籍，只包含请求者可以看到的书籍
+// @Tags 系列
+// @Produce json
+// @Param id path int true "系列ID"
+// @Success 200 {object} models.Series
+// @Failure 404 {object} gin.H "系列未找到"
+// @Router /series/{id} [get]
+func GetSeriesByID(c *gin.Context) {
+	var series models.Series
+	if err := config.DB.Preload("Books", func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(visibleBooks(c)).Order("series_index IS NULL, series_index, id")
+	}).First(&series, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+	c.JSON(http.StatusOK, series)
+}
+
+// UpdateSeries godoc
+// @Summary 更新系列信息
+// @Tags 系列
+// @Accept json
+// @Produce json
+// @Param id path int true "系列ID"
+// @Param series body models.Series true "系列信息"
+// @Success 200 {object} models.Series
+// @Failure 404 {object} gin.H "系列未找到"
+// @Router /series/{id} [put]
+func UpdateSeries(c *gin.Context) {
+	var series models.Series
+	if err := config.DB.First(&series, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Series not found"})
+		return
+	}
+
+	var updatedSeries models.Series
+	if err := c.ShouldBindJSON(&updatedSeries); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updatedSeries.Books = nil
+
+	if result := config.DB.Model(&series).Updates(updatedSeries); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update series"})
+		return
+	}
+	services.InvalidateSeriesBooks(&series.ID)
+	c.JSON(http.StatusOK, series)
+}
+
+// GetContinueSeries godoc
+// @Summary 继续阅读系列
+// @Description 根据用户已读的书籍，推荐其读过部分卷的系列中下一本未读的卷
+// @Tags 系列
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {array} services.SeriesSuggestion
+// @Router /users/{id}/series/continue [get]
+func GetContinueSeries(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	suggestions, err := services.ContinueSeries(config.DB, uint(userID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve series suggestions"})
+		return
+	}
+	c.JSON(http.StatusOK, suggestions)
+}
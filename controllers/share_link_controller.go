@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shareLinkResponse 分享链接及可以直接访问的书籍地址
+type shareLinkResponse struct {
+	models.BookShareLink
+	URL string `json:"url"`
+}
+
+func newShareLinkResponse(link models.BookShareLink) shareLinkResponse {
+	return shareLinkResponse{BookShareLink: link, URL: fmt.Sprintf("/books/%d?share_token=%s", link.BookID, link.Token)}
+}
+
+// CreateBookShareLink godoc
+// @Summary 创建分享链接
+// @Description 书籍的上传者为书籍创建分享链接，持有链接的人可以查看书籍详情、引文和文件，不受可见范围限制
+// @Description （书籍仍需已发布）。可以为不同的人创建多个链接，分别撤销
+// @Tags 书籍
+// @Accept json
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param request body object false "{\"expires_in_days\": 7}"
+// @Success 201 {object} gin.H "分享链接，url 为书籍详情的访问地址"
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍的上传者"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/share-links [post]
+func CreateBookShareLink(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	var request struct {
+		ExpiresInDays int `json:"expires_in_days"` // 0 表示不过期
+	}
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must not be negative"})
+		return
+	}
+	book, ok := findOwnedBook(c, userID)
+	if !ok {
+		return
+	}
+
+	var expiresAt *time.Time
+	if request.ExpiresInDays > 0 {
+		t := time.Now().AddDate(0, 0, request.ExpiresInDays)
+		expiresAt = &t
+	}
+	link, err := services.CreateShareLink(config.DB, book, userID, expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+	c.JSON(http.StatusCreated, newShareLinkResponse(*link))
+}
+
+// GetBookShareLinks godoc
+// @Summary 书籍的分享链接
+// @Description 书籍的上传者查看书籍的全部分享链接，包括已过期的
+// @Tags 书籍
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Success 200 {array} object "分享链接列表"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍的上传者"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/share-links [get]
+func GetBookShareLinks(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	book, ok := findOwnedBook(c, userID)
+	if !ok {
+		return
+	}
+	var links []models.BookShareLink
+	if err := config.DB.Where("book_id = ?", book.ID).Order("id desc").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve share links"})
+		return
+	}
+	response := make([]shareLinkResponse, len(links))
+	for i, link := range links {
+		response[i] = newShareLinkResponse(link)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteBookShareLink godoc
+// @Summary 撤销分享链接
+// @Description 书籍的上传者撤销分享链接，持有该链接的人立即失去访问权限
+// @Tags 书籍
+// @Param id path int true "书籍ID"
+// @Param link_id path int true "分享链接ID"
+// @Success 204 "撤销成功"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不是书籍的上传者"
+// @Failure 404 {object} gin.H "书籍或分享链接未找到"
+// @Router /books/{id}/share-links/{link_id} [delete]
+func DeleteBookShareLink(c *gin.Context) {
+	userID, ok := requireViewer(c)
+	if !ok {
+		return
+	}
+	book, ok := findOwnedBook(c, userID)
+	if !ok {
+		return
+	}
+	result := config.DB.Where("id = ? AND book_id = ?", c.Param("link_id"), book.ID).Delete(&models.BookShareLink{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete share link"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,293 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"bookshare/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetBookTags godoc
+// @Summary 获取书籍标签
+// @Tags 标签
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Success 200 {array} models.Tag
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/tags [get]
+func GetBookTags(c *gin.Context) {
+	book, ok := findVisibleBook(c)
+	if !ok {
+		return
+	}
+	var tags []models.Tag
+	if result := config.DB.Joins("JOIN book_tags ON book_tags.tag_id = tags.id").
+		Where("book_tags.book_id = ?", book.ID).Order("book_tags.created_at").Find(&tags); result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve book tags"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// AddBookTags godoc
+// @Summary 为书籍添加标签
+// @Description 标签名称会被规范化（全角转半角、小写、合并空白），同义词会映射到规范标签。已发布的书籍添加了新标签后重新进入待审核状态
+// @Tags 标签
+// @Accept json
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param request body object true "{\"tags\": [\"科幻\", \"硬科幻\"], \"user_id\": 1}"
+// @Success 201 {array} models.Tag
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "书籍未找到"
+// @Router /books/{id}/tags [post]
+func AddBookTags(c *gin.Context) {
+	var book models.Book
+	if err := config.DB.First(&book, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	var request struct {
+		Tags   []string `json:"tags" binding:"required"`
+		UserID uint     `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, name := range request.Tags {
+		if utils.NormalizeTag(name) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name cannot be empty"})
+			return
+		}
+	}
+
+	var tags []models.Tag
+	status := book.Status
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var added int
+		var err error
+		if tags, added, err = services.AddBookTags(tx, book.ID, request.UserID, request.Tags); err != nil || added == 0 {
+			return err
+		}
+		// 已发布的书籍添加了新标签后需要重新审核
+		return services.ResubmitBook(tx, &book, request.UserID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add tags"})
+		return
+	}
+	if book.Status != status {
+		refreshBookStatus(&book)
+	}
+	services.InvalidateTagCloud()
+	services.SyncSearchIndex(book.ID)
+	for _, tag := range tags {
+		services.SuggestTag(config.DB, tag.ID)
+	}
+	c.JSON(http.StatusCreated, tags)
+}
+
+// RemoveBookTag godoc
+// @Summary 移除书籍标签
+// @Tags 标签
+// @Produce json
+// @Param id path int true "书籍ID"
+// @Param tag path string true "标签名称"
+// @Success 204 "删除成功"
+// @Failure 404 {object} gin.H "标签未找到"
+// @Router /books/{id}/tags/{tag} [delete]
+func RemoveBookTag(c *gin.Context) {
+	tag, err := services.FindTag(config.DB, c.Param("tag"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	result := config.DB.Where("book_id = ? AND tag_id = ?", c.Param("id"), tag.ID).Delete(&models.BookTag{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove tag"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found on this book"})
+		return
+	}
+	services.InvalidateTagCloud()
+	if bookID, err := strconv.ParseUint(c.Param("id"), 10, 64); err == nil {
+		services.SyncSearchIndex(uint(bookID))
+	}
+	services.SuggestTag(config.DB, tag.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// GetBooksByTag godoc
+// @Summary 获取标签下的书籍
+// @Description 同义词标签会返回其规范标签下的书籍
+// @Tags 标签
+// @Produce json
+// @Param tag path string true "标签名称"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量，最大 100" default(10)
+// @Param sort query string false "排序字段 (created_at, updated_at, title, publish_date, page_count)" default(created_at)
+// @Param order query string false "排序方向 (asc, desc)" default(desc)
+// @Param cursor query string false "上一页返回的 next_cursor，用于深翻页"
+// @Success 200 {object} gin.H "分页结果 {tag, data, total, page, page_size, next_cursor, links}"
+// @Failure 400 {object} gin.H "分页参数错误"
+// @Failure 404 {object} gin.H "标签未找到"
+// @Router /tags/{tag}/books [get]
+func GetBooksByTag(c *gin.Context) {
+	tag, err := services.FindTag(config.DB, c.Param("tag"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	p, err := parseListPage(c, services.BookSortColumns, "created_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	p.extra = gin.H{"tag": tag}
+
+	var books []models.Book
+	query := config.DB.Model(&models.Book{}).Scopes(visibleBooks(c), services.ScopeTags(config.DB, []string{tag.Name}, true))
+	p.respond(c, query, &books, "User")
+}
+
+// GetTagCloud godoc
+// @Summary 获取标签云
+// @Description 返回使用次数最多的标签及 1~5 级权重，使用Redis缓存
+// @Tags 标签
+// @Produce json
+// @Param limit query int false "限制数量" default(50)
+// @Success 200 {array} services.TagCloudItem
+// @Router /tags/cloud [get]
+func GetTagCloud(c *gin.Context) {
+	items, err := services.TagCloud(toInt(c.DefaultQuery("limit", "50")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tag cloud"})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// MergeTag godoc
+// @Summary 合并标签
+// @Description 将标签合并到目标标签，书籍关联转移到目标标签，原标签成为同义词
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "被合并的标签ID"
+// @Param request body object true "{\"into_id\": 1}"
+// @Success 200 {object} models.Tag
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "标签未找到"
+// @Router /admin/tags/{id}/merge [post]
+func MergeTag(c *gin.Context) {
+	var request struct {
+		IntoID uint `json:"into_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var source, target models.Tag
+	if err := config.DB.First(&source, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	if err := config.DB.First(&target, request.IntoID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target tag not found"})
+		return
+	}
+	if target.CanonicalID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target tag is itself a synonym", "canonical_id": *target.CanonicalID})
+		return
+	}
+	if source.ID == target.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot merge a tag into itself"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.MergeTags(tx, &source, &target)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge tags"})
+		return
+	}
+	services.InvalidateTagCloud()
+	services.SyncTagBooksIndex(target.ID)
+	services.RemoveSuggestion(services.SuggestTypeTag, source.ID)
+	services.SuggestTag(config.DB, target.ID)
+	c.JSON(http.StatusOK, target)
+}
+
+// AddTagSynonym godoc
+// @Summary 添加标签同义词
+// @Description 添加指向该标签的同义词；同义词已作为独立标签存在时会被合并
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param id path int true "标签ID"
+// @Param request body object true "{\"name\": \"sci-fi\"}"
+// @Success 201 {object} models.Tag
+// @Failure 400 {object} gin.H "请求参数错误"
+// @Failure 404 {object} gin.H "标签未找到"
+// @Router /admin/tags/{id}/synonyms [post]
+func AddTagSynonym(c *gin.Context) {
+	var request struct {
+		Name string `json:"name" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := utils.NormalizeTag(request.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag name cannot be empty"})
+		return
+	}
+
+	var target models.Tag
+	if err := config.DB.First(&target, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+	if target.CanonicalID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag is itself a synonym", "canonical_id": *target.CanonicalID})
+		return
+	}
+
+	var synonym models.Tag
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("name = ?", name).First(&synonym).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			synonym = models.Tag{Name: name, DisplayName: utils.TagDisplayName(request.Name), CanonicalID: &target.ID}
+			return tx.Create(&synonym).Error
+		}
+		if err != nil {
+			return err
+		}
+		if synonym.ID == target.ID {
+			return nil
+		}
+		return services.MergeTags(tx, &synonym, &target)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add synonym"})
+		return
+	}
+	services.InvalidateTagCloud()
+	services.SyncTagBooksIndex(target.ID)
+	services.RemoveSuggestion(services.SuggestTypeTag, synonym.ID)
+	services.SuggestTag(config.DB, target.ID)
+	c.JSON(http.StatusCreated, synonym)
+}
@@ -0,0 +1,294 @@
+package controllers
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetUserTrash godoc
+// @Summary 我的回收站
+// @Description 按删除时间倒序列出用户删除的书籍（上传的）、评论或阅读关系。所属书籍也在回收站中的评论和关系
+// @Description 不单独列出，恢复书籍时一并恢复。超过保留期限（默认 30 天）的条目会被彻底删除
+// @Tags 回收站
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param type path string true "条目类型 (books, comments, relations)"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的条目列表"
+// @Failure 400 {object} gin.H "条目类型或分页参数错误"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/trash/{type} [get]
+func GetUserTrash(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	listTrash(c, &userID)
+}
+
+// GetTrash godoc
+// @Summary 回收站
+// @Description 列出全部用户删除的书籍、评论或阅读关系，参数与 GET /users/{id}/trash/{type} 相同
+// @Tags 后台管理
+// @Produce json
+// @Param type path string true "条目类型 (books, comments, relations)"
+// @Param page query int false "页码" default(1)
+// @Param pageSize query int false "每页数量" default(10)
+// @Param cursor query string false "上一页返回的 next_cursor"
+// @Success 200 {object} gin.H "分页的条目列表"
+// @Failure 400 {object} gin.H "条目类型或分页参数错误"
+// @Router /admin/trash/{type} [get]
+func GetTrash(c *gin.Context) {
+	listTrash(c, nil)
+}
+
+// RestoreUserTrashItem godoc
+// @Summary 从回收站恢复
+// @Description 恢复用户删除的条目。恢复书籍时一并恢复与书籍一起删除的文件、评论和阅读关系，并重新建立检索索引；
+// @Description 恢复评论或阅读关系时，所属书籍必须不在回收站中
+// @Tags 回收站
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param type path string true "条目类型 (books, comments, relations)"
+// @Param item_id path int true "条目ID"
+// @Success 200 {object} gin.H "恢复后的条目"
+// @Failure 404 {object} gin.H "回收站中没有该条目"
+// @Failure 409 {object} gin.H "所属书籍也在回收站中"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Router /users/{id}/trash/{type}/{item_id}/restore [post]
+func RestoreUserTrashItem(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	restoreTrashItem(c, &userID)
+}
+
+// RestoreTrashItem godoc
+// @Summary 管理员从回收站恢复
+// @Description 与 POST /users/{id}/trash/{type}/{item_id}/restore 相同，可以恢复任何用户删除的条目
+// @Tags 后台管理
+// @Produce json
+// @Param type path string true "条目类型 (books, comments, relations)"
+// @Param item_id path int true "条目ID"
+// @Success 200 {object} gin.H "恢复后的条目"
+// @Failure 404 {object} gin.H "回收站中没有该条目"
+// @Failure 409 {object} gin.H "所属书籍也在回收站中"
+// @Router /admin/trash/{type}/{item_id}/restore [post]
+func RestoreTrashItem(c *gin.Context) {
+	restoreTrashItem(c, nil)
+}
+
+// PurgeUserTrashItem godoc
+// @Summary 彻底删除
+// @Description 彻底删除回收站中的条目，无法恢复。彻底删除书籍时一并删除其文件、评论、阅读关系、标签和修订历史
+// @Tags 回收站
+// @Param id path int true "用户ID"
+// @Param type path string true "条目类型 (books, comments, relations)"
+// @Param item_id path int true "条目ID"
+// @Success 204 "删除成功"
+// @Failure 401 {object} gin.H "未登录"
+// @Failure 403 {object} gin.H "不能访问其他用户的数据"
+// @Failure 404 {object} gin.H "回收站中没有该条目"
+// @Router /users/{id}/trash/{type}/{item_id} [delete]
+func PurgeUserTrashItem(c *gin.Context) {
+	userID, ok := requirePathViewer(c)
+	if !ok {
+		return
+	}
+	purgeTrashItem(c, &userID)
+}
+
+// PurgeTrashItem godoc
+// @Summary 管理员彻底删除
+// @Description 与 DELETE /users/{id}/trash/{type}/{item_id} 相同，可以删除任何用户的条目
+// @Tags 后台管理
+// @Param type path string true "条目类型 (books, comments, relations)"
+// @Param item_id path int true "条目ID"
+// @Success 204 "删除成功"
+// @Failure 404 {object} gin.H "回收站中没有该条目"
+// @Router /admin/trash/{type}/{item_id} [delete]
+func PurgeTrashItem(c *gin.Context) {
+	purgeTrashItem(c, nil)
+}
+
+// trashQuery 回收站中某类条目的查询，owner 不为空时只包含该用户的条目
+func trashQuery(itemType string, owner *uint) (*gorm.DB, string, bool) {
+	var query *gorm.DB
+	var table string
+	switch itemType {
+	case services.TrashBooks:
+		table = "books"
+		// 合并到其他书籍的书籍不在回收站中显示
+		query = config.DB.Unscoped().Model(&models.Book{}).
+			Where("books.id NOT IN (?)", config.DB.Model(&models.BookRedirect{}).Select("from_id"))
+	case services.TrashComments:
+		table = "comments"
+		query = config.DB.Unscoped().Model(&models.Comment{}).
+			Joins("JOIN books ON books.id = comments.book_id AND books.deleted_at IS NULL")
+	case services.TrashRelations:
+		table = "user_book_relations"
+		query = config.DB.Unscoped().Model(&models.UserBookRelation{}).
+			Joins("JOIN books ON books.id = user_book_relations.book_id AND books.deleted_at IS NULL")
+	default:
+		return nil, "", false
+	}
+	query = query.Where(table + ".deleted_at IS NOT NULL")
+	if owner != nil {
+		query = query.Where(table+".user_id = ?", *owner)
+	}
+	return query, table, true
+}
+
+// listTrash 分页返回回收站中的条目
+func listTrash(c *gin.Context, owner *uint) {
+	itemType := c.Param("type")
+	query, table, ok := trashQuery(itemType, owner)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trash type"})
+		return
+	}
+	sorts := map[string]services.SortColumn{
+		"deleted_at": {Column: table + ".deleted_at", Field: "deleted_at", Time: true},
+	}
+	p, err := parseListPage(c, sorts, "deleted_at", "desc")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	switch itemType {
+	case services.TrashBooks:
+		var books []models.Book
+		p.respond(c, query, &books)
+	case services.TrashComments:
+		var comments []models.Comment
+		p.respond(c, query, &comments, "Book")
+	case services.TrashRelations:
+		var relations []models.UserBookRelation
+		p.respond(c, query, &relations, "Book")
+	}
+}
+
+// restoreTrashItem 恢复回收站中的条目，owner 不为空时只能恢复该用户的条目
+func restoreTrashItem(c *gin.Context, owner *uint) {
+	query, table, ok := trashQuery(c.Param("type"), owner)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trash type"})
+		return
+	}
+	query = query.Where(table+".id = ?", c.Param("item_id"))
+
+	var (
+		item    interface{}
+		restore func(tx *gorm.DB) error
+		after   func()
+	)
+	switch c.Param("type") {
+	case services.TrashBooks:
+		var book models.Book
+		if err := query.First(&book).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+			return
+		}
+		item = &book
+		restore = func(tx *gorm.DB) error { return services.RestoreBook(tx, &book) }
+		after = func() {
+			config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", book.ID))
+			services.InvalidateSeriesBooks(book.SeriesID)
+			services.InvalidateSearchFacets()
+			services.SyncSearchIndex(book.ID)
+			services.SuggestBookRelated(config.DB, book.ID)
+		}
+	case services.TrashComments:
+		var comment models.Comment
+		// 所属书籍在回收站中的评论不在列表中，这里不连接书籍，以便返回 409
+		if err := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", c.Param("item_id")).
+			Scopes(trashOwner(owner)).First(&comment).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+			return
+		}
+		item = &comment
+		restore = func(tx *gorm.DB) error { return services.RestoreComment(tx, &comment) }
+	case services.TrashRelations:
+		var relation models.UserBookRelation
+		if err := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", c.Param("item_id")).
+			Scopes(trashOwner(owner)).First(&relation).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+			return
+		}
+		item = &relation
+		restore = func(tx *gorm.DB) error { return services.RestoreRelation(tx, &relation) }
+		after = func() { services.SuggestBook(config.DB, relation.BookID) }
+	}
+
+	if err := config.DB.Transaction(restore); err != nil {
+		if errors.Is(err, services.ErrBookInTrash) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore item"})
+		return
+	}
+	if after != nil {
+		after()
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// purgeTrashItem 彻底删除回收站中的条目，owner 不为空时只能删除该用户的条目
+func purgeTrashItem(c *gin.Context, owner *uint) {
+	var model interface{}
+	switch c.Param("type") {
+	case services.TrashBooks:
+		model = &models.Book{}
+	case services.TrashComments:
+		model = &models.Comment{}
+	case services.TrashRelations:
+		model = &models.UserBookRelation{}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid trash type"})
+		return
+	}
+	if err := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", c.Param("item_id")).
+		Scopes(trashOwner(owner)).First(model).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in trash"})
+		return
+	}
+
+	var orphaned []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if book, ok := model.(*models.Book); ok {
+			hashes, err := services.PurgeBook(tx, book)
+			orphaned = hashes
+			return err
+		}
+		return tx.Unscoped().Delete(model).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge item"})
+		return
+	}
+	services.RemoveBlobFiles(orphaned)
+	c.Status(http.StatusNoContent)
+}
+
+// trashOwner 只查询该用户的条目，owner 为空（管理员）时不限制
+func trashOwner(owner *uint) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if owner == nil {
+			return query
+		}
+		return query.Where("user_id = ?", *owner)
+	}
+}
@@ -3,7 +3,9 @@ package controllers
 import (
 	"bookshare/config"
 	"bookshare/models"
+	"bookshare/utils"
 	"net/http" 
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
@@ -61,7 +63,9 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "user_id": user.ID})
+	// 之后的请求通过 "Authorization: Bearer <token>" 携带令牌
+	token := utils.SignUserToken(config.AuthSecret, user.ID, time.Now().Add(config.TokenTTL))
+	c.JSON(http.StatusOK, gin.H{"message": "Login successful", "user_id": user.ID, "token": token})
 }
 
 // GetUserProfile ... (完整的 GetUserProfile 函数)
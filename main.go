@@ -2,9 +2,12 @@ package main
 
 import (
 	"bookshare/config"
+	"bookshare/migrations"
 	"bookshare/models"
 	"bookshare/routers"
+	"bookshare/services"
 	"log"
+	"os"
 )
 
 // @title BookShare API
@@ -22,20 +25,78 @@ import (
 // @host localhost:8080
 // @BasePath /
 func main() {
-	config.InitDB()    // 初始化数据库连接
-	config.InitRedis() // 初始化Redis连接
+	config.InitDB()                  // 初始化数据库连接
+	config.InitRedis()               // 初始化Redis连接
+	config.InitStorage()             // 初始化文件存储目录
+	config.InitAuth()                // 初始化访问令牌的密钥
+	services.InitMetadataProviders() // 初始化外部书目数据源
+	services.InitMail()              // 初始化邮件发送
 
 	// 自动迁移模型，创建或更新表结构
-	err := config.DB.AutoMigrate(&models.User{}, &models.Book{}, &models.Comment{}, &models.UserBookRelation{})
+	err := config.DB.AutoMigrate(
+		&models.User{},
+		&models.Book{},
+		&models.Comment{},
+		&models.UserBookRelation{},
+		&models.FileBlob{},
+		&models.BookFile{},
+		&models.BookIdentifier{},
+		&models.Author{},
+		&models.AuthorAlias{},
+		&models.BookAuthor{},
+		&models.Category{},
+		&models.CategoryAlias{},
+		&models.Tag{},
+		&models.BookTag{},
+		&models.Series{},
+		&models.BookSearchDoc{},
+		&models.SearchTerm{},
+		&models.SavedSearch{},
+		&models.Notification{},
+		&models.ImportJob{},
+		&models.ReadingImport{},
+		&models.ReadingImportEntry{},
+		&models.BookRedirect{},
+		&models.BookDuplicateDismissal{},
+		&models.BookRevision{},
+		&models.BookStatusChange{},
+		&models.BookShareLink{},
+		&models.Follow{},
+	)
 	if err != nil {
 		log.Fatalf("Failed to auto migrate database: %v", err)
 	}
+	// 数据迁移，例如将旧的作者字符串拆分为作者实体
+	if err := migrations.Run(config.DB); err != nil {
+		log.Fatalf("Failed to migrate data: %v", err)
+	}
 	log.Println("Database migration completed!")
 
+	// 命令行子命令，如 bookshare import，执行完即退出
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := services.EnsureSuggestions(config.DB); err != nil {
+		log.Printf("Failed to build search suggestions: %v", err)
+	}
+
+	if err := services.FailInterruptedImports(config.DB); err != nil {
+		log.Printf("Failed to update interrupted import jobs: %v", err)
+	}
+	if err := services.FailInterruptedReadingImports(config.DB); err != nil {
+		log.Printf("Failed to update interrupted reading imports: %v", err)
+	}
+	services.StartSavedSearchWorker() // 启动保存的搜索匹配及邮件摘要任务
+	services.StartTrashPurgeWorker()  // 定期彻底删除回收站中过期的条目
+
 	r := routers.InitRouter() // 初始化路由
 
 	log.Println("Gin server started on :8080")
 	if err := r.Run(":8080"); err != nil {
 		log.Fatalf("Failed to start Gin server: %v", err)
 	}
-}
\ No newline at end of file
+}
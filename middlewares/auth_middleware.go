@@ -2,13 +2,35 @@
 package middlewares
 
 import (
+	"bookshare/config"
+	"bookshare/utils"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
+// AuthMiddleware 校验 "Authorization: Bearer <token>" 中由 /login 签发的令牌，并将用户ID（uint）设置为 user_id。
+// 未携带令牌的请求按未登录处理，由各接口决定是否需要登录；令牌无效或已过期时返回 401
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 实际的认证逻辑在此处，目前为了演示，直接放行
-		// c.Set("user_id", uint(1)) // 模拟设置用户ID，以便后续控制器使用
+		header := c.GetHeader("Authorization")
+		if header == "" {
+			c.Next()
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header"})
+			return
+		}
+		userID, err := utils.ParseUserToken(config.AuthSecret, strings.TrimSpace(token), time.Now())
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set("user_id", userID)
 		c.Next()
 	}
 }
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"bookshare/models"
+	"bookshare/services"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// SplitBookAuthors 将尚未建立作者关联的书籍的作者字符串拆分为作者实体
+func SplitBookAuthors(db *gorm.DB) error {
+	var books []models.Book
+	migrated := 0
+	result := db.Where("author <> '' AND id NOT IN (?)", db.Model(&models.BookAuthor{}).Select("book_id")).
+		FindInBatches(&books, 200, func(_ *gorm.DB, _ int) error {
+			for i := range books {
+				err := db.Transaction(func(tx *gorm.DB) error {
+					return services.SyncBookAuthorsFromString(tx, &books[i])
+				})
+				if err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if migrated > 0 {
+		log.Printf("Split author strings of %d books into authors", migrated)
+	}
+	return nil
+}
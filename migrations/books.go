@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"bookshare/models"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// PublishLegacyBooks 发布流程加入之前上传的书籍在添加 status 列时取默认值 draft，将其改为已发布。
+// 之后新建的书籍都记录了初始状态，以此区分，因此可以在每次启动时执行
+func PublishLegacyBooks(db *gorm.DB) error {
+	result := db.Unscoped().Model(&models.Book{}).
+		Where("status = ? AND id NOT IN (?)", models.BookDraft, db.Model(&models.BookStatusChange{}).Select("book_id")).
+		Update("status", models.BookPublished)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Published %d books uploaded before the publication workflow", result.RowsAffected)
+	}
+	return nil
+}
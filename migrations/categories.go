@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"bookshare/models"
+	"bookshare/services"
+	"bookshare/utils"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// defaultCategory 初始分类树的节点
+type defaultCategory struct {
+	Slug     string
+	Name     string
+	English  string
+	Aliases  []string
+	Children []defaultCategory
+}
+
+var defaultCategories = []defaultCategory{
+	{Slug: "literature", Name: "文学", English: "Literature", Children: []defaultCategory{
+		{Slug: "fiction", Name: "小说", English: "Fiction", Aliases: []string{"长篇小说", "Novel"}, Children: []defaultCategory{
+			{Slug: "science-fiction", Name: "科幻", English: "Science Fiction", Aliases: []string{"科幻小说", "Sci-Fi", "SciFi", "SF"}},
+			{Slug: "fantasy", Name: "奇幻", English: "Fantasy", Aliases: []string{"奇幻小说", "魔幻"}},
+			{Slug: "mystery", Name: "推理", English: "Mystery", Aliases: []string{"推理小说", "侦探小说", "悬疑", "Detective"}},
+			{Slug: "wuxia", Name: "武侠", English: "Wuxia", Aliases: []string{"武侠小说"}},
+		}},
+		{Slug: "poetry", Name: "诗歌", English: "Poetry", Aliases: []string{"诗集"}},
+		{Slug: "essays", Name: "散文", English: "Essays", Aliases: []string{"随笔"}},
+	}},
+	{Slug: "history", Name: "历史", English: "History"},
+	{Slug: "philosophy", Name: "哲学", English: "Philosophy"},
+	{Slug: "computer-science", Name: "计算机", English: "Computer Science", Aliases: []string{"编程", "Programming", "IT"}},
+	{Slug: "business", Name: "经济管理", English: "Business", Aliases: []string{"经济", "管理", "Economics"}},
+	{Slug: "art", Name: "艺术", English: "Art"},
+	{Slug: "children", Name: "童书", English: "Children", Aliases: []string{"儿童文学", "绘本"}},
+}
+
+// MapLegacyCategories 首次运行时创建初始分类树，并把书籍的分类字符串映射到分类。
+// 无法匹配的分类字符串会创建为新的顶级分类，管理员可以之后再调整或合并。
+func MapLegacyCategories(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Category{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return seedCategories(tx, defaultCategories, nil)
+		}); err != nil {
+			return err
+		}
+	}
+
+	var names []string
+	if err := db.Model(&models.Book{}).Where("category_id IS NULL AND category <> ''").
+		Distinct().Pluck("category", &names).Error; err != nil {
+		return err
+	}
+	for _, name := range names {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			category, err := services.ResolveCategory(tx, name)
+			if err != nil {
+				category = &models.Category{
+					Name: name,
+					Slug: services.UniqueCategorySlug(tx, utils.Slugify(name), 0),
+				}
+				if err := tx.Create(category).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Model(&models.Book{}).Where("category_id IS NULL AND category = ?", name).
+				Update("category_id", category.ID).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if len(names) > 0 {
+		log.Printf("Mapped %d legacy category names to categories", len(names))
+	}
+	return nil
+}
+
+func seedCategories(tx *gorm.DB, nodes []defaultCategory, parentID *uint) error {
+	for i, node := range nodes {
+		category := models.Category{
+			ParentID:     parentID,
+			Slug:         node.Slug,
+			Name:         node.Name,
+			DisplayNames: map[string]string{"zh": node.Name, "en": node.English},
+			SortOrder:    i,
+		}
+		for _, alias := range node.Aliases {
+			category.Aliases = append(category.Aliases, models.CategoryAlias{Name: alias})
+		}
+		if err := tx.Create(&category).Error; err != nil {
+			return err
+		}
+		if err := seedCategories(tx, node.Children, &category.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// Run 在表结构迁移之后执行数据迁移。每个迁移都是幂等的，可以在每次启动时执行。
+func Run(db *gorm.DB) error {
+	for _, migrate := range []func(*gorm.DB) error{
+		SplitBookAuthors,
+		MapLegacyCategories,
+		PublishLegacyBooks,
+		BuildSearchIndex,
+	} {
+		if err := migrate(db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"bookshare/services"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// BuildSearchIndex 为尚无检索文档的书籍建立全文索引，首次部署时会为全部书籍建立索引
+func BuildSearchIndex(db *gorm.DB) error {
+	indexed, err := services.IndexMissingBooks(db)
+	if err != nil {
+		return err
+	}
+	if indexed > 0 {
+		log.Printf("Indexed %d books for full-text search", indexed)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Author struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Name           string         `json:"name" gorm:"uniqueIndex;not null;type:varchar(100)"`
+	AlternateNames []AuthorAlias  `json:"alternate_names" gorm:"foreignKey:AuthorID"` // 译名、原名、笔名等
+	Bio            string         `json:"bio" gorm:"type:text"`
+	Photo          string         `json:"photo" gorm:"type:varchar(255)"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
+
+// AuthorAlias 作者的其他名称，例如 "Lu Xun" 是 "鲁迅" 的别名
+type AuthorAlias struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AuthorID  uint      `json:"author_id" gorm:"not null;index"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null;type:varchar(100)"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
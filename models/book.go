@@ -7,16 +7,36 @@ import (
 )
 
 type Book struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Title       string         `json:"title" gorm:"not null;type:varchar(255)"`
-	Author      string         `json:"author" gorm:"not null;type:varchar(100)"`
-	Description string         `json:"description" gorm:"type:text"`
-	CoverImage  string         `json:"cover_image" gorm:"type:varchar(255)"`
-	Category    string         `json:"category" gorm:"type:varchar(50)"`
-	UserID      uint           `json:"user_id" gorm:"not null"` // 上传书籍的用户ID
-	User        User           `json:"user"`                     // 关联用户
-	Comments    []Comment      `json:"comments" gorm:"foreignKey:BookID"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-}
\ No newline at end of file
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Title        string         `json:"title" gorm:"not null;type:varchar(255)"`
+	Author       string         `json:"author" gorm:"not null;type:varchar(100)"` // 作者署名，结构化的作者信息见 Contributors
+	Description  string         `json:"description" gorm:"type:text"`
+	CoverImage   string         `json:"cover_image" gorm:"type:varchar(255)"`
+	Category     string         `json:"category" gorm:"type:varchar(50)"` // 分类名称，随 CategoryID 同步
+	CategoryID   *uint          `json:"category_id" gorm:"index"`
+	CategoryInfo *Category      `json:"category_info,omitempty" gorm:"foreignKey:CategoryID"`
+	Publisher    string         `json:"publisher" gorm:"type:varchar(100);index"`
+	PublishDate  *Date          `json:"publish_date" gorm:"type:date;index;index:idx_book_language_date,priority:2"` // 只知道年份时取当年1月1日
+	PageCount    int            `json:"page_count" gorm:"not null;default:0;index"`                                  // 0 表示未知
+	Language     string         `json:"language" gorm:"type:varchar(10);index:idx_book_language_date,priority:1"`    // ISO 639-1 代码，如 zh、en
+	Format       string         `json:"format" gorm:"type:varchar(20);index"`                                        // paperback, hardcover, ebook, audiobook
+	Edition      string         `json:"edition" gorm:"type:varchar(50)"`
+	ISBN10       *string        `json:"isbn10" gorm:"column:isbn10;type:varchar(10);uniqueIndex"` // 979 前缀的书籍没有 ISBN-10
+	ISBN13       *string        `json:"isbn13" gorm:"column:isbn13;type:varchar(13);uniqueIndex"`
+	SeriesID     *uint          `json:"series_id" gorm:"index:idx_book_series"`
+	SeriesIndex  *float64       `json:"series_index" gorm:"type:decimal(8,2);index:idx_book_series"` // 系列中的卷序号，外传等可以使用小数，如 1.5
+	Series       *Series        `json:"series,omitempty"`
+	PrevInSeries *Book          `json:"prev_in_series,omitempty" gorm:"-"`                                // 系列中的上一卷，仅在书籍详情中返回
+	NextInSeries *Book          `json:"next_in_series,omitempty" gorm:"-"`                                // 系列中的下一卷，仅在书籍详情中返回
+	UserID       uint           `json:"user_id" gorm:"not null"`                                          // 上传书籍的用户ID
+	Status       string         `json:"status" gorm:"type:varchar(20);not null;default:draft;index"`      // 发布状态，见 BookDraft 等常量，批量导入的书籍也需要审核
+	StatusReason string         `json:"status_reason" gorm:"type:varchar(500)"`                           // 驳回或下架的原因
+	SubmittedAt  *time.Time     `json:"submitted_at" gorm:"index"`                                        // 最近一次提交审核的时间
+	Visibility   string         `json:"visibility" gorm:"type:varchar(20);not null;default:public;index"` // 可见范围，见 VisibilityPublic 等常量
+	User         User           `json:"user"`                                                             // 关联用户
+	Comments     []Comment      `json:"comments" gorm:"foreignKey:BookID"`
+	Contributors []BookAuthor   `json:"contributors,omitempty" gorm:"foreignKey:BookID"` // 作者、译者、编者、绘者
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
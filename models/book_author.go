@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// 作者在书籍中承担的角色
+const (
+	AuthorRoleAuthor      = "author"
+	AuthorRoleTranslator  = "translator"
+	AuthorRoleEditor      = "editor"
+	AuthorRoleIllustrator = "illustrator"
+)
+
+// BookAuthor 书籍与作者的多对多关联，同一作者可以在一本书中承担不同角色
+type BookAuthor struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	BookID    uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_book_author_role"`
+	Book      *Book     `json:"book,omitempty"`
+	AuthorID  uint      `json:"author_id" gorm:"not null;uniqueIndex:idx_book_author_role;index"`
+	Author    Author    `json:"author"`
+	Role      string    `json:"role" gorm:"not null;type:varchar(20);uniqueIndex:idx_book_author_role"` // author, translator, editor, illustrator
+	Position  int       `json:"position" gorm:"not null;default:0"`                                     // 署名顺序
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsValidAuthorRole 检查角色是否受支持
+func IsValidAuthorRole(role string) bool {
+	switch role {
+	case AuthorRoleAuthor, AuthorRoleTranslator, AuthorRoleEditor, AuthorRoleIllustrator:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BookFile 书籍的附件（电子书文件），实际内容保存在共享的 FileBlob 中
+type BookFile struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	BookID    uint           `json:"book_id" gorm:"not null;index"`
+	Book      Book           `json:"book"`
+	BlobID    uint           `json:"blob_id" gorm:"not null;index"`
+	Blob      FileBlob       `json:"blob"`
+	UserID    uint           `json:"user_id" gorm:"not null"` // 上传文件的用户ID
+	FileName  string         `json:"file_name" gorm:"not null;type:varchar(255)"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
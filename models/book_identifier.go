@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// BookIdentifier 书籍在外部系统中的标识，例如 goodreads、douban、amazon 的编号或 Calibre 书库的 uuid。
+// ISBN 保存在书籍本身，不在此表中
+type BookIdentifier struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	BookID    uint      `json:"book_id" gorm:"not null;index"`
+	Scheme    string    `json:"scheme" gorm:"not null;type:varchar(30);uniqueIndex:idx_identifier_scheme_value"` // 小写，如 goodreads
+	Value     string    `json:"value" gorm:"not null;type:varchar(191);uniqueIndex:idx_identifier_scheme_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// BookRedirect 合并重复书籍后，被合并书籍的ID指向保留的书籍，旧链接据此重定向
+type BookRedirect struct {
+	FromID    uint      `json:"from_id" gorm:"primaryKey;autoIncrement:false"` // 被合并的书籍ID
+	ToID      uint      `json:"to_id" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BookDuplicateDismissal 管理员确认不是重复的两本书，重复检测不再报告。BookID 总是小于 OtherID
+type BookDuplicateDismissal struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	BookID    uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_duplicate_dismissal"`
+	OtherID   uint      `json:"other_id" gorm:"not null;uniqueIndex:idx_duplicate_dismissal;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+// 书籍修订的操作类型
+const (
+	RevisionBaseline     = "baseline" // 开始记录修订前书籍已有的内容
+	RevisionCreate       = "create"
+	RevisionUpdate       = "update"
+	RevisionContributors = "contributors" // 修改作者署名
+	RevisionImport       = "import"       // 批量导入时合并到已有书籍
+	RevisionMerge        = "merge"        // 合并重复书籍
+	RevisionRevert       = "revert"
+)
+
+// BookRevision 书籍的一次创建或修改，保存修改后的字段快照和变化的字段
+type BookRevision struct {
+	ID         uint                   `json:"id" gorm:"primaryKey"`
+	BookID     uint                   `json:"book_id" gorm:"not null;index:idx_revision_book_created"`
+	UserID     uint                   `json:"user_id" gorm:"not null;default:0"` // 修改者，0 表示系统或管理员操作
+	Action     string                 `json:"action" gorm:"not null;type:varchar(20)"`
+	Changes    []BookFieldChange      `json:"changes" gorm:"serializer:json;type:text"`
+	Snapshot   map[string]interface{} `json:"snapshot" gorm:"serializer:json;type:text"` // 修改后记录修订的全部字段，回滚时恢复为该内容
+	RevertedTo *uint                  `json:"reverted_to,omitempty"`                     // 回滚时恢复到的修订ID
+	CreatedAt  time.Time              `json:"created_at" gorm:"index:idx_revision_book_created"`
+}
+
+// BookFieldChange 一个字段的修改，字段名与书籍的 JSON 字段一致
+type BookFieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// BookSearchDoc 书籍的全文检索文档，每个字段使用 ngram 分词的 FULLTEXT 索引，
+// 以便分别计算各字段的相关度；ft_book_search 覆盖全部字段，用于筛选匹配的书籍
+type BookSearchDoc struct {
+	BookID      uint      `json:"book_id" gorm:"primaryKey;autoIncrement:false"`
+	Title       string    `json:"title" gorm:"type:varchar(255);index:ft_book_search_title,class:FULLTEXT,option:WITH PARSER ngram;index:ft_book_search,class:FULLTEXT,option:WITH PARSER ngram"`
+	Authors     string    `json:"authors" gorm:"type:text;index:ft_book_search_authors,class:FULLTEXT,option:WITH PARSER ngram;index:ft_book_search,class:FULLTEXT,option:WITH PARSER ngram"` // 作者署名、作者名称及别名
+	Description string    `json:"description" gorm:"type:text;index:ft_book_search_description,class:FULLTEXT,option:WITH PARSER ngram;index:ft_book_search,class:FULLTEXT,option:WITH PARSER ngram"`
+	Tags        string    `json:"tags" gorm:"type:text;index:ft_book_search_tags,class:FULLTEXT,option:WITH PARSER ngram;index:ft_book_search,class:FULLTEXT,option:WITH PARSER ngram"`
+	Pinyin      string    `json:"pinyin" gorm:"type:text;index:ft_book_search_pinyin,class:FULLTEXT,option:WITH PARSER ngram"` // 书名和作者名的全拼及首字母
+	UpdatedAt   time.Time `json:"updated_at"`
+}
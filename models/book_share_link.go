@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// 书籍的可见范围
+const (
+	VisibilityPublic    = "public"    // 所有人可见
+	VisibilityFollowers = "followers" // 仅上传者的关注者可见
+	VisibilityPrivate   = "private"   // 仅上传者可见，可以通过分享链接分享给指定的人
+	VisibilityLink      = "link"      // 不出现在任何列表中，持有分享链接的人可见
+)
+
+// BookShareLink 书籍的分享链接，持有链接的人可以查看书籍及其文件，不受可见范围限制
+type BookShareLink struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	BookID    uint       `json:"book_id" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null"` // 创建链接的用户
+	Token     string     `json:"token" gorm:"not null;type:varchar(64);uniqueIndex"`
+	ExpiresAt *time.Time `json:"expires_at"` // 为空表示不过期
+	CreatedAt time.Time  `json:"created_at"`
+}
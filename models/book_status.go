@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// 书籍的发布状态：草稿 → 待审核 → 已发布 / 已驳回，已发布的书籍可以被下架。
+// 只有已发布的书籍出现在公开的列表、搜索和推荐中
+const (
+	BookDraft       = "draft"
+	BookPending     = "pending"
+	BookPublished   = "published"
+	BookRejected    = "rejected"
+	BookUnpublished = "unpublished"
+)
+
+// BookStatusChange 书籍发布状态的一次变化
+type BookStatusChange struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	BookID     uint      `json:"book_id" gorm:"not null;index:idx_status_change_book_created"`
+	UserID     uint      `json:"user_id" gorm:"not null;default:0"` // 操作者，0 表示管理员
+	Action     string    `json:"action" gorm:"not null;type:varchar(20)"`
+	FromStatus string    `json:"from_status" gorm:"type:varchar(20)"` // 为空表示新建书籍时的初始状态
+	ToStatus   string    `json:"to_status" gorm:"not null;type:varchar(20)"`
+	Reason     string    `json:"reason" gorm:"type:varchar(500)"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index:idx_status_change_book_created"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Category 分类树中的一个节点，ParentID 为空表示顶级分类
+type Category struct {
+	ID           uint              `json:"id" gorm:"primaryKey"`
+	ParentID     *uint             `json:"parent_id" gorm:"index"`
+	Slug         string            `json:"slug" gorm:"uniqueIndex;not null;type:varchar(100)"`
+	Name         string            `json:"name" gorm:"not null;type:varchar(50)"`          // 默认显示名称
+	DisplayNames map[string]string `json:"display_names" gorm:"serializer:json;type:text"` // 多语言显示名称，例如 {"en": "Science Fiction"}
+	SortOrder    int               `json:"sort_order" gorm:"not null;default:0"`
+	Aliases      []CategoryAlias   `json:"aliases,omitempty" gorm:"foreignKey:CategoryID"`
+	DisplayName  string            `json:"display_name,omitempty" gorm:"-"` // 按请求语言选择的显示名称
+	Children     []*Category       `json:"children,omitempty" gorm:"-"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// CategoryAlias 映射到分类的其他写法，例如 "Sci-Fi"、"科幻小说" 都指向 "科幻"
+type CategoryAlias struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CategoryID uint      `json:"category_id" gorm:"not null;index"`
+	Name       string    `json:"name" gorm:"uniqueIndex;not null;type:varchar(50)"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// LocalizedName 返回指定语言的显示名称，没有对应翻译时返回默认名称
+func (c *Category) LocalizedName(lang string) string {
+	if name, ok := c.DisplayNames[lang]; ok && name != "" {
+		return name
+	}
+	return c.Name
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Date 只包含日期的时间类型，JSON 中格式为 "2006-01-02"，也接受 "2006-01" 和 "2006"
+type Date struct {
+	time.Time
+}
+
+var dateLayouts = []string{"2006-01-02", "2006-01", "2006", time.RFC3339}
+
+// ParseDate 解析日期字符串，只有年份或年月时取该年/月的第一天。
+// 零值日期（0001-01-01）表示未填写，不能作为有效日期
+func ParseDate(s string) (Date, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			d := Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+			if d.Year() < 1 || d.IsZero() {
+				break
+			}
+			return d, nil
+		}
+	}
+	return Date{}, fmt.Errorf("invalid date: %q", s)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(`"` + d.Format("2006-01-02") + `"`), nil
+}
+
+// UnmarshalJSON 空字符串得到零值，*Date 字段收到 "" 时不为 nil，但零值按未填写处理：
+// 写入数据库时为 NULL，更新书籍时表示清除该日期
+func (d *Date) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value 实现 driver.Valuer，零值写入 NULL
+func (d Date) Value() (driver.Value, error) {
+	if d.IsZero() {
+		return nil, nil
+	}
+	return d.Time, nil
+}
+
+// Scan 实现 sql.Scanner
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		d.Time = v
+	case []byte:
+		parsed, err := ParseDate(string(v))
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case string:
+		parsed, err := ParseDate(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+	case nil:
+		d.Time = time.Time{}
+	default:
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDateJSON(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string // 解析后再序列化的结果，"<nil>" 表示字段为 nil
+		wantErr bool
+	}{
+		{`{"date": "2008-01-02"}`, `"2008-01-02"`, false},
+		{`{"date": "2008-01"}`, `"2008-01-01"`, false},
+		{`{"date": "2008"}`, `"2008-01-01"`, false},
+		{`{"date": null}`, "<nil>", false},
+		{`{}`, "<nil>", false},
+		{`{"date": ""}`, "null", false},
+		{`{"date": "0001-01-01"}`, "", true},
+		{`{"date": "0000"}`, "", true},
+		{`{"date": "2008-13-01"}`, "", true},
+		{`{"date": "soon"}`, "", true},
+	}
+	for _, tt := range tests {
+		var v struct {
+			Date *Date `json:"date"`
+		}
+		err := json.Unmarshal([]byte(tt.input), &v)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Unmarshal(%s) = %v, want error", tt.input, v.Date)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unmarshal(%s) error = %v", tt.input, err)
+			continue
+		}
+		got := "<nil>"
+		if v.Date != nil {
+			data, _ := json.Marshal(v.Date)
+			got = string(data)
+		}
+		if got != tt.want {
+			t.Errorf("Unmarshal(%s) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDateValue(t *testing.T) {
+	if v, err := (Date{}).Value(); err != nil || v != nil {
+		t.Errorf("zero Date Value() = %v, %v, want NULL", v, err)
+	}
+	d, err := ParseDate("2008-01")
+	if err != nil {
+		t.Fatalf("ParseDate() error = %v", err)
+	}
+	if v, err := d.Value(); err != nil || v == nil {
+		t.Errorf("Value() = %v, %v, want a time", v, err)
+	}
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// FileBlob 按 SHA-256 内容寻址存储的文件实体，相同内容的上传共享同一个 Blob
+type FileBlob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Hash      string    `json:"hash" gorm:"uniqueIndex;not null;type:char(64)"` // 文件内容的 SHA-256
+	Size      int64     `json:"size" gorm:"not null"`
+	MimeType  string    `json:"mime_type" gorm:"type:varchar(100)"`
+	RefCount  int       `json:"ref_count" gorm:"not null;default:0"` // 引用该 Blob 的书籍文件数量
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
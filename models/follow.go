@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Follow 用户之间的关注关系，FollowerID 关注了 FolloweeID
+type Follow struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	FollowerID uint      `json:"follower_id" gorm:"not null;uniqueIndex:idx_follow_pair,priority:1"`
+	Follower   *User     `json:"follower,omitempty" gorm:"foreignKey:FollowerID"`
+	FolloweeID uint      `json:"followee_id" gorm:"not null;uniqueIndex:idx_follow_pair,priority:2;index"`
+	Followee   *User     `json:"followee,omitempty" gorm:"foreignKey:FolloweeID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+)
+
+// 导入任务状态
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// 导入结果中每一行的状态
+const (
+	ImportRowCreated   = "created"
+	ImportRowValid     = "valid"     // 试运行时校验通过
+	ImportRowDuplicate = "duplicate" // 跳过
+	ImportRowMerged    = "merged"    // 与已有书籍合并
+	ImportRowError     = "error"
+)
+
+// ImportJob 批量导入书籍的后台任务
+type ImportJob struct {
+	ID         uint              `json:"id" gorm:"primaryKey"`
+	UserID     uint              `json:"user_id" gorm:"not null;index"`           // 导入的书籍归属的用户
+	Source     string            `json:"source" gorm:"not null;type:varchar(20)"` // csv, jsonl, calibre
+	FileName   string            `json:"file_name" gorm:"type:varchar(255)"`
+	DryRun     bool              `json:"dry_run" gorm:"not null;default:false"` // 只校验不写入
+	Merge      bool              `json:"merge" gorm:"not null;default:false"`   // 与已有书籍重复时补充缺失的信息，而不是跳过
+	Status     string            `json:"status" gorm:"not null;type:varchar(20);index"`
+	Total      int               `json:"total" gorm:"not null;default:0"`
+	Processed  int               `json:"processed" gorm:"not null;default:0"`
+	Created    int               `json:"created" gorm:"not null;default:0"`
+	Duplicates int               `json:"duplicates" gorm:"not null;default:0"`
+	Merged     int               `json:"merged" gorm:"not null;default:0"`
+	Failed     int               `json:"failed" gorm:"not null;default:0"`
+	Error      string            `json:"error,omitempty" gorm:"type:text"` // 任务整体失败的原因
+	Report     []ImportRowResult `json:"report,omitempty" gorm:"serializer:json;type:longtext"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	FinishedAt *time.Time        `json:"finished_at"`
+}
+
+// ImportRowResult 导入文件中一行的处理结果
+type ImportRowResult struct {
+	Row            int      `json:"row"` // 在导入文件中的行号
+	Title          string   `json:"title,omitempty"`
+	Status         string   `json:"status"`
+	BookID         uint     `json:"book_id,omitempty"`
+	DuplicateOf    uint     `json:"duplicate_of,omitempty"`     // 已存在的书籍ID
+	DuplicateOfRow int      `json:"duplicate_of_row,omitempty"` // 与文件中前面的某一行重复
+	Files          int      `json:"files,omitempty"`            // 保存的书籍文件和封面数量
+	Errors         []string `json:"errors,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"` // 不影响导入的问题，例如书籍文件缺失
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// 通知类型
+const (
+	NotificationSavedSearchMatch = "saved_search_match"
+	NotificationBookApproved     = "book_approved"
+	NotificationBookRejected     = "book_rejected"
+	NotificationBookUnpublished  = "book_unpublished"
+)
+
+// Notification 站内通知
+type Notification struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index:idx_notification_user_read"`
+	Type          string     `json:"type" gorm:"not null;type:varchar(30)"`
+	Title         string     `json:"title" gorm:"not null;type:varchar(255)"`
+	Message       string     `json:"message" gorm:"type:text"`
+	BookID        *uint      `json:"book_id" gorm:"uniqueIndex:idx_notification_search_book"`
+	Book          *Book      `json:"book,omitempty"`
+	SavedSearchID *uint      `json:"saved_search_id" gorm:"uniqueIndex:idx_notification_search_book"` // 同一个保存的搜索对同一本书只通知一次
+	ReadAt        *time.Time `json:"read_at" gorm:"index:idx_notification_user_read"`
+	EmailedAt     *time.Time `json:"emailed_at"` // 已包含在邮件摘要中的时间
+	CreatedAt     time.Time  `json:"created_at"`
+}
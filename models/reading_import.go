@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+)
+
+// ReadingImportReview 阅读记录导入完成，但还有不确定的匹配等待用户确认
+const ReadingImportReview = "review"
+
+// 阅读记录导入中每一条的状态
+const (
+	ReadingEntryImported  = "imported"  // 已匹配到书籍并写入阅读记录
+	ReadingEntryReview    = "review"    // 匹配不确定，等待用户确认
+	ReadingEntryUnmatched = "unmatched" // 没有找到书籍，且未开启自动创建
+	ReadingEntrySkipped   = "skipped"   // 用户确认跳过
+	ReadingEntryError     = "error"
+)
+
+// 阅读记录匹配到书籍的方式
+const (
+	MatchByIdentifier = "identifier" // 豆瓣、Goodreads 的条目编号
+	MatchByISBN       = "isbn"
+	MatchByTitle      = "title"   // 书名和作者的模糊匹配
+	MatchByCreated    = "created" // 新建的书籍
+	MatchByUser       = "user"    // 用户在确认时选择的书籍
+)
+
+// ReadingImport 从豆瓣、Goodreads 导出文件导入阅读记录的任务
+type ReadingImport struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	Source        string     `json:"source" gorm:"not null;type:varchar(20)"` // goodreads, douban
+	FileName      string     `json:"file_name" gorm:"type:varchar(255)"`
+	CreateMissing bool       `json:"create_missing" gorm:"not null;default:false"`  // 找不到的书籍自动创建
+	Status        string     `json:"status" gorm:"not null;type:varchar(20);index"` // pending, running, review, completed, failed
+	Total         int        `json:"total" gorm:"not null;default:0"`
+	Processed     int        `json:"processed" gorm:"not null;default:0"`
+	Imported      int        `json:"imported" gorm:"not null;default:0"`
+	BooksCreated  int        `json:"books_created" gorm:"not null;default:0"`
+	Review        int        `json:"review" gorm:"not null;default:0"` // 等待确认的条目数量
+	Unmatched     int        `json:"unmatched" gorm:"not null;default:0"`
+	Skipped       int        `json:"skipped" gorm:"not null;default:0"`
+	Failed        int        `json:"failed" gorm:"not null;default:0"`
+	Error         string     `json:"error,omitempty" gorm:"type:text"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	FinishedAt    *time.Time `json:"finished_at"`
+}
+
+// ReadingImportEntry 导入文件中的一条阅读记录及其匹配结果
+type ReadingImportEntry struct {
+	ID         uint                     `json:"id" gorm:"primaryKey"`
+	ImportID   uint                     `json:"import_id" gorm:"not null;index:idx_reading_entry_status"`
+	Row        int                      `json:"row"` // 在导入文件中的行号
+	Status     string                   `json:"status" gorm:"not null;type:varchar(20);index:idx_reading_entry_status"`
+	Title      string                   `json:"title" gorm:"type:varchar(255)"`
+	Author     string                   `json:"author" gorm:"type:varchar(255)"`
+	Book       ReadingImportBook        `json:"book" gorm:"serializer:json;type:text"` // 导入文件中的书目信息，用于创建书籍
+	Relation   string                   `json:"relation" gorm:"type:varchar(20)"`      // read, reading, want_to_read
+	Rating     *int                     `json:"rating"`
+	ReadAt     *Date                    `json:"read_at" gorm:"type:date"`
+	AddedAt    *time.Time               `json:"added_at"` // 在原站点标记的时间
+	BookID     *uint                    `json:"book_id"`
+	MatchedBy  string                   `json:"matched_by,omitempty" gorm:"type:varchar(20)"`
+	Score      float64                  `json:"score"` // 匹配的可信度，0-1
+	Candidates []ReadingImportCandidate `json:"candidates,omitempty" gorm:"serializer:json;type:text"`
+	RelationID *uint                    `json:"relation_id"`
+	Errors     []string                 `json:"errors,omitempty" gorm:"serializer:json;type:text"`
+	CreatedAt  time.Time                `json:"created_at"`
+	UpdatedAt  time.Time                `json:"updated_at"`
+}
+
+// ReadingImportBook 导入文件中一条阅读记录的书目信息
+type ReadingImportBook struct {
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	ISBN13      string `json:"isbn13,omitempty"`
+	ISBN10      string `json:"isbn10,omitempty"`
+	Publisher   string `json:"publisher,omitempty"`
+	PublishDate *Date  `json:"publish_date,omitempty"`
+	PageCount   int    `json:"page_count,omitempty"`
+	Scheme      string `json:"scheme,omitempty"` // 原站点的条目编号，如 douban、goodreads
+	ExternalID  string `json:"external_id,omitempty"`
+}
+
+// ReadingImportCandidate 不确定匹配的候选书籍
+type ReadingImportCandidate struct {
+	BookID uint    `json:"book_id"`
+	Title  string  `json:"title"`
+	Author string  `json:"author"`
+	Score  float64 `json:"score"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SavedSearch 用户保存的搜索，有新书匹配时通知用户
+type SavedSearch struct {
+	ID          uint              `json:"id" gorm:"primaryKey"`
+	UserID      uint              `json:"user_id" gorm:"not null;index"`
+	Name        string            `json:"name" gorm:"not null;type:varchar(100)"`
+	Query       string            `json:"query" gorm:"type:varchar(500)"`             // 高级搜索语句，可为空
+	Filters     map[string]string `json:"filters" gorm:"serializer:json;type:text"`   // 与书籍列表相同的筛选参数，如 {"language": "zh", "year_from": "2010"}
+	EmailDigest bool              `json:"email_digest" gorm:"not null;default:false"` // 是否在邮件摘要中包含匹配结果
+	LastBookID  uint              `json:"last_book_id" gorm:"not null;default:0"`     // 已检查过的最大书籍ID，只有更新的书籍才会触发通知
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt    `json:"deleted_at" gorm:"index"`
+}
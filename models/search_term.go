@@ -0,0 +1,7 @@
+package models
+
+// SearchTerm 搜索词表，收录书名、作者名中的拉丁字母单词和拼音，用于拼写纠错
+type SearchTerm struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Term string `json:"term" gorm:"uniqueIndex;not null;type:varchar(64)"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Series 系列/丛书，例如《三体》三部曲，书籍通过 SeriesIndex 排序
+type Series struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Title       string         `json:"title" gorm:"not null;type:varchar(255);index"`
+	Description string         `json:"description" gorm:"type:text"`
+	CoverImage  string         `json:"cover_image" gorm:"type:varchar(255)"`
+	Books       []Book         `json:"books,omitempty" gorm:"foreignKey:SeriesID"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+}
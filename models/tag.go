@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// Tag 用户为书籍添加的自由标签，名称经过规范化处理
+type Tag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null;type:varchar(50)"` // 规范化后的名称
+	DisplayName string    `json:"display_name" gorm:"not null;type:varchar(50)"`     // 首次使用时的写法
+	CanonicalID *uint     `json:"canonical_id" gorm:"index"`                         // 不为空表示该标签是同义词，指向规范标签
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// BookTag 书籍与标签的多对多关联
+type BookTag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	BookID    uint      `json:"book_id" gorm:"not null;uniqueIndex:idx_book_tag"`
+	TagID     uint      `json:"tag_id" gorm:"not null;uniqueIndex:idx_book_tag;index"`
+	Tag       Tag       `json:"tag"`
+	UserID    uint      `json:"user_id" gorm:"not null"` // 添加标签的用户ID
+	CreatedAt time.Time `json:"created_at"`
+}
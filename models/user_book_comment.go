@@ -6,14 +6,33 @@ import (
 	"gorm.io/gorm"
 )
 
+// 用户与书籍的关系类型
+const (
+	RelationCollected  = "collected"
+	RelationRead       = "read"
+	RelationReading    = "reading"
+	RelationWantToRead = "want_to_read"
+)
+
+// IsValidRelationType 检查关系类型是否有效
+func IsValidRelationType(relationType string) bool {
+	switch relationType {
+	case RelationCollected, RelationRead, RelationReading, RelationWantToRead:
+		return true
+	}
+	return false
+}
+
 type UserBookRelation struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	UserID       uint           `json:"user_id" gorm:"not null"`
 	User         User           `json:"user"`
 	BookID       uint           `json:"book_id" gorm:"not null"`
 	Book         Book           `json:"book"`
-	RelationType string         `json:"relation_type" gorm:"not null;type:varchar(20)"` // collected, read
+	RelationType string         `json:"relation_type" gorm:"not null;type:varchar(20)"` // collected, read, reading, want_to_read
+	Rating       *int           `json:"rating" gorm:"type:tinyint"`                     // 评分 1-5，未评分为空
+	ReadAt       *Date          `json:"read_at" gorm:"type:date"`                       // 读完的日期
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
-}
\ No newline at end of file
+}
@@ -52,6 +52,25 @@ func InitRouter() *gin.Engine {
 		userRoutes.GET("/:id/books", controllers.GetBooksByUser)
 		userRoutes.GET("/:id/relations", controllers.GetUserRelations)
 		userRoutes.GET("/:id/relations/:type", controllers.GetUserRelationByType)
+		userRoutes.GET("/:id/series/continue", controllers.GetContinueSeries)
+		userRoutes.GET("/:id/bibliography", controllers.GetUserBibliography)
+		userRoutes.POST("/:id/saved-searches", controllers.CreateSavedSearch)
+		userRoutes.GET("/:id/saved-searches", controllers.GetSavedSearches)
+		userRoutes.DELETE("/:id/saved-searches/:search_id", controllers.DeleteSavedSearch)
+		userRoutes.POST("/:id/reading-imports", controllers.ImportReadingHistory)
+		userRoutes.GET("/:id/reading-imports/:import_id", controllers.GetReadingImport)
+		userRoutes.GET("/:id/reading-imports/:import_id/entries", controllers.GetReadingImportEntries)
+		userRoutes.POST("/:id/reading-imports/:import_id/entries/:entry_id/resolve", controllers.ResolveReadingEntry)
+		userRoutes.GET("/:id/trash/:type", controllers.GetUserTrash)
+		userRoutes.POST("/:id/trash/:type/:item_id/restore", controllers.RestoreUserTrashItem)
+		userRoutes.DELETE("/:id/trash/:type/:item_id", controllers.PurgeUserTrashItem)
+		userRoutes.POST("/:id/following", controllers.FollowUser)
+		userRoutes.GET("/:id/following", controllers.GetFollowing)
+		userRoutes.DELETE("/:id/following/:followee_id", controllers.UnfollowUser)
+		userRoutes.GET("/:id/followers", controllers.GetFollowers)
+		userRoutes.GET("/:id/notifications", controllers.GetNotifications)
+		userRoutes.POST("/:id/notifications/read-all", controllers.MarkAllNotificationsRead)
+		userRoutes.POST("/:id/notifications/:notification_id/read", controllers.MarkNotificationRead)
 
 		// 2. 然后再注册只包含单个通配符的通用路由
 		// 所有参数都使用 :id
@@ -68,10 +87,77 @@ func InitRouter() *gin.Engine {
 	{
 		bookRoutes.POST("", controllers.CreateBook)
 		bookRoutes.GET("", controllers.GetAllBooks)
+		bookRoutes.POST("/import", controllers.ImportBooks)
+		bookRoutes.GET("/import/:job_id", controllers.GetImportJob)
+		bookRoutes.GET("/export", controllers.ExportBooks)
 		bookRoutes.GET("/:id", controllers.GetBookByID)
 		bookRoutes.PUT("/:id", controllers.UpdateBook)
 		bookRoutes.DELETE("/:id", controllers.DeleteBook)
 		bookRoutes.GET("/category/:category", controllers.GetBooksByCategory)
+		bookRoutes.GET("/isbn/:isbn", controllers.GetBookByISBN)
+		bookRoutes.GET("/isbn/:isbn/metadata", controllers.GetBookMetadataByISBN)
+		bookRoutes.POST("/fill-from-isbn", controllers.FillBookFromISBN)
+		bookRoutes.POST("/:id/files", controllers.UploadBookFile)
+		bookRoutes.GET("/:id/files", controllers.GetBookFiles)
+		bookRoutes.GET("/:id/files/:file_id", controllers.DownloadBookFile)
+		bookRoutes.DELETE("/:id/files/:file_id", controllers.DeleteBookFile)
+		bookRoutes.PUT("/:id/contributors", controllers.SetBookContributors)
+		bookRoutes.GET("/:id/revisions", controllers.GetBookRevisions)
+		bookRoutes.POST("/:id/revisions/:revision_id/revert", controllers.RevertBook)
+		bookRoutes.POST("/:id/status", controllers.ChangeBookStatus)
+		bookRoutes.GET("/:id/status-history", controllers.GetBookStatusHistory)
+		bookRoutes.POST("/:id/share-links", controllers.CreateBookShareLink)
+		bookRoutes.GET("/:id/share-links", controllers.GetBookShareLinks)
+		bookRoutes.DELETE("/:id/share-links/:link_id", controllers.DeleteBookShareLink)
+		bookRoutes.GET("/:id/tags", controllers.GetBookTags)
+		bookRoutes.POST("/:id/tags", controllers.AddBookTags)
+		bookRoutes.DELETE("/:id/tags/:tag", controllers.RemoveBookTag)
+		bookRoutes.GET("/:id/cite", controllers.CiteBook)
+	}
+
+	// Search Group
+	searchRoutes := r.Group("/search")
+	searchRoutes.Use(middlewares.AuthMiddleware())
+	{
+		searchRoutes.GET("", controllers.SearchBooks)
+		searchRoutes.GET("/suggest", controllers.SuggestSearch)
+	}
+
+	// Series Group
+	seriesRoutes := r.Group("/series")
+	seriesRoutes.Use(middlewares.AuthMiddleware())
+	{
+		seriesRoutes.POST("", controllers.CreateSeries)
+		seriesRoutes.GET("/:id", controllers.GetSeriesByID)
+		seriesRoutes.PUT("/:id", controllers.UpdateSeries)
+	}
+
+	// Tag Group
+	tagRoutes := r.Group("/tags")
+	tagRoutes.Use(middlewares.AuthMiddleware())
+	{
+		tagRoutes.GET("/cloud", controllers.GetTagCloud)
+		tagRoutes.GET("/:tag/books", controllers.GetBooksByTag)
+	}
+
+	// Category Group
+	categoryRoutes := r.Group("/categories")
+	categoryRoutes.Use(middlewares.AuthMiddleware())
+	{
+		categoryRoutes.GET("", controllers.GetCategoryTree)
+		categoryRoutes.GET("/:category", controllers.GetCategory)
+	}
+
+	// Author Group
+	authorRoutes := r.Group("/authors")
+	authorRoutes.Use(middlewares.AuthMiddleware())
+	{
+		authorRoutes.POST("", controllers.CreateAuthor)
+		authorRoutes.GET("", controllers.GetAuthors)
+		authorRoutes.GET("/:id", controllers.GetAuthorByID)
+		authorRoutes.PUT("/:id", controllers.UpdateAuthor)
+		authorRoutes.GET("/:id/books", controllers.GetAuthorBooks)
+		authorRoutes.POST("/:id/aliases", controllers.AddAuthorAlias)
 	}
 
 	// Comment Group
@@ -104,6 +190,31 @@ func InitRouter() *gin.Engine {
 		adminRoutes.GET("/books/popular", controllers.GetPopularBooks)
 	}
 
+	// Admin Management Group - 后台管理接口
+	adminManageRoutes := r.Group("/admin")
+	adminManageRoutes.Use(middlewares.AdminAuthMiddleware())
+	{
+		adminManageRoutes.GET("/files/duplicates", controllers.GetDuplicateFiles)
+		adminManageRoutes.GET("/books/duplicates", controllers.GetDuplicateBooks)
+		adminManageRoutes.POST("/books/duplicates/dismiss", controllers.DismissDuplicateBooks)
+		adminManageRoutes.POST("/books/:id/merge", controllers.MergeBooks)
+		adminManageRoutes.POST("/books/:id/revisions/:revision_id/revert", controllers.AdminRevertBook)
+		adminManageRoutes.GET("/books/moderation", controllers.GetModerationQueue)
+		adminManageRoutes.POST("/books/:id/status", controllers.ModerateBook)
+		adminManageRoutes.POST("/authors/:id/merge", controllers.MergeAuthors)
+		adminManageRoutes.GET("/categories", controllers.GetCategoryTree)
+		adminManageRoutes.POST("/categories", controllers.CreateCategory)
+		adminManageRoutes.PUT("/categories/:id", controllers.UpdateCategory)
+		adminManageRoutes.DELETE("/categories/:id", controllers.DeleteCategory)
+		adminManageRoutes.POST("/categories/:id/aliases", controllers.AddCategoryAlias)
+		adminManageRoutes.POST("/tags/:id/merge", controllers.MergeTag)
+		adminManageRoutes.POST("/tags/:id/synonyms", controllers.AddTagSynonym)
+		adminManageRoutes.POST("/search/reindex", controllers.RebuildSearchIndex)
+		adminManageRoutes.GET("/trash/:type", controllers.GetTrash)
+		adminManageRoutes.POST("/trash/:type/:item_id/restore", controllers.RestoreTrashItem)
+		adminManageRoutes.DELETE("/trash/:type/:item_id", controllers.PurgeTrashItem)
+	}
+
 	// --- Swagger Docs 配置 (可选) ---
 	// 确保已安装 github.com/swaggo/gin-swagger 和 github.com/swaggo/swag/cmd/swag
 	// 1. 在项目根目录运行 `swag init` 生成 docs 目录
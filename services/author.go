@@ -0,0 +1,207 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrAuthorNotFound = errors.New("author not found")
+
+// ResolveAuthor 按名称或别名查找作者，不存在时创建
+func ResolveAuthor(tx *gorm.DB, name string) (*models.Author, error) {
+	name = utils.CleanAuthorName(name)
+	if name == "" {
+		return nil, errors.New("author name is required")
+	}
+
+	var author models.Author
+	err := tx.Where("name = ?", name).First(&author).Error
+	if err == nil {
+		return &author, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var alias models.AuthorAlias
+	if err := tx.Where("name = ?", name).First(&alias).Error; err == nil {
+		if err := tx.First(&author, alias.AuthorID).Error; err == nil {
+			return &author, nil
+		}
+	}
+
+	author = models.Author{Name: name}
+	if err := tx.Create(&author).Error; err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+// SetBookContributors 用给定的署名替换书籍的全部作者关联，并同步书籍的作者署名字符串。
+// 署名项可以通过 AuthorID 指定已有作者，也可以只给出 Author.Name 自动匹配或创建。
+func SetBookContributors(tx *gorm.DB, book *models.Book, contributors []models.BookAuthor) error {
+	if err := tx.Where("book_id = ?", book.ID).Delete(&models.BookAuthor{}).Error; err != nil {
+		return err
+	}
+
+	type credit struct {
+		authorID uint
+		role     string
+	}
+	saved := make([]models.BookAuthor, 0, len(contributors))
+	seen := make(map[credit]bool)
+	for i, contributor := range contributors {
+		if contributor.Role == "" {
+			contributor.Role = models.AuthorRoleAuthor
+		}
+		if !models.IsValidAuthorRole(contributor.Role) {
+			return errors.New("invalid author role: " + contributor.Role)
+		}
+
+		if contributor.AuthorID != 0 {
+			if err := tx.First(&contributor.Author, contributor.AuthorID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: %d", ErrAuthorNotFound, contributor.AuthorID)
+				}
+				return err
+			}
+		} else {
+			author, err := ResolveAuthor(tx, contributor.Author.Name)
+			if err != nil {
+				return err
+			}
+			contributor.Author = *author
+		}
+
+		key := credit{contributor.Author.ID, contributor.Role}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		record := models.BookAuthor{
+			BookID:   book.ID,
+			AuthorID: contributor.Author.ID,
+			Role:     contributor.Role,
+			Position: i,
+		}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		record.Author = contributor.Author
+		saved = append(saved, record)
+	}
+
+	book.Contributors = saved
+	if display := FormatAuthorCredits(saved); display != "" && display != book.Author {
+		book.Author = display
+		return tx.Model(&models.Book{}).Where("id = ?", book.ID).Update("author", display).Error
+	}
+	return nil
+}
+
+// SyncBookAuthorsFromString 根据书籍的作者署名字符串重建作者关联
+func SyncBookAuthorsFromString(tx *gorm.DB, book *models.Book) error {
+	credits := utils.ParseAuthorCredits(book.Author)
+	contributors := make([]models.BookAuthor, 0, len(credits))
+	for _, credit := range credits {
+		contributors = append(contributors, models.BookAuthor{
+			Role:   credit.Role,
+			Author: models.Author{Name: credit.Name},
+		})
+	}
+	return SetBookContributors(tx, book, contributors)
+}
+
+// RefreshAuthorBookCredits 作者改名或合并后，按作者关联重新生成其全部书籍（包括回收站中的）的作者署名字符串，
+// 返回署名有变化的书籍ID
+func RefreshAuthorBookCredits(tx *gorm.DB, authorID uint) ([]uint, error) {
+	var bookIDs []uint
+	if err := tx.Model(&models.BookAuthor{}).Where("author_id = ?", authorID).Distinct().Pluck("book_id", &bookIDs).Error; err != nil {
+		return nil, err
+	}
+	var changed []uint
+	for _, bookID := range bookIDs {
+		var contributors []models.BookAuthor
+		if err := tx.Preload("Author").Where("book_id = ?", bookID).Order("position").Find(&contributors).Error; err != nil {
+			return nil, err
+		}
+		display := FormatAuthorCredits(contributors)
+		if display == "" {
+			continue
+		}
+		result := tx.Unscoped().Model(&models.Book{}).Where("id = ? AND author <> ?", bookID, display).Update("author", display)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			changed = append(changed, bookID)
+		}
+	}
+	return changed, nil
+}
+
+// FormatAuthorCredits 生成用于展示的作者署名，例如 "卡勒德·胡赛尼 / 李继宏 译"
+func FormatAuthorCredits(contributors []models.BookAuthor) string {
+	roleLabels := map[string]string{
+		models.AuthorRoleTranslator:  " 译",
+		models.AuthorRoleEditor:      " 编",
+		models.AuthorRoleIllustrator: " 绘",
+	}
+
+	var groups []string
+	for _, role := range []string{models.AuthorRoleAuthor, models.AuthorRoleTranslator, models.AuthorRoleEditor, models.AuthorRoleIllustrator} {
+		var names []string
+		for _, contributor := range contributors {
+			if contributor.Role == role {
+				names = append(names, contributor.Author.Name)
+			}
+		}
+		if len(names) > 0 {
+			groups = append(groups, strings.Join(names, "、")+roleLabels[role])
+		}
+	}
+
+	display := strings.Join(groups, " / ")
+	if len([]rune(display)) > 100 { // 与 books.author 的长度限制一致
+		display = string([]rune(display)[:100])
+	}
+	return display
+}
+
+// MergeAuthors 将 source 合并到 target：转移书籍关联，并把 source 的名称和别名保留为 target 的别名
+func MergeAuthors(tx *gorm.DB, source, target *models.Author) error {
+	var links []models.BookAuthor
+	if err := tx.Where("author_id = ?", source.ID).Find(&links).Error; err != nil {
+		return err
+	}
+	for _, link := range links {
+		var count int64
+		tx.Model(&models.BookAuthor{}).
+			Where("book_id = ? AND author_id = ? AND role = ?", link.BookID, target.ID, link.Role).
+			Count(&count)
+		if count > 0 {
+			if err := tx.Delete(&link).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(&link).Update("author_id", target.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.AuthorAlias{}).Where("author_id = ?", source.ID).Update("author_id", target.ID).Error; err != nil {
+		return err
+	}
+	// 作者名称有唯一索引，彻底删除后才能作为别名保存
+	if err := tx.Unscoped().Delete(source).Error; err != nil {
+		return err
+	}
+	return tx.Create(&models.AuthorAlias{AuthorID: target.ID, Name: source.Name}).Error
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// NormalizeBookISBN 校验并补全书籍的 ISBN-10/ISBN-13，两者同时提供时必须指向同一本书
+func NormalizeBookISBN(book *models.Book) error {
+	var input10, input13 string
+	if book.ISBN10 != nil {
+		input10 = *book.ISBN10
+	}
+	if book.ISBN13 != nil {
+		input13 = *book.ISBN13
+	}
+	book.ISBN10, book.ISBN13 = nil, nil
+	if input10 == "" && input13 == "" {
+		return nil
+	}
+
+	var isbn10, isbn13 string
+	for _, input := range []string{input13, input10} {
+		if input == "" {
+			continue
+		}
+		i10, i13, err := utils.ParseISBN(input)
+		if err != nil {
+			return fmt.Errorf("invalid ISBN: %s", input)
+		}
+		if isbn13 != "" && isbn13 != i13 {
+			return errors.New("isbn10 and isbn13 refer to different books")
+		}
+		isbn10, isbn13 = i10, i13
+	}
+
+	if isbn10 != "" {
+		book.ISBN10 = &isbn10
+	}
+	book.ISBN13 = &isbn13
+	return nil
+}
+
+// FindBookByISBN 查找使用相同 ISBN 的其他书籍，excludeID 用于更新时排除自身。
+// 只查找未删除的书籍，用于去重和合并；检查 ISBN 是否可用时使用 FindISBNConflict
+func FindBookByISBN(db *gorm.DB, isbn13 *string, excludeID uint) *models.Book {
+	return findBookByISBN(db, isbn13, excludeID)
+}
+
+// FindISBNConflict 查找占用该 ISBN 的其他书籍。回收站中的书籍仍占用 ISBN 的唯一索引，
+// 也会被找到，可以通过 DeletedAt 区分
+func FindISBNConflict(db *gorm.DB, isbn13 *string, excludeID uint) *models.Book {
+	return findBookByISBN(db.Unscoped(), isbn13, excludeID)
+}
+
+func findBookByISBN(db *gorm.DB, isbn13 *string, excludeID uint) *models.Book {
+	if isbn13 == nil {
+		return nil
+	}
+	var book models.Book
+	query := db.Where("isbn13 = ?", *isbn13)
+	if excludeID != 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+	if query.First(&book).Error != nil {
+		return nil
+	}
+	return &book
+}
+
+// ValidateContributors 检查结构化作者的角色和作者信息
+func ValidateContributors(contributors []models.BookAuthor) error {
+	for _, contributor := range contributors {
+		if contributor.Role != "" && !models.IsValidAuthorRole(contributor.Role) {
+			return fmt.Errorf("invalid author role: %s", contributor.Role)
+		}
+		if contributor.AuthorID == 0 && strings.TrimSpace(contributor.Author.Name) == "" {
+			return errors.New("each contributor needs an author_id or author.name")
+		}
+	}
+	return nil
+}
+
+// ApplyBookCategory 将书籍关联到分类树：优先使用 category_id，否则按分类名称或别名匹配，
+// 匹配不到时保留原始分类字符串
+func ApplyBookCategory(db *gorm.DB, book *models.Book) error {
+	book.CategoryInfo = nil
+	if book.CategoryID != nil {
+		var category models.Category
+		if err := db.First(&category, *book.CategoryID).Error; err != nil {
+			return errors.New("category not found")
+		}
+		book.Category = category.Name
+		return nil
+	}
+	if book.Category == "" {
+		return nil
+	}
+	if category, err := ResolveCategory(db, book.Category); err == nil {
+		book.CategoryID = &category.ID
+		book.Category = category.Name
+	}
+	return nil
+}
+
+// ValidateBookSeries 检查书籍引用的系列是否存在
+func ValidateBookSeries(db *gorm.DB, book *models.Book) bool {
+	book.Series = nil
+	if book.SeriesID == nil {
+		return true
+	}
+	var count int64
+	db.Model(&models.Series{}).Where("id = ?", *book.SeriesID).Count(&count)
+	return count > 0
+}
+
+// CreateBook 在事务中创建书籍及其作者署名，未提供结构化作者时从作者署名字符串中解析
+func CreateBook(tx *gorm.DB, book *models.Book, contributors []models.BookAuthor) error {
+	if err := tx.Omit("CategoryInfo").Create(book).Error; err != nil {
+		return err
+	}
+	if len(contributors) > 0 {
+		return SetBookContributors(tx, book, contributors)
+	}
+	return SyncBookAuthorsFromString(tx, book)
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 支持的书籍格式
+var BookFormats = []string{"paperback", "hardcover", "ebook", "audiobook", "other"}
+
+// BookFilter 书籍列表的筛选条件，零值表示不限制
+type BookFilter struct {
+	Category           string   `json:"category,omitempty"`
+	IncludeDescendants bool     `json:"include_descendants,omitempty"`
+	Tags               []string `json:"tags,omitempty"`
+	MatchAllTags       bool     `json:"match_all_tags,omitempty"`
+	Authors            []uint   `json:"authors,omitempty"`
+	Publisher          string   `json:"publisher,omitempty"`
+	YearFrom           int      `json:"year_from,omitempty"`
+	YearTo             int      `json:"year_to,omitempty"`
+	Decades            []int    `json:"decades,omitempty"` // 出版年代，如 1990 表示 1990~1999 年
+	PagesMin           int      `json:"pages_min,omitempty"`
+	PagesMax           int      `json:"pages_max,omitempty"`
+	Languages          []string `json:"languages,omitempty"`
+	Formats            []string `json:"formats,omitempty"`
+}
+
+// Scope 返回应用全部筛选条件的查询作用域
+func (f BookFilter) Scope(db *gorm.DB) func(*gorm.DB) *gorm.DB {
+	var tagScope func(*gorm.DB) *gorm.DB
+	if len(f.Tags) > 0 {
+		tagScope = ScopeTags(db, f.Tags, f.MatchAllTags)
+	}
+
+	return func(query *gorm.DB) *gorm.DB {
+		if f.Category != "" {
+			query = ScopeCategoryKey(db, f.Category, f.IncludeDescendants)(query)
+		}
+		if tagScope != nil {
+			query = tagScope(query)
+		}
+		if len(f.Authors) > 0 {
+			query = query.Where("books.id IN (?)", db.Model(&models.BookAuthor{}).Select("book_id").Where("author_id IN ?", f.Authors))
+		}
+		if f.Publisher != "" {
+			query = query.Where("books.publisher = ?", f.Publisher)
+		}
+		// 年份范围使用 publish_date 的区间比较，可以利用索引
+		if f.YearFrom > 0 {
+			query = query.Where("books.publish_date >= ?", time.Date(f.YearFrom, 1, 1, 0, 0, 0, 0, time.UTC))
+		}
+		if f.YearTo > 0 {
+			query = query.Where("books.publish_date < ?", time.Date(f.YearTo+1, 1, 1, 0, 0, 0, 0, time.UTC))
+		}
+		if len(f.Decades) > 0 {
+			conditions := make([]string, len(f.Decades))
+			args := make([]interface{}, 0, len(f.Decades)*2)
+			for i, decade := range f.Decades {
+				conditions[i] = "(books.publish_date >= ? AND books.publish_date < ?)"
+				args = append(args,
+					time.Date(decade, 1, 1, 0, 0, 0, 0, time.UTC),
+					time.Date(decade+10, 1, 1, 0, 0, 0, 0, time.UTC))
+			}
+			query = query.Where(strings.Join(conditions, " OR "), args...)
+		}
+		// 页数为 0 表示未知，指定页数范围时排除
+		if f.PagesMin > 0 || f.PagesMax > 0 {
+			query = query.Where("books.page_count > 0")
+		}
+		if f.PagesMin > 0 {
+			query = query.Where("books.page_count >= ?", f.PagesMin)
+		}
+		if f.PagesMax > 0 {
+			query = query.Where("books.page_count <= ?", f.PagesMax)
+		}
+		if len(f.Languages) > 0 {
+			query = query.Where("books.language IN ?", f.Languages)
+		}
+		if len(f.Formats) > 0 {
+			query = query.Where("books.format IN ?", f.Formats)
+		}
+		return query
+	}
+}
+
+// ParseBookFilter 从查询参数解析书籍筛选条件：
+// year_from/year_to、pages_min/pages_max 为闭区间，author_id、decade、language 和 format 可用逗号分隔多选
+func ParseBookFilter(values url.Values) (BookFilter, error) {
+	filter := BookFilter{
+		Category:           values.Get("category"),
+		IncludeDescendants: values.Get("include_descendants") != "false",
+		// tags=a,b 按标签筛选，tag_mode=and（默认，需包含全部标签）或 or（包含任一标签）
+		Tags:         utils.SplitList(values.Get("tags")),
+		MatchAllTags: values.Get("tag_mode") != "or",
+		Publisher:    strings.TrimSpace(values.Get("publisher")),
+	}
+
+	ranges := []struct {
+		param string
+		dest  *int
+	}{
+		{"year_from", &filter.YearFrom},
+		{"year_to", &filter.YearTo},
+		{"pages_min", &filter.PagesMin},
+		{"pages_max", &filter.PagesMax},
+	}
+	for _, r := range ranges {
+		value := values.Get(r.param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("invalid %s: %s", r.param, value)
+		}
+		*r.dest = n
+	}
+	if filter.YearFrom > 0 && filter.YearTo > 0 && filter.YearFrom > filter.YearTo {
+		return filter, errors.New("year_from cannot be greater than year_to")
+	}
+	if filter.PagesMin > 0 && filter.PagesMax > 0 && filter.PagesMin > filter.PagesMax {
+		return filter, errors.New("pages_min cannot be greater than pages_max")
+	}
+
+	for _, value := range values["author_id"] {
+		for _, item := range utils.SplitList(value) {
+			id, err := strconv.ParseUint(item, 10, 64)
+			if err != nil || id == 0 {
+				return filter, fmt.Errorf("invalid author_id: %s", item)
+			}
+			filter.Authors = append(filter.Authors, uint(id))
+		}
+	}
+	for _, value := range values["decade"] {
+		for _, item := range utils.SplitList(value) {
+			decade, err := strconv.Atoi(strings.TrimSuffix(item, "s"))
+			if err != nil || decade <= 0 || decade%10 != 0 {
+				return filter, fmt.Errorf("invalid decade: %s", item)
+			}
+			filter.Decades = append(filter.Decades, decade)
+		}
+	}
+	for _, value := range values["language"] {
+		for _, lang := range utils.SplitList(value) {
+			code, ok := utils.NormalizeLanguage(lang)
+			if !ok {
+				return filter, fmt.Errorf("invalid language: %s", lang)
+			}
+			filter.Languages = append(filter.Languages, code)
+		}
+	}
+	for _, value := range values["format"] {
+		for _, format := range utils.SplitList(value) {
+			format = strings.ToLower(format)
+			if !IsValidBookFormat(format) {
+				return filter, fmt.Errorf("invalid format: %s", format)
+			}
+			filter.Formats = append(filter.Formats, format)
+		}
+	}
+	return filter, nil
+}
+
+// ScopeCategoryKey 按分类（ID、slug、名称或别名）筛选书籍，可包含全部子分类；
+// 分类不存在时退回到按分类字符串精确匹配，兼容尚未映射的旧数据
+func ScopeCategoryKey(db *gorm.DB, key string, includeDescendants bool) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		category, err := ResolveCategory(db, key)
+		if err != nil {
+			return query.Where("books.category = ?", key)
+		}
+		ids := []uint{category.ID}
+		if includeDescendants {
+			if descendants, err := CategoryDescendantIDs(db, category.ID); err == nil {
+				ids = descendants
+			}
+		}
+		return ScopeCategories(ids)(query)
+	}
+}
+
+// BookSortColumns 书籍列表允许排序的字段
+var BookSortColumns = map[string]SortColumn{
+	"created_at":   {Column: "books.created_at", Field: "created_at", Time: true},
+	"updated_at":   {Column: "books.updated_at", Field: "updated_at", Time: true},
+	"title":        {Column: "books.title", Field: "title"},
+	"publish_date": {Column: "books.publish_date", Field: "publish_date", Nullable: true, Time: true},
+	"page_count":   {Column: "books.page_count", Field: "page_count"},
+}
+
+// SortBooks 按给定字段排序，并以 ID 作为第二排序键保证结果稳定
+func SortBooks(sort, order string) (func(*gorm.DB) *gorm.DB, error) {
+	column, ok := BookSortColumns[sort]
+	if !ok {
+		return nil, fmt.Errorf("invalid sort field: %s", sort)
+	}
+	switch strings.ToLower(order) {
+	case "asc":
+		return column.Order(false), nil
+	case "desc":
+		return column.Order(true), nil
+	}
+	return nil, fmt.Errorf("invalid sort order: %s", order)
+}
+
+// IsValidBookFormat 检查书籍格式是否受支持
+func IsValidBookFormat(format string) bool {
+	for _, f := range BookFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeBookMetadata 规范化书籍的语言、格式和出版日期字段
+func NormalizeBookMetadata(book *models.Book) error {
+	// "publish_date": "" 视为未填写
+	if book.PublishDate != nil && book.PublishDate.IsZero() {
+		book.PublishDate = nil
+	}
+	if book.Language != "" {
+		code, ok := utils.NormalizeLanguage(book.Language)
+		if !ok {
+			return fmt.Errorf("invalid language: %s", book.Language)
+		}
+		book.Language = code
+	}
+	if book.Format != "" {
+		book.Format = strings.ToLower(strings.TrimSpace(book.Format))
+		if !IsValidBookFormat(book.Format) {
+			return fmt.Errorf("invalid format: %s, expected one of %s", book.Format, strings.Join(BookFormats, ", "))
+		}
+	}
+	if book.PageCount < 0 {
+		return fmt.Errorf("page_count cannot be negative")
+	}
+	return nil
+}
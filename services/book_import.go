@@ -0,0 +1,700 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/utils"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 导入文件格式
+const (
+	ImportSourceCSV   = "csv"
+	ImportSourceJSONL = "jsonl"
+)
+
+const (
+	// MaxImportRows 单个导入文件最多的行数
+	MaxImportRows = 5000
+	// importProgressEvery 每处理多少行更新一次任务进度
+	importProgressEvery = 20
+)
+
+// ImportRecord 从导入文件中解析出的一本书
+type ImportRecord struct {
+	Row          int
+	Book         models.Book
+	Contributors []models.BookAuthor
+	Tags         []string
+	SeriesTitle  string // 按名称匹配系列，未提供 series_id 时使用
+	CreateSeries bool   // 按名称匹配不到系列时创建，而不是报错
+	Identifiers  []models.BookIdentifier
+	Files        []ImportFile // 随书籍保存的文件
+	Cover        *ImportFile  // 封面图片，书籍没有封面时作为封面
+	Errors       []string     // 解析阶段发现的错误
+	blobHashes   []string     // 保存文件时写入的 Blob 哈希，事务失败时用于清理
+}
+
+// ImportSourceFromName 根据文件扩展名推断导入格式
+func ImportSourceFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv":
+		return ImportSourceCSV
+	case ".jsonl", ".ndjson", ".json":
+		return ImportSourceJSONL
+	case ".zip", ".db":
+		return ImportSourceCalibre
+	}
+	return ""
+}
+
+// ParseImportFile 按格式解析导入文件，文件整体无法解析时返回错误，单行的问题记录在 ImportRecord.Errors 中
+func ParseImportFile(source string, r io.Reader) ([]ImportRecord, error) {
+	var (
+		records []ImportRecord
+		err     error
+	)
+	switch source {
+	case ImportSourceCSV:
+		records, err = parseImportCSV(r)
+	case ImportSourceJSONL:
+		records, err = parseImportJSONL(r)
+	default:
+		return nil, fmt.Errorf("unsupported import format: %s", source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return checkImportRecords(records)
+}
+
+// checkImportRecords 检查解析出的记录数量
+func checkImportRecords(records []ImportRecord) ([]ImportRecord, error) {
+	if len(records) == 0 {
+		return nil, errors.New("import file contains no books")
+	}
+	if len(records) > MaxImportRows {
+		return nil, fmt.Errorf("import file has %d rows, at most %d are allowed", len(records), MaxImportRows)
+	}
+	return records, nil
+}
+
+// importColumnAliases CSV 表头的别名
+var importColumnAliases = map[string]string{
+	"authors": "author",
+	"pages":   "page_count",
+	"lang":    "language",
+	"series":  "series_title",
+	"tag":     "tags",
+	"isbn_10": "isbn10",
+	"isbn_13": "isbn13",
+	"cover":   "cover_image",
+	"year":    "publish_date",
+}
+
+// parseImportCSV 解析带表头的 CSV，列名与书籍的 JSON 字段一致，另外支持 isbn、series、tags（逗号分隔）
+func parseImportCSV(r io.Reader) ([]ImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	hasTitle := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		if alias, ok := importColumnAliases[name]; ok {
+			name = alias
+		}
+		columns[i] = name
+		hasTitle = hasTitle || name == "title"
+	}
+	if !hasTitle {
+		return nil, errors.New("CSV header must contain a title column")
+	}
+
+	var records []ImportRecord
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			records = append(records, ImportRecord{Row: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}})
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(fields))
+		for i, value := range fields {
+			if i < len(columns) {
+				values[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+		if strings.Join(fields, "") == "" {
+			continue // 空行
+		}
+		records = append(records, importRecordFromValues(line, values))
+	}
+	return records, nil
+}
+
+// importRecordFromValues 将一行字段转换为导入记录
+func importRecordFromValues(row int, values map[string]string) ImportRecord {
+	record := ImportRecord{Row: row}
+	book := &record.Book
+	book.Title = values["title"]
+	book.Author = values["author"]
+	book.Description = values["description"]
+	book.CoverImage = values["cover_image"]
+	book.Category = values["category"]
+	book.Publisher = values["publisher"]
+	book.Language = values["language"]
+	book.Format = values["format"]
+	book.Edition = values["edition"]
+	record.SeriesTitle = values["series_title"]
+	record.Tags = utils.SplitList(values["tags"])
+
+	if v := values["isbn13"]; v != "" {
+		book.ISBN13 = &v
+	} else if v := values["isbn"]; v != "" {
+		book.ISBN13 = &v
+	}
+	if v := values["isbn10"]; v != "" {
+		book.ISBN10 = &v
+	}
+	if v := values["publish_date"]; v != "" {
+		if date, err := models.ParseDate(v); err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("invalid publish_date: %s", v))
+		} else {
+			book.PublishDate = &date
+		}
+	}
+	if v := values["page_count"]; v != "" {
+		if n, err := strconv.Atoi(v); err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("invalid page_count: %s", v))
+		} else {
+			book.PageCount = n
+		}
+	}
+	if v := values["category_id"]; v != "" {
+		if id, err := strconv.ParseUint(v, 10, 64); err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("invalid category_id: %s", v))
+		} else {
+			categoryID := uint(id)
+			book.CategoryID = &categoryID
+		}
+	}
+	if v := values["series_id"]; v != "" {
+		if id, err := strconv.ParseUint(v, 10, 64); err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("invalid series_id: %s", v))
+		} else {
+			seriesID := uint(id)
+			book.SeriesID = &seriesID
+		}
+	}
+	if v := values["series_index"]; v != "" {
+		if index, err := strconv.ParseFloat(v, 64); err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("invalid series_index: %s", v))
+		} else {
+			book.SeriesIndex = &index
+		}
+	}
+	return record
+}
+
+// importLine JSON Lines 中的一行，字段与创建书籍的请求一致
+type importLine struct {
+	models.Book
+	ISBN        string   `json:"isbn"`
+	SeriesTitle string   `json:"series_title"`
+	Tags        []string `json:"tags"`
+}
+
+// parseImportJSONL 解析 JSON Lines，每行一个书籍对象
+func parseImportJSONL(r io.Reader) ([]ImportRecord, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var records []ImportRecord
+	for row := 1; scanner.Scan(); row++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" {
+			continue
+		}
+		var line importLine
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			records = append(records, ImportRecord{Row: row, Errors: []string{"invalid JSON: " + err.Error()}})
+			continue
+		}
+		book := line.Book
+		book.ID, book.UserID, book.User = 0, 0, models.User{}
+		book.CreatedAt, book.UpdatedAt = time.Time{}, time.Time{}
+		// 发布状态由导入流程决定，可见范围使用默认值，不能由导入文件指定
+		book.Status, book.StatusReason, book.SubmittedAt, book.Visibility = "", "", nil, ""
+		book.Comments = nil
+		if book.ISBN13 == nil && line.ISBN != "" {
+			book.ISBN13 = &line.ISBN
+		}
+		record := ImportRecord{Row: row, Book: book, Contributors: book.Contributors, Tags: line.Tags, SeriesTitle: line.SeriesTitle}
+		record.Book.Contributors = nil
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+	return records, nil
+}
+
+// validateImportRecord 按创建书籍的规则校验并规范化导入记录，返回全部错误
+func validateImportRecord(db *gorm.DB, record *ImportRecord) []string {
+	errs := append([]string(nil), record.Errors...)
+	book := &record.Book
+	book.Title = strings.TrimSpace(book.Title)
+	book.Author = strings.TrimSpace(book.Author)
+	if book.Title == "" {
+		errs = append(errs, "title is required")
+	}
+	if book.Author == "" && len(record.Contributors) == 0 {
+		errs = append(errs, "author is required")
+	}
+	if err := NormalizeBookISBN(book); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := ValidateContributors(record.Contributors); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := ApplyBookCategory(db, book); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if book.SeriesID == nil && record.SeriesTitle != "" {
+		var series models.Series
+		if err := db.Where("title = ?", record.SeriesTitle).First(&series).Error; err != nil {
+			if !record.CreateSeries {
+				errs = append(errs, fmt.Sprintf("series not found: %s", record.SeriesTitle))
+			}
+		} else {
+			book.SeriesID = &series.ID
+		}
+	}
+	if !ValidateBookSeries(db, book) {
+		errs = append(errs, "series not found")
+	}
+	if err := NormalizeBookMetadata(book); err != nil {
+		errs = append(errs, err.Error())
+	}
+	for i := range record.Identifiers {
+		identifier := &record.Identifiers[i]
+		identifier.Scheme = strings.ToLower(strings.TrimSpace(identifier.Scheme))
+		identifier.Value = strings.TrimSpace(identifier.Value)
+		if identifier.Scheme == "" || identifier.Value == "" || len(identifier.Scheme) > 30 || len(identifier.Value) > 191 {
+			errs = append(errs, fmt.Sprintf("invalid identifier: %s:%s", identifier.Scheme, identifier.Value))
+		}
+	}
+	return errs
+}
+
+// findDuplicateBook 按外部标识、ISBN 或书名加作者查找已存在的书籍
+func findDuplicateBook(db *gorm.DB, record *ImportRecord) *models.Book {
+	book := &record.Book
+	for _, identifier := range record.Identifiers {
+		var existing models.Book
+		if db.Where("id = (?)", db.Model(&models.BookIdentifier{}).Select("book_id").
+			Where("scheme = ? AND value = ?", identifier.Scheme, identifier.Value)).First(&existing).Error == nil {
+			return &existing
+		}
+	}
+	if existing := FindBookByISBN(db, book.ISBN13, 0); existing != nil {
+		return existing
+	}
+	if book.Author == "" {
+		return nil
+	}
+	var existing models.Book
+	if db.Where("title = ? AND author = ?", book.Title, book.Author).First(&existing).Error != nil {
+		return nil
+	}
+	return &existing
+}
+
+// importDedupeKeys 文件内去重使用的键
+func importDedupeKeys(record *ImportRecord) []string {
+	book := &record.Book
+	var keys []string
+	for _, identifier := range record.Identifiers {
+		keys = append(keys, "id:"+identifier.Scheme+"\x00"+identifier.Value)
+	}
+	if book.ISBN13 != nil {
+		keys = append(keys, "isbn:"+*book.ISBN13)
+	}
+	if book.Author != "" {
+		keys = append(keys, "title:"+strings.ToLower(book.Title)+"\x00"+strings.ToLower(book.Author))
+	}
+	return keys
+}
+
+// StartImportJob 保存导入任务并在后台执行，cleanup 不为空时在任务结束后调用，用于删除临时文件
+func StartImportJob(db *gorm.DB, job *models.ImportJob, records []ImportRecord, cleanup func()) error {
+	job.Status = models.ImportPending
+	job.Total = len(records)
+	if err := db.Create(job).Error; err != nil {
+		return err
+	}
+	run := *job // 后台任务使用副本，调用方可以继续读取 job
+	go func() {
+		if cleanup != nil {
+			defer cleanup()
+		}
+		RunImportJob(db, &run, records)
+	}()
+	return nil
+}
+
+// RunImportJob 逐行校验、去重并创建书籍，定期更新任务进度，结束时保存每一行的结果
+func RunImportJob(db *gorm.DB, job *models.ImportJob, records []ImportRecord) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Import job %d panicked: %v", job.ID, r)
+			finishImportJob(db, job, fmt.Errorf("internal error: %v", r))
+		}
+	}()
+
+	job.Status = models.ImportRunning
+	job.Total = len(records)
+	db.Model(job).Updates(map[string]interface{}{"status": job.Status, "total": job.Total})
+
+	seen := make(map[string]int)
+	var createdIDs, mergedIDs []uint
+	seriesIDs := make(map[uint]bool)
+	job.Report = make([]models.ImportRowResult, 0, len(records))
+	for i := range records {
+		result := importRecord(db, job, &records[i], seen)
+		switch result.Status {
+		case models.ImportRowCreated:
+			job.Created++
+			createdIDs = append(createdIDs, result.BookID)
+			if records[i].Book.SeriesID != nil {
+				seriesIDs[*records[i].Book.SeriesID] = true
+			}
+		case models.ImportRowDuplicate:
+			job.Duplicates++
+		case models.ImportRowMerged:
+			job.Merged++
+			if !job.DryRun {
+				mergedIDs = append(mergedIDs, result.BookID)
+				if records[i].Book.SeriesID != nil {
+					seriesIDs[*records[i].Book.SeriesID] = true
+				}
+			}
+		case models.ImportRowError:
+			job.Failed++
+		}
+		job.Report = append(job.Report, result)
+		job.Processed++
+		if job.Processed%importProgressEvery == 0 {
+			db.Model(job).Updates(map[string]interface{}{
+				"processed": job.Processed, "created": job.Created, "duplicates": job.Duplicates, "merged": job.Merged, "failed": job.Failed,
+			})
+		}
+	}
+
+	// 新书的搜索索引、补全和相关缓存在全部导入后统一更新
+	if len(createdIDs) > 0 {
+		SyncSearchIndex(createdIDs...)
+		for _, id := range createdIDs {
+			SuggestBookRelated(db, id)
+		}
+		TriggerSavedSearches()
+	}
+	if len(mergedIDs) > 0 {
+		for _, id := range mergedIDs {
+			config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", id))
+		}
+		SyncSearchIndex(mergedIDs...)
+		// 合并的书籍可能重新进入待审核，不再出现在标签云中
+		InvalidateTagCloud()
+	}
+	for seriesID := range seriesIDs {
+		InvalidateSeriesBooks(&seriesID)
+	}
+	finishImportJob(db, job, nil)
+}
+
+// importRecord 处理一行：校验、去重，非试运行时创建书籍或与已有书籍合并
+func importRecord(db *gorm.DB, job *models.ImportJob, record *ImportRecord, seen map[string]int) models.ImportRowResult {
+	result := models.ImportRowResult{Row: record.Row, Title: strings.TrimSpace(record.Book.Title)}
+	if errs := validateImportRecord(db, record); len(errs) > 0 {
+		result.Status, result.Errors = models.ImportRowError, errs
+		return result
+	}
+	book := &record.Book
+	result.Warnings = missingImportFiles(record)
+
+	keys := importDedupeKeys(record)
+	for _, key := range keys {
+		if row, ok := seen[key]; ok {
+			result.Status, result.DuplicateOfRow = models.ImportRowDuplicate, row
+			return result
+		}
+	}
+	for _, key := range keys {
+		seen[key] = record.Row
+	}
+	if existing := findDuplicateBook(db, record); existing != nil {
+		result.DuplicateOf = existing.ID
+		if !job.Merge {
+			result.Status = models.ImportRowDuplicate
+			return result
+		}
+		result.Status, result.BookID = models.ImportRowMerged, existing.ID
+		if job.DryRun {
+			return result
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			files, err := mergeImportRecord(tx, existing, record, job.UserID)
+			result.Files = files
+			return err
+		})
+		if err != nil {
+			RemoveBlobFiles(record.blobHashes)
+			result.Status, result.BookID = models.ImportRowError, 0
+			result.Errors = []string{"failed to merge book: " + err.Error()}
+		}
+		return result
+	}
+
+	if job.DryRun {
+		result.Status = models.ImportRowValid
+		return result
+	}
+	// 导入的书籍与直接上传的一样需要审核后才公开
+	now := time.Now()
+	book.UserID, book.Status, book.SubmittedAt = job.UserID, models.BookPending, &now
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := resolveImportSeries(tx, record); err != nil {
+			return err
+		}
+		if err := CreateBook(tx, book, record.Contributors); err != nil {
+			return err
+		}
+		files, _, err := saveImportExtras(tx, book, record, job.UserID)
+		result.Files = files
+		if err != nil {
+			return err
+		}
+		if err := RecordInitialBookStatus(tx, book); err != nil {
+			return err
+		}
+		return RecordBookRevision(tx, nil, models.BookRevision{BookID: book.ID, UserID: job.UserID, Action: models.RevisionCreate})
+	})
+	if err != nil {
+		RemoveBlobFiles(record.blobHashes)
+		// 与并发创建的书籍在唯一索引上冲突
+		if existing := FindISBNConflict(db, book.ISBN13, 0); existing != nil {
+			result.Status, result.DuplicateOf = models.ImportRowDuplicate, existing.ID
+			return result
+		}
+		result.Status, result.Errors = models.ImportRowError, []string{"failed to create book: " + err.Error()}
+		return result
+	}
+	result.Status, result.BookID = models.ImportRowCreated, book.ID
+	return result
+}
+
+// resolveImportSeries 按名称匹配不到系列且允许创建时，在事务中创建系列
+func resolveImportSeries(tx *gorm.DB, record *ImportRecord) error {
+	if record.Book.SeriesID != nil || record.SeriesTitle == "" || !record.CreateSeries {
+		return nil
+	}
+	series := models.Series{Title: record.SeriesTitle}
+	if err := tx.Where("title = ?", series.Title).FirstOrCreate(&series).Error; err != nil {
+		return err
+	}
+	record.Book.SeriesID = &series.ID
+	return nil
+}
+
+// saveImportExtras 保存书籍的标签、外部标识、文件和封面，返回保存的文件数量，以及是否添加了标签或文件
+func saveImportExtras(tx *gorm.DB, book *models.Book, record *ImportRecord, userID uint) (int, bool, error) {
+	tags := 0
+	if len(record.Tags) > 0 {
+		var err error
+		if _, tags, err = AddBookTags(tx, book.ID, userID, record.Tags); err != nil {
+			return 0, false, err
+		}
+	}
+	for _, identifier := range record.Identifiers {
+		identifier.ID, identifier.BookID = 0, book.ID
+		// 标识已属于其他书籍时保留原有关联
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&identifier).Error; err != nil {
+			return 0, false, err
+		}
+	}
+
+	saved := 0
+	for _, file := range record.Files {
+		bookFile, err := attachImportFile(tx, book.ID, userID, file, &record.blobHashes)
+		if err != nil {
+			return saved, false, err
+		}
+		if bookFile != nil {
+			saved++
+		}
+	}
+	if record.Cover != nil {
+		cover, err := attachImportFile(tx, book.ID, userID, *record.Cover, &record.blobHashes)
+		if err != nil {
+			return saved, false, err
+		}
+		if cover != nil {
+			saved++
+			if book.CoverImage == "" {
+				book.CoverImage = fmt.Sprintf("/books/%d/files/%d", book.ID, cover.ID)
+				if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Update("cover_image", book.CoverImage).Error; err != nil {
+					return saved, false, err
+				}
+			}
+		}
+	}
+	return saved, tags > 0 || saved > 0, nil
+}
+
+// attachImportFile 将本地文件保存为书籍文件，写入的 Blob 哈希追加到 hashes。文件不存在或书籍已有相同内容的文件时返回 nil
+func attachImportFile(tx *gorm.DB, bookID, userID uint, file ImportFile, hashes *[]string) (*models.BookFile, error) {
+	f, err := os.Open(file.Path)
+	if err != nil {
+		return nil, nil // 缺失的文件已记录在警告中
+	}
+	defer f.Close()
+	blob, err := SaveBlob(tx, f, file.MimeType)
+	if err != nil {
+		return nil, err
+	}
+	*hashes = append(*hashes, blob.Hash)
+
+	var count int64
+	if err := tx.Model(&models.BookFile{}).Where("book_id = ? AND blob_id = ?", bookID, blob.ID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		// 引用计数至少还有已存在的书籍文件，不会产生需要清理的 Blob
+		_, err := ReleaseBlob(tx, blob.ID)
+		return nil, err
+	}
+	bookFile := models.BookFile{BookID: bookID, BlobID: blob.ID, UserID: userID, FileName: file.Name}
+	if err := tx.Omit("Book", "Blob").Create(&bookFile).Error; err != nil {
+		return nil, err
+	}
+	return &bookFile, nil
+}
+
+// missingImportFiles 检查记录引用的文件是否存在
+func missingImportFiles(record *ImportRecord) []string {
+	files := record.Files
+	if record.Cover != nil {
+		files = append(files[:len(files):len(files)], *record.Cover)
+	}
+	var warnings []string
+	for _, file := range files {
+		if _, err := os.Stat(file.Path); err != nil {
+			warnings = append(warnings, "file not found: "+file.Name)
+		}
+	}
+	return warnings
+}
+
+// mergeImportRecord 用导入的信息补充已有书籍：只填写为空的字段，不覆盖已有内容，
+// 并追加标签、外部标识、文件和封面。返回保存的文件数量
+func mergeImportRecord(tx *gorm.DB, existing *models.Book, record *ImportRecord, userID uint) (int, error) {
+	if err := resolveImportSeries(tx, record); err != nil {
+		return 0, err
+	}
+	before := *existing
+	book := &record.Book
+	var fields []string
+	fill := func(field string, empty bool, set func()) {
+		if empty {
+			set()
+			fields = append(fields, field)
+		}
+	}
+	fill("Description", existing.Description == "" && book.Description != "", func() { existing.Description = book.Description })
+	fill("Publisher", existing.Publisher == "" && book.Publisher != "", func() { existing.Publisher = book.Publisher })
+	fill("PublishDate", existing.PublishDate == nil && book.PublishDate != nil, func() { existing.PublishDate = book.PublishDate })
+	fill("PageCount", existing.PageCount == 0 && book.PageCount > 0, func() { existing.PageCount = book.PageCount })
+	fill("Language", existing.Language == "" && book.Language != "", func() { existing.Language = book.Language })
+	fill("Format", existing.Format == "" && book.Format != "", func() { existing.Format = book.Format })
+	fill("Edition", existing.Edition == "" && book.Edition != "", func() { existing.Edition = book.Edition })
+	fill("CoverImage", existing.CoverImage == "" && book.CoverImage != "", func() { existing.CoverImage = book.CoverImage })
+	if existing.Category == "" && book.Category != "" {
+		existing.Category, existing.CategoryID = book.Category, book.CategoryID
+		fields = append(fields, "Category", "CategoryID")
+	}
+	if existing.SeriesID == nil && book.SeriesID != nil {
+		existing.SeriesID, existing.SeriesIndex = book.SeriesID, book.SeriesIndex
+		fields = append(fields, "SeriesID", "SeriesIndex")
+	}
+	if existing.ISBN13 == nil && book.ISBN13 != nil && FindISBNConflict(tx, book.ISBN13, existing.ID) == nil {
+		existing.ISBN13, existing.ISBN10 = book.ISBN13, book.ISBN10
+		fields = append(fields, "ISBN13", "ISBN10")
+	}
+	if len(fields) > 0 {
+		if err := tx.Model(existing).Select(fields).Updates(existing).Error; err != nil {
+			return 0, err
+		}
+	}
+	// 系列失效使用合并后的系列
+	book.SeriesID = existing.SeriesID
+	files, added, err := saveImportExtras(tx, existing, record, userID)
+	if err != nil {
+		return files, err
+	}
+	if err := RecordBookRevision(tx, &before, models.BookRevision{BookID: existing.ID, UserID: userID, Action: models.RevisionImport}); err != nil {
+		return files, err
+	}
+	// 合并到已发布的书籍与直接修改一样需要重新审核
+	if added {
+		return files, ResubmitBook(tx, existing, userID)
+	}
+	return files, ResubmitEditedBook(tx, existing, &before, userID)
+}
+
+// finishImportJob 保存导入任务的最终状态和报告
+func finishImportJob(db *gorm.DB, job *models.ImportJob, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = models.ImportCompleted
+	if err != nil {
+		job.Status, job.Error = models.ImportFailed, err.Error()
+	}
+	if err := db.Model(job).Select("status", "processed", "created", "duplicates", "merged", "failed", "error", "report", "finished_at").Updates(job).Error; err != nil {
+		log.Printf("Failed to save import job %d: %v", job.ID, err)
+	}
+}
+
+// FailInterruptedImports 服务重启时，将上次未完成的导入任务标记为失败
+func FailInterruptedImports(db *gorm.DB) error {
+	return db.Model(&models.ImportJob{}).Where("status IN ?", []string{models.ImportPending, models.ImportRunning}).
+		Updates(map[string]interface{}{"status": models.ImportFailed, "error": "interrupted by server restart", "finished_at": time.Now()}).Error
+}
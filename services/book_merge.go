@@ -0,0 +1,488 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 判断两本书可能重复的依据
+const (
+	DuplicateByISBN   = "isbn"         // ISBN-10 与 ISBN-13 指向同一本书
+	DuplicateByTitle  = "title_author" // 正题名和作者相似
+	DuplicateByCover  = "cover"        // 封面文件内容或地址相同
+	duplicateMinScore = 0.85           // 书名和作者的相似度达到该值时报告
+	// duplicateBlockLimit 同一书名或作者下的书籍超过该数量时不再两两比较，通常是"未知作者"之类的占位值
+	duplicateBlockLimit = 200
+)
+
+// DuplicateBook 重复报告中的书籍摘要
+type DuplicateBook struct {
+	ID         uint      `json:"id"`
+	Title      string    `json:"title"`
+	Author     string    `json:"author"`
+	ISBN10     *string   `json:"isbn10" gorm:"column:isbn10"`
+	ISBN13     *string   `json:"isbn13" gorm:"column:isbn13"`
+	Publisher  string    `json:"publisher"`
+	CoverImage string    `json:"cover_image"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DuplicateCandidate 可能重复的一对书籍，Score 为 0-1 的可信度
+type DuplicateCandidate struct {
+	Book    DuplicateBook `json:"book"`
+	Other   DuplicateBook `json:"other"`
+	Score   float64       `json:"score"`
+	Reasons []string      `json:"reasons"`
+}
+
+// bookFileURL 书籍文件的下载地址，导入的封面以此形式保存在 cover_image 中
+var bookFileURL = regexp.MustCompile(`^/books/(\d+)/files/(\d+)$`)
+
+// FindDuplicateBooks 扫描全部书籍，返回可能重复的书籍对，按可信度从高到低排列。
+// 书名和作者只在相同正题名或相同第一作者的书籍之间比较；两本书都有 ISBN 且不同时视为不同版本，不按书名报告。
+// 管理员确认过不是重复的书籍对不会出现在结果中
+func FindDuplicateBooks(db *gorm.DB, limit int) ([]DuplicateCandidate, error) {
+	var books []DuplicateBook
+	if err := db.Model(&models.Book{}).Select("id, title, author, isbn10, isbn13, publisher, cover_image, created_at").
+		Order("id").Find(&books).Error; err != nil {
+		return nil, err
+	}
+	coverKeys, err := bookCoverKeys(db, books)
+	if err != nil {
+		return nil, err
+	}
+	var dismissals []models.BookDuplicateDismissal
+	if err := db.Find(&dismissals).Error; err != nil {
+		return nil, err
+	}
+	dismissed := make(map[[2]uint]bool, len(dismissals))
+	for _, d := range dismissals {
+		dismissed[[2]uint{d.BookID, d.OtherID}] = true
+	}
+
+	pairs := make(map[[2]uint]*DuplicateCandidate)
+	add := func(a, b int, reason string, score float64) {
+		x, y := books[a], books[b]
+		if x.ID > y.ID {
+			x, y = y, x
+		}
+		key := [2]uint{x.ID, y.ID}
+		if x.ID == y.ID || dismissed[key] {
+			return
+		}
+		pair := pairs[key]
+		if pair == nil {
+			pair = &DuplicateCandidate{Book: x, Other: y}
+			pairs[key] = pair
+		}
+		for _, r := range pair.Reasons {
+			if r == reason {
+				pair.Score = max(pair.Score, score)
+				return
+			}
+		}
+		pair.Reasons = append(pair.Reasons, reason)
+		pair.Score = max(pair.Score, score)
+	}
+
+	// ISBN：旧数据中可能只保存了 ISBN-10，或保存了未规范化的写法
+	byISBN := make(map[string][]int)
+	for i, book := range books {
+		if book.ISBN13 != nil {
+			if _, isbn13, err := utils.ParseISBN(*book.ISBN13); err == nil {
+				byISBN[isbn13] = append(byISBN[isbn13], i)
+			}
+		}
+	}
+	for i, book := range books {
+		if book.ISBN10 != nil {
+			if _, isbn13, err := utils.ParseISBN(*book.ISBN10); err == nil && (book.ISBN13 == nil || *book.ISBN13 != isbn13) {
+				byISBN[isbn13] = append(byISBN[isbn13], i)
+			}
+		}
+	}
+	for _, group := range byISBN {
+		eachPair(group, func(a, b int) { add(a, b, DuplicateByISBN, 1) })
+	}
+
+	// 封面
+	byCover := make(map[string][]int)
+	for i, book := range books {
+		if key := coverKeys[book.ID]; key != "" {
+			byCover[key] = append(byCover[key], i)
+		}
+	}
+	for _, group := range byCover {
+		if len(group) <= duplicateBlockLimit {
+			eachPair(group, func(a, b int) { add(a, b, DuplicateByCover, 0.95) })
+		}
+	}
+
+	// 书名和作者
+	byTitle := make(map[string][]int)
+	byAuthor := make(map[string][]int)
+	for i, book := range books {
+		if key := utils.TitleKey(book.Title); key != "" {
+			byTitle[key] = append(byTitle[key], i)
+		}
+		if key := utils.AuthorKey(book.Author); key != "" {
+			byAuthor[key] = append(byAuthor[key], i)
+		}
+	}
+	compare := func(a, b int) {
+		x, y := books[a], books[b]
+		if x.ISBN13 != nil && y.ISBN13 != nil && *x.ISBN13 != *y.ISBN13 {
+			return
+		}
+		score := utils.TitleSimilarity(x.Title, y.Title)
+		if x.Author != "" && y.Author != "" {
+			score = 0.7*score + 0.3*utils.AuthorSimilarity(x.Author, y.Author)
+		} else {
+			score *= 0.9
+		}
+		if score >= duplicateMinScore {
+			add(a, b, DuplicateByTitle, score)
+		}
+	}
+	for _, blocks := range []map[string][]int{byTitle, byAuthor} {
+		for _, group := range blocks {
+			if len(group) <= duplicateBlockLimit {
+				eachPair(group, compare)
+			}
+		}
+	}
+
+	candidates := make([]DuplicateCandidate, 0, len(pairs))
+	for _, pair := range pairs {
+		candidates = append(candidates, *pair)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		if candidates[i].Book.ID != candidates[j].Book.ID {
+			return candidates[i].Book.ID < candidates[j].Book.ID
+		}
+		return candidates[i].Other.ID < candidates[j].Other.ID
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// eachPair 对分组中的每两个元素调用 fn
+func eachPair(group []int, fn func(a, b int)) {
+	for i := 0; i < len(group); i++ {
+		for j := i + 1; j < len(group); j++ {
+			fn(group[i], group[j])
+		}
+	}
+}
+
+// bookCoverKeys 封面的比较依据：上传的封面使用文件内容的 SHA-256，外部封面使用地址本身
+func bookCoverKeys(db *gorm.DB, books []DuplicateBook) (map[uint]string, error) {
+	keys := make(map[uint]string)
+	fileIDs := make(map[uint]uint)
+	for _, book := range books {
+		if book.CoverImage == "" {
+			continue
+		}
+		if m := bookFileURL.FindStringSubmatch(book.CoverImage); m != nil {
+			fileID, _ := strconv.ParseUint(m[2], 10, 64)
+			fileIDs[uint(fileID)] = book.ID
+			continue
+		}
+		keys[book.ID] = "url:" + strings.ToLower(book.CoverImage)
+	}
+	if len(fileIDs) == 0 {
+		return keys, nil
+	}
+	ids := make([]uint, 0, len(fileIDs))
+	for id := range fileIDs {
+		ids = append(ids, id)
+	}
+	var rows []struct {
+		ID   uint
+		Hash string
+	}
+	err := db.Model(&models.BookFile{}).Select("book_files.id, file_blobs.hash").
+		Joins("JOIN file_blobs ON file_blobs.id = book_files.blob_id").
+		Where("book_files.id IN ?", ids).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		keys[fileIDs[row.ID]] = "sha256:" + row.Hash
+	}
+	return keys, nil
+}
+
+// DismissDuplicate 记录两本书不是重复，之后的重复检测不再报告
+func DismissDuplicate(db *gorm.DB, bookID, otherID uint) error {
+	if bookID > otherID {
+		bookID, otherID = otherID, bookID
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.BookDuplicateDismissal{BookID: bookID, OtherID: otherID}).Error
+}
+
+// MergeBooks 将重复的书籍合并到目标书籍：评论、阅读关系、标签、文件、作者署名、外部标识和通知都转移到目标书籍，
+// 目标书籍为空的字段用被合并书籍的信息补充，最后删除被合并的书籍并保留从其ID到目标书籍的重定向。
+// 合并由管理员执行，与管理员回滚一样不会让已发布的目标书籍重新进入审核。
+// 应在事务中调用，返回需要在提交后清理的 Blob 哈希
+func MergeBooks(tx *gorm.DB, source, target *models.Book) ([]string, error) {
+	before := *target
+	if err := mergeBookFields(tx, source, target); err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Model(&models.Comment{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := mergeBookRelations(tx, source.ID, target.ID); err != nil {
+		return nil, err
+	}
+	if err := mergeBookTags(tx, source.ID, target.ID); err != nil {
+		return nil, err
+	}
+	orphaned, err := mergeBookFiles(tx, source, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := mergeBookContributors(tx, source.ID, target.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.BookIdentifier{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+		return nil, err
+	}
+
+	// 同一个保存的搜索对同一本书只通知一次
+	var notified []uint
+	if err := tx.Model(&models.Notification{}).Where("book_id = ? AND saved_search_id IS NOT NULL", target.ID).
+		Pluck("saved_search_id", &notified).Error; err != nil {
+		return nil, err
+	}
+	if len(notified) > 0 {
+		if err := tx.Where("book_id = ? AND saved_search_id IN ?", source.ID, notified).Delete(&models.Notification{}).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Model(&models.Notification{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.ReadingImportEntry{}).Where("book_id = ?", source.ID).Update("book_id", target.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("book_id = ? OR other_id = ?", source.ID, source.ID).Delete(&models.BookDuplicateDismissal{}).Error; err != nil {
+		return nil, err
+	}
+
+	// 之前合并到被合并书籍的ID直接指向目标书籍，避免多次跳转
+	if err := tx.Model(&models.BookRedirect{}).Where("to_id = ?", source.ID).Update("to_id", target.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Create(&models.BookRedirect{FromID: source.ID, ToID: target.ID}).Error; err != nil {
+		return nil, err
+	}
+	if err := RemoveBookFromIndex(tx, source.ID); err != nil {
+		return nil, err
+	}
+	if err := RecordBookRevision(tx, &before, models.BookRevision{BookID: target.ID, Action: models.RevisionMerge}); err != nil {
+		return nil, err
+	}
+	return orphaned, tx.Delete(source).Error
+}
+
+// mergeBookFields 用被合并书籍的信息补充目标书籍为空的字段，不覆盖已有内容
+func mergeBookFields(tx *gorm.DB, source, target *models.Book) error {
+	var fields []string
+	fill := func(field string, empty bool, set func()) {
+		if empty {
+			set()
+			fields = append(fields, field)
+		}
+	}
+	fill("Description", target.Description == "" && source.Description != "", func() { target.Description = source.Description })
+	fill("Publisher", target.Publisher == "" && source.Publisher != "", func() { target.Publisher = source.Publisher })
+	fill("PublishDate", target.PublishDate == nil && source.PublishDate != nil, func() { target.PublishDate = source.PublishDate })
+	fill("PageCount", target.PageCount == 0 && source.PageCount > 0, func() { target.PageCount = source.PageCount })
+	fill("Language", target.Language == "" && source.Language != "", func() { target.Language = source.Language })
+	fill("Format", target.Format == "" && source.Format != "", func() { target.Format = source.Format })
+	fill("Edition", target.Edition == "" && source.Edition != "", func() { target.Edition = source.Edition })
+	// 封面如果是被合并书籍的文件，地址在转移文件时更新
+	fill("CoverImage", target.CoverImage == "" && source.CoverImage != "", func() { target.CoverImage = source.CoverImage })
+	if target.Category == "" && source.Category != "" {
+		target.Category, target.CategoryID = source.Category, source.CategoryID
+		fields = append(fields, "Category", "CategoryID")
+	}
+	if target.SeriesID == nil && source.SeriesID != nil {
+		target.SeriesID, target.SeriesIndex = source.SeriesID, source.SeriesIndex
+		fields = append(fields, "SeriesID", "SeriesIndex")
+	}
+	if isbn10, isbn13 := source.ISBN10, source.ISBN13; isbn13 != nil {
+		// ISBN 有唯一索引，软删除的书籍仍然占用，先从被合并的书籍上移除
+		if err := tx.Model(source).Updates(map[string]interface{}{"isbn10": nil, "isbn13": nil}).Error; err != nil {
+			return err
+		}
+		if target.ISBN13 == nil {
+			target.ISBN13, target.ISBN10 = isbn13, isbn10
+			fields = append(fields, "ISBN13", "ISBN10")
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return tx.Model(target).Select(fields).Updates(target).Error
+}
+
+// mergeBookRelations 转移阅读关系。同一用户在两本书上有相同的关系时只保留一条，补充缺少的评分和读完日期；
+// 阅读状态不同时保留更靠后的状态，例如已读和想读合并为已读
+func mergeBookRelations(tx *gorm.DB, sourceID, targetID uint) error {
+	var sources, targets []models.UserBookRelation
+	if err := tx.Where("book_id = ?", sourceID).Find(&sources).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("book_id = ?", targetID).Find(&targets).Error; err != nil {
+		return err
+	}
+	for i := range sources {
+		relation := &sources[i]
+		for j := range targets {
+			existing := &targets[j]
+			if existing.ID == 0 || existing.UserID != relation.UserID {
+				continue
+			}
+			sourceRank, targetRank := readingStatusRank[relation.RelationType], readingStatusRank[existing.RelationType]
+			if existing.RelationType != relation.RelationType && (sourceRank == 0 || targetRank == 0) {
+				continue // 收藏与阅读状态互不影响
+			}
+			if sourceRank > targetRank {
+				// 被合并书籍上的状态更靠后，替换目标书籍上的状态
+				if err := tx.Delete(existing).Error; err != nil {
+					return err
+				}
+				existing.ID = 0
+				continue
+			}
+			var fields []string
+			if existing.Rating == nil && relation.Rating != nil {
+				existing.Rating = relation.Rating
+				fields = append(fields, "Rating")
+			}
+			if existing.ReadAt == nil && relation.ReadAt != nil && existing.RelationType == models.RelationRead {
+				existing.ReadAt = relation.ReadAt
+				fields = append(fields, "ReadAt")
+			}
+			if len(fields) > 0 {
+				if err := tx.Model(existing).Select(fields).Updates(existing).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Delete(relation).Error; err != nil {
+				return err
+			}
+			break
+		}
+	}
+	// 包括已删除的关系，保留完整的历史
+	return tx.Unscoped().Model(&models.UserBookRelation{}).Where("book_id = ?", sourceID).Update("book_id", targetID).Error
+}
+
+// mergeBookTags 转移标签，目标书籍已有的标签不重复添加
+func mergeBookTags(tx *gorm.DB, sourceID, targetID uint) error {
+	var existing []uint
+	if err := tx.Model(&models.BookTag{}).Where("book_id = ?", targetID).Pluck("tag_id", &existing).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		if err := tx.Where("book_id = ? AND tag_id IN ?", sourceID, existing).Delete(&models.BookTag{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.BookTag{}).Where("book_id = ?", sourceID).Update("book_id", targetID).Error
+}
+
+// mergeBookFiles 转移书籍文件，目标书籍已有相同内容的文件时删除重复的一份并释放引用。
+// 指向被合并书籍文件的封面地址随之更新
+func mergeBookFiles(tx *gorm.DB, source, target *models.Book) ([]string, error) {
+	var files, targetFiles []models.BookFile
+	if err := tx.Where("book_id = ?", source.ID).Find(&files).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("book_id = ?", target.ID).Find(&targetFiles).Error; err != nil {
+		return nil, err
+	}
+	blobFiles := make(map[uint]uint, len(targetFiles))
+	for _, file := range targetFiles {
+		blobFiles[file.BlobID] = file.ID
+	}
+
+	var orphaned []string
+	moved := make(map[uint]uint, len(files)) // 被合并书籍的文件ID -> 目标书籍上的文件ID
+	for _, file := range files {
+		if fileID, ok := blobFiles[file.BlobID]; ok {
+			if err := tx.Delete(&file).Error; err != nil {
+				return nil, err
+			}
+			hash, err := ReleaseBlob(tx, file.BlobID)
+			if err != nil {
+				return nil, err
+			}
+			if hash != "" {
+				orphaned = append(orphaned, hash)
+			}
+			moved[file.ID] = fileID
+			continue
+		}
+		if err := tx.Model(&file).Update("book_id", target.ID).Error; err != nil {
+			return nil, err
+		}
+		blobFiles[file.BlobID] = file.ID
+		moved[file.ID] = file.ID
+	}
+
+	if m := bookFileURL.FindStringSubmatch(target.CoverImage); m != nil && m[1] == strconv.FormatUint(uint64(source.ID), 10) {
+		fileID, _ := strconv.ParseUint(m[2], 10, 64)
+		cover := ""
+		if id, ok := moved[uint(fileID)]; ok {
+			cover = fmt.Sprintf("/books/%d/files/%d", target.ID, id)
+		}
+		target.CoverImage = cover
+		if err := tx.Model(target).Update("cover_image", cover).Error; err != nil {
+			return nil, err
+		}
+	}
+	return orphaned, nil
+}
+
+// mergeBookContributors 补充目标书籍没有的署名角色（如只有被合并的书籍记录了译者），其余署名以目标书籍为准
+func mergeBookContributors(tx *gorm.DB, sourceID, targetID uint) error {
+	var roles []string
+	if err := tx.Model(&models.BookAuthor{}).Where("book_id = ?", targetID).Distinct().Pluck("role", &roles).Error; err != nil {
+		return err
+	}
+	if len(roles) > 0 {
+		if err := tx.Where("book_id = ? AND role IN ?", sourceID, roles).Delete(&models.BookAuthor{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&models.BookAuthor{}).Where("book_id = ?", sourceID).Update("book_id", targetID).Error
+}
+
+// ResolveBookRedirect 返回已合并书籍当前对应的书籍ID
+func ResolveBookRedirect(db *gorm.DB, bookID uint) (uint, bool) {
+	var redirect models.BookRedirect
+	if err := db.First(&redirect, "from_id = ?", bookID).Error; err != nil {
+		return 0, false
+	}
+	return redirect.ToID, true
+}
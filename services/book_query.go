@@ -0,0 +1,314 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BookQuery 编译后的高级搜索语句
+type BookQuery struct {
+	Raw   string
+	Terms []string // 用于高亮的词项
+	where clause.Expr
+	rank  *SearchQuery // 由未取反的全文搜索词、书名和作者条件组成，用于相关度排序
+}
+
+// ParseBookQuery 解析并编译高级搜索语句（语法见 utils.ParseQuery），语法或取值错误时返回 *utils.QueryError
+func ParseBookQuery(db *gorm.DB, input string) (*BookQuery, error) {
+	node, err := utils.ParseQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	c := &queryCompiler{db: db}
+	where, err := c.compile(node, false)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &BookQuery{Raw: strings.TrimSpace(input), where: where}
+	if len(c.rankTexts) > 0 {
+		q.rank = NewSearchQuery(db, strings.Join(c.rankTexts, " "))
+		q.Terms = q.rank.Terms
+	}
+	return q, nil
+}
+
+// Scope 按搜索语句筛选书籍
+func (q *BookQuery) Scope(query *gorm.DB) *gorm.DB {
+	return query.Where(q.where)
+}
+
+// OrderByRelevance 按相关度从高到低排序；语句中没有全文搜索词时按 ID 倒序
+func (q *BookQuery) OrderByRelevance(query *gorm.DB) *gorm.DB {
+	if q.rank == nil || q.rank.fallback() {
+		return query.Order("books.id DESC")
+	}
+	query = query.Joins("LEFT JOIN book_search_docs ON book_search_docs.book_id = books.id")
+	return q.rank.OrderByRelevance(query)
+}
+
+// score 相关度的查询字段，需要与 OrderByRelevance 一起使用
+func (q *BookQuery) score() clause.Expr {
+	if q.rank == nil || q.rank.fallback() {
+		return clause.Expr{SQL: "0"}
+	}
+	return q.rank.relevance()
+}
+
+type queryCompiler struct {
+	db        *gorm.DB
+	rankTexts []string
+}
+
+func (c *queryCompiler) compile(node utils.QueryNode, negated bool) (clause.Expr, error) {
+	switch n := node.(type) {
+	case *utils.QueryTerm:
+		return c.compileTerm(n, negated)
+	case *utils.QueryNot:
+		inner, err := c.compile(n.Node, !negated)
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		// 条件涉及可为空的字段（出版日期、分类等）时结果可能为 NULL，NOT NULL 仍为 NULL，
+		// 会把这些书籍排除在外；先将 NULL 视为不匹配再取反
+		return clause.Expr{SQL: "NOT COALESCE((?), FALSE)", Vars: []interface{}{inner}}, nil
+	case *utils.QueryAnd:
+		return c.compileGroup(n.Nodes, " AND ", negated)
+	case *utils.QueryOr:
+		return c.compileGroup(n.Nodes, " OR ", negated)
+	}
+	return clause.Expr{}, fmt.Errorf("unsupported query node %T", node)
+}
+
+func (c *queryCompiler) compileGroup(nodes []utils.QueryNode, op string, negated bool) (clause.Expr, error) {
+	parts := make([]string, len(nodes))
+	vars := make([]interface{}, len(nodes))
+	for i, node := range nodes {
+		expr, err := c.compile(node, negated)
+		if err != nil {
+			return clause.Expr{}, err
+		}
+		parts[i] = "(?)"
+		vars[i] = expr
+	}
+	return clause.Expr{SQL: strings.Join(parts, op), Vars: vars}, nil
+}
+
+func (c *queryCompiler) compileTerm(term *utils.QueryTerm, negated bool) (clause.Expr, error) {
+	invalid := func(message string) error {
+		return &utils.QueryError{Position: term.Position, Token: term.Token, Message: message}
+	}
+
+	switch term.Field {
+	case "":
+		if !negated {
+			c.rankTexts = append(c.rankTexts, term.Value)
+		}
+		if term.Phrase {
+			return c.matchDoc("book_search_docs.title, book_search_docs.authors, book_search_docs.description, book_search_docs.tags", term.Value, "books.title LIKE ? OR books.author LIKE ?"), nil
+		}
+		search := NewSearchQuery(c.db, term.Value)
+		return search.condition(c.db), nil
+
+	case "title":
+		if !negated {
+			c.rankTexts = append(c.rankTexts, term.Value)
+		}
+		return c.matchDoc("book_search_docs.title", term.Value, "books.title LIKE ?"), nil
+
+	case "author":
+		if !negated {
+			c.rankTexts = append(c.rankTexts, term.Value)
+		}
+		return c.matchDoc("book_search_docs.authors", term.Value, "books.author LIKE ?"), nil
+
+	case "tag":
+		tag, err := FindTag(c.db, term.Value)
+		if err != nil {
+			if errors.Is(err, ErrInvalidTag) {
+				return clause.Expr{}, invalid("invalid tag")
+			}
+			// 不存在的标签不匹配任何书籍
+			return clause.Expr{SQL: "1 = 0"}, nil
+		}
+		return clause.Expr{
+			SQL:  "books.id IN (?)",
+			Vars: []interface{}{c.db.Model(&models.BookTag{}).Select("book_id").Where("tag_id = ?", tag.ID)},
+		}, nil
+
+	case "category":
+		category, err := ResolveCategory(c.db, term.Value)
+		if err != nil {
+			return clause.Expr{SQL: "books.category = ?", Vars: []interface{}{term.Value}}, nil
+		}
+		ids, err := CategoryDescendantIDs(c.db, category.ID)
+		if err != nil {
+			ids = []uint{category.ID}
+		}
+		return clause.Expr{SQL: "books.category_id IN ?", Vars: []interface{}{ids}}, nil
+
+	case "language":
+		code, ok := utils.NormalizeLanguage(term.Value)
+		if !ok {
+			return clause.Expr{}, invalid("invalid language " + term.Value)
+		}
+		return clause.Expr{SQL: "books.language = ?", Vars: []interface{}{code}}, nil
+
+	case "format":
+		format := strings.ToLower(term.Value)
+		if !IsValidBookFormat(format) {
+			return clause.Expr{}, invalid("invalid format " + term.Value + ", expected one of " + strings.Join(BookFormats, ", "))
+		}
+		return clause.Expr{SQL: "books.format = ?", Vars: []interface{}{format}}, nil
+
+	case "publisher":
+		return clause.Expr{SQL: "books.publisher LIKE ?", Vars: []interface{}{"%" + term.Value + "%"}}, nil
+
+	case "series":
+		return clause.Expr{
+			SQL:  "books.series_id IN (?)",
+			Vars: []interface{}{c.db.Model(&models.Series{}).Select("id").Where("title LIKE ?", "%"+term.Value+"%")},
+		}, nil
+
+	case "isbn":
+		_, isbn13, err := utils.ParseISBN(term.Value)
+		if err != nil {
+			return clause.Expr{}, invalid("invalid ISBN")
+		}
+		return clause.Expr{SQL: "books.isbn13 = ?", Vars: []interface{}{isbn13}}, nil
+
+	case "year":
+		from, to, err := parseQueryRange(term.Value, maxQueryYear)
+		if err != nil {
+			return clause.Expr{}, invalid(err.Error())
+		}
+		var parts []string
+		var vars []interface{}
+		if from != nil {
+			parts = append(parts, "books.publish_date >= ?")
+			vars = append(vars, time.Date(*from, 1, 1, 0, 0, 0, 0, time.UTC))
+		}
+		if to != nil && *to < maxQueryYear {
+			parts = append(parts, "books.publish_date < ?")
+			vars = append(vars, time.Date(*to+1, 1, 1, 0, 0, 0, 0, time.UTC))
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "books.publish_date IS NOT NULL")
+		}
+		return clause.Expr{SQL: strings.Join(parts, " AND "), Vars: vars}, nil
+
+	case "pages":
+		from, to, err := parseQueryRange(term.Value, maxQueryPages)
+		if err != nil {
+			return clause.Expr{}, invalid(err.Error())
+		}
+		// 页数为 0 表示未知，不参与比较
+		parts := []string{"books.page_count > 0"}
+		var vars []interface{}
+		if from != nil {
+			parts = append(parts, "books.page_count >= ?")
+			vars = append(vars, *from)
+		}
+		if to != nil {
+			parts = append(parts, "books.page_count <= ?")
+			vars = append(vars, *to)
+		}
+		return clause.Expr{SQL: strings.Join(parts, " AND "), Vars: vars}, nil
+	}
+	return clause.Expr{}, invalid("unsupported field " + term.Field)
+}
+
+// matchDoc 在检索文档的指定字段中按短语匹配；值过短时退回到书籍字段的模糊匹配
+func (c *queryCompiler) matchDoc(columns, value, fallback string) clause.Expr {
+	value = utils.ToSimplified(strings.TrimSpace(value))
+	if utf8.RuneCountInString(value) < minSearchRunes {
+		search := "%" + value + "%"
+		vars := make([]interface{}, strings.Count(fallback, "?"))
+		for i := range vars {
+			vars[i] = search
+		}
+		return clause.Expr{SQL: fallback, Vars: vars}
+	}
+	phrase := `"` + strings.ReplaceAll(value, `"`, " ") + `"`
+	return clause.Expr{
+		SQL: "books.id IN (?)",
+		Vars: []interface{}{c.db.Model(&models.BookSearchDoc{}).Select("book_id").
+			Where("MATCH("+columns+") AGAINST(? IN BOOLEAN MODE)", phrase)},
+	}
+}
+
+// 年份和页数范围的上限，超出时数据库无法表示对应的日期，或比较没有意义
+const (
+	maxQueryYear  = 9999
+	maxQueryPages = 100000
+)
+
+// parseQueryRange 解析范围：2000、>2000、>=2000、<2000、<=2000、2000..2010，返回闭区间的上下界，
+// 上下界都必须在 0 到 max 之间
+func parseQueryRange(value string, max int) (from, to *int, err error) {
+	parse := func(s string) (*int, error) {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid number %q", s)
+		}
+		if n > max {
+			return nil, fmt.Errorf("number %d is out of range, expected at most %d", n, max)
+		}
+		return &n, nil
+	}
+	plusOne := func(n *int) *int { v := *n + 1; return &v }
+	minusOne := func(n *int) *int { v := *n - 1; return &v }
+
+	switch {
+	case strings.Contains(value, ".."):
+		low, high, _ := strings.Cut(value, "..")
+		if low != "" {
+			if from, err = parse(low); err != nil {
+				return nil, nil, err
+			}
+		}
+		if high != "" {
+			if to, err = parse(high); err != nil {
+				return nil, nil, err
+			}
+		}
+		if from == nil && to == nil {
+			return nil, nil, errors.New("empty range")
+		}
+		if from != nil && to != nil && *from > *to {
+			return nil, nil, errors.New("range start is greater than range end")
+		}
+	case strings.HasPrefix(value, ">="):
+		from, err = parse(value[2:])
+	case strings.HasPrefix(value, "<="):
+		to, err = parse(value[2:])
+	case strings.HasPrefix(value, ">"):
+		if from, err = parse(value[1:]); err == nil {
+			from = plusOne(from)
+		}
+	case strings.HasPrefix(value, "<"):
+		if to, err = parse(value[1:]); err == nil {
+			to = minusOne(to)
+		}
+	default:
+		if from, err = parse(value); err == nil {
+			to = from
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if (from != nil && *from > max) || (to != nil && *to < 0) {
+		return nil, nil, fmt.Errorf("range %q is out of range 0..%d", value, max)
+	}
+	return from, to, nil
+}
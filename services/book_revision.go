@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bookshare/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNothingToRevert = errors.New("book already matches this revision")
+	ErrRevertConflict  = errors.New("cannot revert")
+)
+
+// bookRevisionFields 记录修订的书籍字段：JSON 字段名和结构体字段名，按展示顺序排列
+var bookRevisionFields = []struct{ JSON, Field string }{
+	{"title", "Title"}, {"author", "Author"}, {"description", "Description"}, {"cover_image", "CoverImage"},
+	{"category", "Category"}, {"category_id", "CategoryID"}, {"publisher", "Publisher"}, {"publish_date", "PublishDate"},
+	{"page_count", "PageCount"}, {"language", "Language"}, {"format", "Format"}, {"edition", "Edition"},
+	{"isbn10", "ISBN10"}, {"isbn13", "ISBN13"}, {"series_id", "SeriesID"}, {"series_index", "SeriesIndex"},
+}
+
+// bookSnapshot 书籍中记录修订的字段，值为 JSON 形式，与接口返回的书籍一致
+func bookSnapshot(book *models.Book) map[string]interface{} {
+	data, _ := json.Marshal(book)
+	var values map[string]interface{}
+	json.Unmarshal(data, &values)
+	snapshot := make(map[string]interface{}, len(bookRevisionFields))
+	for _, field := range bookRevisionFields {
+		snapshot[field.JSON] = values[field.JSON]
+	}
+	return snapshot
+}
+
+// diffBookSnapshots 比较两个快照，old 为空表示新建的书籍，此时只列出有内容的字段
+func diffBookSnapshots(old, snapshot map[string]interface{}) []models.BookFieldChange {
+	var changes []models.BookFieldChange
+	for _, field := range bookRevisionFields {
+		before, after := old[field.JSON], snapshot[field.JSON]
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		if old == nil && (after == nil || after == "" || after == float64(0)) {
+			continue
+		}
+		changes = append(changes, models.BookFieldChange{Field: field.JSON, Old: before, New: after})
+	}
+	return changes
+}
+
+// RecordBookRevision 在事务中记录书籍的一次修改：重新读取修改后的书籍，与修改前的 before 比较。
+// before 为空表示新建；没有字段变化时不记录。书籍第一次记录修改时，先保存修改前的内容作为基线，以便回滚
+func RecordBookRevision(tx *gorm.DB, before *models.Book, revision models.BookRevision) error {
+	var after models.Book
+	if err := tx.First(&after, revision.BookID).Error; err != nil {
+		return err
+	}
+	var old map[string]interface{}
+	if before != nil {
+		old = bookSnapshot(before)
+	}
+	snapshot := bookSnapshot(&after)
+	changes := diffBookSnapshots(old, snapshot)
+	if before != nil && len(changes) == 0 {
+		return nil
+	}
+
+	if before != nil {
+		var count int64
+		if err := tx.Model(&models.BookRevision{}).Where("book_id = ?", revision.BookID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			baseline := models.BookRevision{BookID: revision.BookID, Action: models.RevisionBaseline, Snapshot: old, CreatedAt: before.UpdatedAt}
+			if err := tx.Create(&baseline).Error; err != nil {
+				return err
+			}
+		}
+	}
+	revision.Changes, revision.Snapshot = changes, snapshot
+	return tx.Create(&revision).Error
+}
+
+// RevertBook 将书籍恢复为指定修订时的内容，并记录一次回滚修订。
+// 修订中的 ISBN 已被其他书籍使用、或系列已删除时无法回滚；分类已删除时只恢复分类名称
+func RevertBook(tx *gorm.DB, book *models.Book, revision *models.BookRevision, userID uint) error {
+	var target models.Book
+	data, err := json.Marshal(revision.Snapshot)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &target); err != nil {
+		return err
+	}
+
+	current := bookSnapshot(book)
+	changed := make(map[string]bool)
+	var fields []string
+	for _, field := range bookRevisionFields {
+		if !reflect.DeepEqual(current[field.JSON], revision.Snapshot[field.JSON]) {
+			changed[field.Field] = true
+			fields = append(fields, field.Field)
+		}
+	}
+	if len(fields) == 0 {
+		return ErrNothingToRevert
+	}
+	if changed["ISBN13"] && FindISBNConflict(tx, target.ISBN13, book.ID) != nil {
+		return fmt.Errorf("%w: ISBN %s is used by another book", ErrRevertConflict, *target.ISBN13)
+	}
+	if changed["SeriesID"] && !ValidateBookSeries(tx, &target) {
+		return fmt.Errorf("%w: series no longer exists", ErrRevertConflict)
+	}
+	if changed["CategoryID"] && target.CategoryID != nil {
+		var count int64
+		tx.Model(&models.Category{}).Where("id = ?", *target.CategoryID).Count(&count)
+		if count == 0 {
+			target.CategoryID = nil
+		}
+	}
+
+	before := *book
+	if err := tx.Model(book).Select(fields).Updates(&target).Error; err != nil {
+		return err
+	}
+	if err := tx.First(book, book.ID).Error; err != nil {
+		return err
+	}
+	if changed["Author"] {
+		if err := SyncBookAuthorsFromString(tx, book); err != nil {
+			return err
+		}
+	}
+	return RecordBookRevision(tx, &before, models.BookRevision{
+		BookID: book.ID, UserID: userID, Action: models.RevisionRevert, RevertedTo: &revision.ID,
+	})
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"bookshare/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 书籍发布流程中的操作
+const (
+	BookActionSubmit    = "submit"    // 上传者提交审核
+	BookActionWithdraw  = "withdraw"  // 上传者撤回审核，回到草稿
+	BookActionApprove   = "approve"   // 管理员审核通过并发布
+	BookActionReject    = "reject"    // 管理员驳回
+	BookActionUnpublish = "unpublish" // 管理员下架已发布的书籍
+	BookActionEdit      = "edit"      // 修改已发布书籍的内容后自动重新提交审核，不能通过接口执行
+)
+
+var (
+	ErrInvalidBookAction = errors.New("invalid action, expected one of submit, withdraw, approve, reject, unpublish")
+	ErrBookActionDenied  = errors.New("action is not allowed")
+	ErrBookStatus        = errors.New("book status does not allow this action")
+	ErrReasonRequired    = errors.New("a reason is required")
+)
+
+// bookTransition 一个操作允许的起始状态、目标状态，以及是否由管理员执行、是否需要填写原因
+type bookTransition struct {
+	from      []string
+	to        string
+	moderator bool
+	reason    bool
+}
+
+var bookTransitions = map[string]bookTransition{
+	BookActionSubmit:    {from: []string{models.BookDraft, models.BookRejected, models.BookUnpublished}, to: models.BookPending},
+	BookActionWithdraw:  {from: []string{models.BookPending}, to: models.BookDraft},
+	BookActionApprove:   {from: []string{models.BookPending}, to: models.BookPublished, moderator: true},
+	BookActionReject:    {from: []string{models.BookPending}, to: models.BookRejected, moderator: true, reason: true},
+	BookActionUnpublish: {from: []string{models.BookPublished}, to: models.BookUnpublished, moderator: true, reason: true},
+}
+
+// PublishedBooks 只查询已发布的书籍，不区分可见范围；公开的列表和搜索使用 PublicBooks 或 VisibleBooks
+func PublishedBooks(query *gorm.DB) *gorm.DB {
+	return query.Where("books.status = ?", models.BookPublished)
+}
+
+// IsValidBookStatus 检查是否为有效的发布状态
+func IsValidBookStatus(status string) bool {
+	switch status {
+	case models.BookDraft, models.BookPending, models.BookPublished, models.BookRejected, models.BookUnpublished:
+		return true
+	}
+	return false
+}
+
+// RecordInitialBookStatus 记录新建书籍的初始状态，新建时直接提交审核记为一次提交
+func RecordInitialBookStatus(tx *gorm.DB, book *models.Book) error {
+	change := models.BookStatusChange{BookID: book.ID, UserID: book.UserID, Action: "create", ToStatus: book.Status}
+	if book.Status == models.BookPending {
+		change.Action = BookActionSubmit
+	}
+	return tx.Create(&change).Error
+}
+
+// TransitionBook 在事务中执行发布流程中的操作。asModerator 为 false 时只能执行上传者的操作（提交、撤回），
+// 且 userID 必须是上传者；管理员的操作会通知上传者，驳回和下架时必须填写原因
+func TransitionBook(tx *gorm.DB, book *models.Book, action string, userID uint, asModerator bool, reason string) error {
+	transition, ok := bookTransitions[action]
+	if !ok {
+		return ErrInvalidBookAction
+	}
+	if transition.moderator != asModerator || (!asModerator && book.UserID != userID) {
+		return ErrBookActionDenied
+	}
+	allowed := false
+	for _, status := range transition.from {
+		allowed = allowed || book.Status == status
+	}
+	if !allowed {
+		return fmt.Errorf("%w: book is %s", ErrBookStatus, book.Status)
+	}
+	reason = strings.TrimSpace(reason)
+	if transition.reason && reason == "" {
+		return ErrReasonRequired
+	}
+	if runes := []rune(reason); len(runes) > 500 {
+		reason = string(runes[:500])
+	}
+
+	from := book.Status
+	updates := map[string]interface{}{"status": transition.to, "status_reason": reason}
+	if transition.to == models.BookPending {
+		updates["submitted_at"] = time.Now()
+	}
+	if err := tx.Model(book).Updates(updates).Error; err != nil {
+		return err
+	}
+	change := models.BookStatusChange{BookID: book.ID, UserID: userID, Action: action, FromStatus: from, ToStatus: transition.to, Reason: reason}
+	if err := tx.Create(&change).Error; err != nil {
+		return err
+	}
+	if !asModerator {
+		return nil
+	}
+	return tx.Create(bookStatusNotification(book, action, reason)).Error
+}
+
+// ResubmitEditedBook 已发布的书籍内容被修改后重新进入待审核状态，审核通过前不再公开，避免绕过审核。
+// before 为修改前的书籍，修改记录修订的字段时才重新提交；需要在修改书籍的同一事务中调用
+func ResubmitEditedBook(tx *gorm.DB, book, before *models.Book, userID uint) error {
+	if before.Status != models.BookPublished {
+		return nil
+	}
+	var after models.Book
+	if err := tx.First(&after, book.ID).Error; err != nil {
+		return err
+	}
+	if len(diffBookSnapshots(bookSnapshot(before), bookSnapshot(&after))) == 0 {
+		return nil
+	}
+	return resubmitBook(tx, book, userID)
+}
+
+// ResubmitBook 为已发布的书籍添加标签、文件等不记录在修订中的内容后，同样重新进入待审核状态。
+// book 为修改前读取的书籍，需要在修改书籍的同一事务中调用
+func ResubmitBook(tx *gorm.DB, book *models.Book, userID uint) error {
+	if book.Status != models.BookPublished {
+		return nil
+	}
+	return resubmitBook(tx, book, userID)
+}
+
+// resubmitBook 将已发布的书籍改为待审核，并记录状态变化
+func resubmitBook(tx *gorm.DB, book *models.Book, userID uint) error {
+	now := time.Now()
+	updates := map[string]interface{}{"status": models.BookPending, "status_reason": "", "submitted_at": now}
+	if err := tx.Model(&models.Book{}).Where("id = ?", book.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	book.Status, book.StatusReason, book.SubmittedAt = models.BookPending, "", &now
+	change := models.BookStatusChange{
+		BookID: book.ID, UserID: userID, Action: BookActionEdit, FromStatus: models.BookPublished, ToStatus: models.BookPending,
+	}
+	return tx.Create(&change).Error
+}
+
+// bookStatusNotification 审核结果通知，驳回和下架的原因放在通知内容中
+func bookStatusNotification(book *models.Book, action, reason string) *models.Notification {
+	notification := &models.Notification{UserID: book.UserID, BookID: &book.ID, Message: reason}
+	switch action {
+	case BookActionApprove:
+		notification.Type = models.NotificationBookApproved
+		notification.Title = notificationTitle(fmt.Sprintf("Your book \"%s\" has been published", book.Title))
+	case BookActionReject:
+		notification.Type = models.NotificationBookRejected
+		notification.Title = notificationTitle(fmt.Sprintf("Your book \"%s\" was not approved", book.Title))
+	case BookActionUnpublish:
+		notification.Type = models.NotificationBookUnpublished
+		notification.Title = notificationTitle(fmt.Sprintf("Your book \"%s\" has been unpublished", book.Title))
+	}
+	return notification
+}
@@ -0,0 +1,351 @@
+package services
+
+import (
+	"archive/zip"
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "github.com/ncruces/go-sqlite3/driver" // 注册 sqlite3 驱动，用于读取 Calibre 的 metadata.db
+	_ "github.com/ncruces/go-sqlite3/embed"
+)
+
+// ImportSourceCalibre Calibre 书库：包含 metadata.db 和各书籍目录的文件夹，上传时打包为 zip
+const ImportSourceCalibre = "calibre"
+
+const (
+	calibreMetadataFile = "metadata.db"
+	calibreCoverFile    = "cover.jpg"
+	// maxCalibreLibrarySize 解压 Calibre 书库时允许的总大小，防止压缩炸弹
+	maxCalibreLibrarySize = 4 << 30
+)
+
+// calibreFileTypes Calibre 书籍格式对应的 MIME 类型，标准库的扩展名表中通常没有电子书格式
+var calibreFileTypes = map[string]string{
+	"epub": "application/epub+zip",
+	"mobi": "application/x-mobipocket-ebook",
+	"azw":  "application/vnd.amazon.ebook",
+	"azw3": "application/vnd.amazon.ebook",
+	"fb2":  "application/x-fictionbook+xml",
+	"djvu": "image/vnd.djvu",
+	"cbz":  "application/vnd.comicbook+zip",
+	"cbr":  "application/vnd.comicbook-rar",
+}
+
+// ImportFile 导入时随书籍一起保存的本地文件
+type ImportFile struct {
+	Path     string
+	Name     string
+	MimeType string
+}
+
+// ExtractCalibreLibrary 将上传的 Calibre 书库解压到临时目录并返回书库所在目录，调用方负责删除 root。
+// 压缩包中的书库可以位于任意一层子目录下；直接上传 metadata.db 时只能导入元数据，没有封面和书籍文件
+func ExtractCalibreLibrary(r io.ReaderAt, size int64) (root, library string, err error) {
+	root, err = os.MkdirTemp(filepath.Join(config.UploadDir, "tmp"), "calibre-*")
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(root)
+		}
+	}()
+
+	header := make([]byte, 16)
+	if _, err := r.ReadAt(header, 0); err == nil && string(header) == "SQLite format 3\x00" {
+		if err := copyToFile(filepath.Join(root, calibreMetadataFile), io.NewSectionReader(r, 0, size)); err != nil {
+			return "", "", err
+		}
+		return root, root, nil
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return "", "", errors.New("calibre library must be a zip archive or a metadata.db file")
+	}
+	var total uint64
+	for _, entry := range archive.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		if !filepath.IsLocal(entry.Name) {
+			return "", "", fmt.Errorf("invalid path in archive: %s", entry.Name)
+		}
+		if total += entry.UncompressedSize64; total > maxCalibreLibrarySize {
+			return "", "", errors.New("calibre library is too large")
+		}
+		if err := extractZipEntry(entry, filepath.Join(root, filepath.FromSlash(entry.Name))); err != nil {
+			return "", "", err
+		}
+	}
+
+	library, err = findCalibreLibrary(root)
+	if err != nil {
+		return "", "", err
+	}
+	return root, library, nil
+}
+
+func extractZipEntry(entry *zip.File, path string) error {
+	src, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// 按声明的大小截断，压缩包中的大小信息不可信
+	return copyToFile(path, io.LimitReader(src, int64(entry.UncompressedSize64)))
+}
+
+func copyToFile(path string, r io.Reader) error {
+	dst, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, r)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// findCalibreLibrary 查找层级最浅的 metadata.db 所在目录
+func findCalibreLibrary(root string) (string, error) {
+	library, depth := "", -1
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() != calibreMetadataFile {
+			return err
+		}
+		dir := filepath.Dir(path)
+		if n := strings.Count(dir, string(filepath.Separator)); depth < 0 || n < depth {
+			library, depth = dir, n
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if library == "" {
+		return "", errors.New("calibre library archive does not contain metadata.db")
+	}
+	return library, nil
+}
+
+// ParseCalibreLibrary 读取 Calibre 书库的 metadata.db，将每本书的作者、系列、标签、出版社、语言、简介、
+// 标识符以及封面和书籍文件转换为导入记录。Row 使用书籍在 Calibre 中的 id
+func ParseCalibreLibrary(library string) ([]ImportRecord, error) {
+	path, err := filepath.Abs(filepath.Join(library, calibreMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("not a calibre library: %s not found", calibreMetadataFile)
+	}
+	// 以只读方式打开，不影响正在运行的 Calibre
+	dsn := (&url.URL{Scheme: "file", Path: filepath.ToSlash(path), RawQuery: "mode=ro"}).String()
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	reader := calibreReader{db: db, library: library}
+	records, err := reader.books()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calibre library: %w", err)
+	}
+	return checkImportRecords(records)
+}
+
+type calibreReader struct {
+	db      *sql.DB
+	library string
+}
+
+// books 读取全部书籍，关联信息按书籍 id 批量查询后合并
+func (r *calibreReader) books() ([]ImportRecord, error) {
+	rows, err := r.db.Query(`SELECT id, title, COALESCE(pubdate, ''), COALESCE(series_index, 1), path, has_cover, COALESCE(uuid, '')
+		FROM books ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []ImportRecord
+	index := make(map[int64]int)
+	paths := make(map[int64]string)
+	for rows.Next() {
+		var (
+			id             int64
+			title, pubdate string
+			seriesIndex    float64
+			path, uuid     string
+			hasCover       bool
+			record         ImportRecord
+		)
+		if err := rows.Scan(&id, &title, &pubdate, &seriesIndex, &path, &hasCover, &uuid); err != nil {
+			return nil, err
+		}
+		record.Row = int(id)
+		record.Book.Title = title
+		record.Book.SeriesIndex = &seriesIndex
+		record.CreateSeries = true
+		if date, ok := calibreDate(pubdate); ok {
+			record.Book.PublishDate = &date
+		}
+		if uuid != "" {
+			record.Identifiers = append(record.Identifiers, models.BookIdentifier{Scheme: ImportSourceCalibre, Value: uuid})
+		}
+		if dir, ok := r.bookDir(path); ok {
+			paths[id] = dir
+			if hasCover {
+				record.Cover = &ImportFile{Path: filepath.Join(dir, calibreCoverFile), Name: calibreCoverFile, MimeType: "image/jpeg"}
+			}
+		} else {
+			record.Errors = append(record.Errors, fmt.Sprintf("invalid book path: %s", path))
+		}
+		index[id] = len(records)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	each := func(query string, fn func(record *ImportRecord, value string)) error {
+		return r.eachValue(query, func(id int64, value string) {
+			if i, ok := index[id]; ok {
+				fn(&records[i], value)
+			}
+		})
+	}
+	err = errors.Join(
+		each(`SELECT l.book, a.name FROM books_authors_link l JOIN authors a ON a.id = l.author ORDER BY l.id`,
+			func(record *ImportRecord, name string) {
+				record.Contributors = append(record.Contributors, models.BookAuthor{Role: models.AuthorRoleAuthor, Author: models.Author{Name: name}})
+			}),
+		each(`SELECT l.book, s.name FROM books_series_link l JOIN series s ON s.id = l.series`,
+			func(record *ImportRecord, name string) { record.SeriesTitle = name }),
+		each(`SELECT l.book, t.name FROM books_tags_link l JOIN tags t ON t.id = l.tag ORDER BY l.id`,
+			func(record *ImportRecord, name string) { record.Tags = append(record.Tags, name) }),
+		each(`SELECT l.book, p.name FROM books_publishers_link l JOIN publishers p ON p.id = l.publisher`,
+			func(record *ImportRecord, name string) { record.Book.Publisher = name }),
+		each(`SELECT l.book, g.lang_code FROM books_languages_link l JOIN languages g ON g.id = l.lang_code ORDER BY l.book, l.item_order DESC`,
+			func(record *ImportRecord, code string) {
+				// 多语种的书籍取第一种语言，按 item_order 倒序遍历使第一种最后写入
+				if language, ok := utils.NormalizeLanguage(code); ok {
+					record.Book.Language = language
+				}
+			}),
+		each(`SELECT book, text FROM comments`,
+			func(record *ImportRecord, text string) { record.Book.Description = utils.HTMLToText(text) }),
+		each(`SELECT book, type || ':' || val FROM identifiers ORDER BY id`,
+			func(record *ImportRecord, value string) { addCalibreIdentifier(record, value) }),
+		each(`SELECT book, lower(format) || ':' || name FROM data ORDER BY id`,
+			func(record *ImportRecord, value string) {
+				format, name, _ := strings.Cut(value, ":")
+				dir, ok := paths[int64(record.Row)]
+				if !ok {
+					return
+				}
+				record.Book.Format = "ebook"
+				record.Files = append(record.Files, ImportFile{
+					Path:     filepath.Join(dir, name+"."+format),
+					Name:     name + "." + format,
+					MimeType: calibreMimeType(format),
+				})
+			}),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		record := &records[i]
+		if record.SeriesTitle == "" {
+			record.Book.SeriesIndex = nil // Calibre 对不属于系列的书籍也记录 1.0
+		}
+		if len(record.Contributors) > 0 {
+			record.Book.Author = FormatAuthorCredits(record.Contributors)
+		}
+	}
+	return records, nil
+}
+
+// eachValue 执行返回 (book, value) 两列的查询
+func (r *calibreReader) eachValue(query string, fn func(id int64, value string)) error {
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			id    int64
+			value sql.NullString
+		)
+		if err := rows.Scan(&id, &value); err != nil {
+			return err
+		}
+		if value.Valid && strings.TrimSpace(value.String) != "" {
+			fn(id, strings.TrimSpace(value.String))
+		}
+	}
+	return rows.Err()
+}
+
+// bookDir 书籍目录的本地路径，path 来自书库数据库，不允许指向书库之外
+func (r *calibreReader) bookDir(path string) (string, bool) {
+	path = filepath.FromSlash(path)
+	if path == "" || !filepath.IsLocal(path) {
+		return "", false
+	}
+	return filepath.Join(r.library, path), true
+}
+
+// addCalibreIdentifier ISBN 写入书籍，其余标识符按类型保存
+func addCalibreIdentifier(record *ImportRecord, value string) {
+	scheme, id, _ := strings.Cut(value, ":")
+	scheme, id = strings.ToLower(strings.TrimSpace(scheme)), strings.TrimSpace(id)
+	if scheme == "" || id == "" {
+		return
+	}
+	if scheme == "isbn" {
+		if record.Book.ISBN13 == nil {
+			record.Book.ISBN13 = &id
+		}
+		return
+	}
+	record.Identifiers = append(record.Identifiers, models.BookIdentifier{Scheme: scheme, Value: id})
+}
+
+// calibreDate 解析 Calibre 的日期，未设置的日期保存为 0101-01-01
+func calibreDate(s string) (models.Date, bool) {
+	if len(s) < 10 {
+		return models.Date{}, false
+	}
+	date, err := models.ParseDate(s[:10])
+	if err != nil || date.Year() <= 101 {
+		return models.Date{}, false
+	}
+	return date, true
+}
+
+func calibreMimeType(format string) string {
+	if mimeType, ok := calibreFileTypes[format]; ok {
+		return mimeType
+	}
+	return mime.TypeByExtension("." + format)
+}
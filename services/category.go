@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bookshare/models"
+	"errors"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrCategoryCycle = errors.New("category cannot be moved under itself or its descendants")
+
+// LoadCategories 加载全部分类（含别名），按排序值和名称排序
+func LoadCategories(db *gorm.DB) ([]models.Category, error) {
+	var categories []models.Category
+	err := db.Preload("Aliases").Order("sort_order, name").Find(&categories).Error
+	return categories, err
+}
+
+// BuildCategoryTree 将分类列表组装成树，lang 不为空时填充对应语言的显示名称
+func BuildCategoryTree(categories []models.Category, lang string) []*models.Category {
+	nodes := make(map[uint]*models.Category, len(categories))
+	for i := range categories {
+		category := &categories[i]
+		category.Children = nil
+		if lang != "" {
+			category.DisplayName = category.LocalizedName(lang)
+		}
+		nodes[category.ID] = category
+	}
+
+	roots := make([]*models.Category, 0)
+	for i := range categories {
+		category := &categories[i]
+		if parent, ok := nodes[derefUint(category.ParentID)]; ok && category.ParentID != nil {
+			parent.Children = append(parent.Children, category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+	return roots
+}
+
+// CategoryDescendantIDs 返回分类自身及其所有子孙分类的ID
+func CategoryDescendantIDs(db *gorm.DB, id uint) ([]uint, error) {
+	var categories []models.Category
+	if err := db.Select("id", "parent_id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{id}
+	visited := map[uint]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !visited[child] {
+				visited[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// ResolveCategory 根据ID、slug、名称、多语言名称或别名查找分类，忽略大小写和首尾空白
+func ResolveCategory(db *gorm.DB, key string) (*models.Category, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if id, err := strconv.ParseUint(key, 10, 64); err == nil {
+		var category models.Category
+		if err := db.First(&category, id).Error; err == nil {
+			return &category, nil
+		}
+	}
+
+	categories, err := LoadCategories(db)
+	if err != nil {
+		return nil, err
+	}
+	normalized := normalizeCategoryName(key)
+	slug := strings.ToLower(key)
+	for i := range categories {
+		category := &categories[i]
+		if category.Slug == slug || normalizeCategoryName(category.Name) == normalized {
+			return category, nil
+		}
+		for _, name := range category.DisplayNames {
+			if normalizeCategoryName(name) == normalized {
+				return category, nil
+			}
+		}
+		for _, alias := range category.Aliases {
+			if normalizeCategoryName(alias.Name) == normalized {
+				return category, nil
+			}
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// CheckCategoryParent 检查把分类移动到 parentID 下是否会形成环
+func CheckCategoryParent(db *gorm.DB, id uint, parentID *uint) error {
+	if parentID == nil || id == 0 {
+		return nil
+	}
+	descendants, err := CategoryDescendantIDs(db, id)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		if descendant == *parentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// UniqueCategorySlug 在 slug 已被占用时追加数字后缀
+func UniqueCategorySlug(db *gorm.DB, slug string, excludeID uint) string {
+	if slug == "" {
+		slug = "category"
+	}
+	candidate := slug
+	for i := 2; ; i++ {
+		var count int64
+		db.Model(&models.Category{}).Where("slug = ? AND id <> ?", candidate, excludeID).Count(&count)
+		if count == 0 {
+			return candidate
+		}
+		candidate = slug + "-" + strconv.Itoa(i)
+	}
+}
+
+// ScopeCategories 将书籍查询限制在给定的分类中
+func ScopeCategories(ids []uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("books.category_id IN ?", ids)
+	}
+}
+
+func normalizeCategoryName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func derefUint(p *uint) uint {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
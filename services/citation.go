@@ -0,0 +1,587 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// CitationFormats 支持的引文格式，gbt7714、apa、mla 为格式化好的文本
+var CitationFormats = map[string]ExportFormat{
+	"bibtex":   {ContentType: "application/x-bibtex; charset=utf-8", Extension: "bib"},
+	"ris":      {ContentType: "application/x-research-info-systems; charset=utf-8", Extension: "ris"},
+	"csl-json": {ContentType: "application/vnd.citationstyles.csl+json; charset=utf-8", Extension: "json"},
+	"gbt7714":  {ContentType: "text/plain; charset=utf-8", Extension: "txt"},
+	"apa":      {ContentType: "text/plain; charset=utf-8", Extension: "txt"},
+	"mla":      {ContentType: "text/plain; charset=utf-8", Extension: "txt"},
+}
+
+// CitationStyles 格式化文本的引文样式
+var CitationStyles = []string{"gbt7714", "apa", "mla"}
+
+// citation 从书籍中整理出的引文信息
+type citation struct {
+	book         *models.Book
+	authors      []utils.PersonalName
+	translators  []utils.PersonalName
+	editors      []utils.PersonalName
+	illustrators []utils.PersonalName
+	year         int // 0 表示出版年不详
+}
+
+// LoadCitationBooks 按给定顺序加载书籍及生成引文所需的作者和系列
+func LoadCitationBooks(db *gorm.DB, ids []uint) ([]models.Book, error) {
+	var books []models.Book
+	if err := db.Preload("Contributors", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Preload("Contributors.Author").Preload("Series").
+		Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	position := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		position[id] = i
+	}
+	sort.Slice(books, func(i, j int) bool { return position[books[i].ID] < position[books[j].ID] })
+	return books, nil
+}
+
+func newCitation(book *models.Book) *citation {
+	c := &citation{book: book}
+	if book.PublishDate != nil {
+		c.year = book.PublishDate.Year()
+	}
+	add := func(name, role string) {
+		person := utils.ParsePersonalName(name)
+		switch role {
+		case models.AuthorRoleTranslator:
+			c.translators = append(c.translators, person)
+		case models.AuthorRoleEditor:
+			c.editors = append(c.editors, person)
+		case models.AuthorRoleIllustrator:
+			c.illustrators = append(c.illustrators, person)
+		default:
+			c.authors = append(c.authors, person)
+		}
+	}
+	// 没有结构化作者时从署名字符串中解析
+	if len(book.Contributors) > 0 {
+		for _, link := range book.Contributors {
+			add(link.Author.Name, link.Role)
+		}
+	} else {
+		for _, credit := range utils.ParseAuthorCredits(book.Author) {
+			add(credit.Name, credit.Role)
+		}
+	}
+	return c
+}
+
+// FormatCitations 生成多本书的引文，多本书时为参考文献列表
+func FormatCitations(books []models.Book, format string) (string, error) {
+	citations := make([]*citation, len(books))
+	for i := range books {
+		citations[i] = newCitation(&books[i])
+	}
+
+	var entries []string
+	switch format {
+	case "bibtex":
+		keys := make(map[string]int)
+		for _, c := range citations {
+			key := c.bibtexKey()
+			// 重复的键加上 a、b、c 后缀
+			if n := keys[key]; n > 0 {
+				keys[key]++
+				key += string(rune('a' + n - 1))
+			} else {
+				keys[key] = 1
+			}
+			entries = append(entries, c.bibtex(key))
+		}
+		return strings.Join(entries, "\n"), nil
+	case "ris":
+		for _, c := range citations {
+			entries = append(entries, c.ris())
+		}
+		return strings.Join(entries, ""), nil
+	case "csl-json":
+		items := make([]cslItem, len(citations))
+		for i, c := range citations {
+			items[i] = c.csl()
+		}
+		data, err := json.MarshalIndent(items, "", "  ")
+		return string(data) + "\n", err
+	case "gbt7714":
+		for i, c := range citations {
+			entry := c.gbt7714()
+			if len(citations) > 1 {
+				entry = fmt.Sprintf("[%d] %s", i+1, entry)
+			}
+			entries = append(entries, entry)
+		}
+	case "apa", "mla":
+		for _, c := range citations {
+			if format == "apa" {
+				entries = append(entries, c.apa())
+			} else {
+				entries = append(entries, c.mla())
+			}
+		}
+		// 参考文献按作者排序
+		sort.Strings(entries)
+	default:
+		return "", fmt.Errorf("unsupported citation format: %s", format)
+	}
+	return strings.Join(entries, "\n") + "\n", nil
+}
+
+// FormatCitationStyles 返回一本书在所有格式化样式下的引文
+func FormatCitationStyles(book *models.Book) map[string]string {
+	c := newCitation(book)
+	return map[string]string{"gbt7714": c.gbt7714(), "apa": c.apa(), "mla": c.mla()}
+}
+
+var bibtexKeyChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// asciiKey 生成只包含小写字母和数字的键，中文转为拼音
+func asciiKey(s string, maxLen int) string {
+	if forms := utils.PinyinForms(s); len(forms) > 0 && containsHanRune(s) {
+		s = forms[0]
+	}
+	s = bibtexKeyChars.ReplaceAllString(strings.ToLower(s), "")
+	if len(s) > maxLen {
+		s = s[:maxLen]
+	}
+	return s
+}
+
+func containsHanRune(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return unicode.Is(unicode.Han, r) }) >= 0
+}
+
+// bibtexKey 引用键，如 liucixin2008santi
+func (c *citation) bibtexKey() string {
+	var key string
+	if len(c.authors) > 0 {
+		key = asciiKey(c.authors[0].Family, 16)
+	}
+	if c.year > 0 {
+		key += strconv.Itoa(c.year)
+	}
+	if title := strings.Fields(c.book.Title); len(title) > 0 {
+		key += asciiKey(title[0], 12)
+	}
+	if key == "" {
+		key = fmt.Sprintf("book%d", c.book.ID)
+	}
+	return key
+}
+
+var bibtexEscaper = strings.NewReplacer(
+	`\`, `\textbackslash{}`, "{", `\{`, "}", `\}`, "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+	"~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+)
+
+// bibtexNames 以 and 连接人名，中文人名加括号避免被拆分
+func bibtexNames(names []utils.PersonalName) string {
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if name.Given == "" {
+			parts[i] = "{" + bibtexEscaper.Replace(name.Family) + "}"
+		} else {
+			parts[i] = bibtexEscaper.Replace(name.Inverted())
+		}
+	}
+	return strings.Join(parts, " and ")
+}
+
+func (c *citation) bibtex(key string) string {
+	book := c.book
+	var b strings.Builder
+	fmt.Fprintf(&b, "@book{%s,\n", key)
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "  %s = {%s},\n", name, value)
+		}
+	}
+	field("author", bibtexNames(c.authors))
+	field("editor", bibtexNames(c.editors))
+	field("translator", bibtexNames(c.translators))
+	field("title", "{"+bibtexEscaper.Replace(book.Title)+"}") // 双层括号保留大小写
+	if book.Series != nil {
+		field("series", bibtexEscaper.Replace(book.Series.Title))
+		if book.SeriesIndex != nil {
+			field("volume", strconv.FormatFloat(*book.SeriesIndex, 'f', -1, 64))
+		}
+	}
+	field("edition", bibtexEscaper.Replace(book.Edition))
+	field("publisher", bibtexEscaper.Replace(book.Publisher))
+	if c.year > 0 {
+		field("year", strconv.Itoa(c.year))
+	}
+	if book.ISBN13 != nil {
+		field("isbn", *book.ISBN13)
+	}
+	if book.PageCount > 0 {
+		field("pagetotal", strconv.Itoa(book.PageCount))
+	}
+	field("language", book.Language)
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func (c *citation) ris() string {
+	book := c.book
+	var b strings.Builder
+	tag := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			fmt.Fprintf(&b, "%s  - %s\r\n", name, value)
+		}
+	}
+	tag("TY", "BOOK")
+	for _, name := range c.authors {
+		tag("AU", name.Inverted())
+	}
+	for _, name := range c.editors {
+		tag("ED", name.Inverted())
+	}
+	for _, name := range c.translators {
+		tag("A4", name.Inverted())
+	}
+	tag("TI", book.Title)
+	if book.Series != nil {
+		tag("T2", book.Series.Title)
+		if book.SeriesIndex != nil {
+			tag("VL", strconv.FormatFloat(*book.SeriesIndex, 'f', -1, 64))
+		}
+	}
+	if c.year > 0 {
+		tag("PY", strconv.Itoa(c.year))
+	}
+	tag("ET", book.Edition)
+	tag("PB", book.Publisher)
+	if book.ISBN13 != nil {
+		tag("SN", *book.ISBN13)
+	}
+	if book.PageCount > 0 {
+		tag("SP", strconv.Itoa(book.PageCount))
+	}
+	tag("LA", book.Language)
+	tag("AB", book.Description)
+	b.WriteString("ER  - \r\n")
+	return b.String()
+}
+
+// cslName CSL-JSON 人名，中文人名使用 literal
+type cslName struct {
+	Family  string `json:"family,omitempty"`
+	Given   string `json:"given,omitempty"`
+	Literal string `json:"literal,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	ID               string    `json:"id"`
+	Type             string    `json:"type"`
+	Title            string    `json:"title"`
+	Author           []cslName `json:"author,omitempty"`
+	Editor           []cslName `json:"editor,omitempty"`
+	Translator       []cslName `json:"translator,omitempty"`
+	Illustrator      []cslName `json:"illustrator,omitempty"`
+	Issued           *cslDate  `json:"issued,omitempty"`
+	Publisher        string    `json:"publisher,omitempty"`
+	Edition          string    `json:"edition,omitempty"`
+	ISBN             string    `json:"ISBN,omitempty"`
+	NumberOfPages    int       `json:"number-of-pages,omitempty"`
+	Language         string    `json:"language,omitempty"`
+	CollectionTitle  string    `json:"collection-title,omitempty"`
+	CollectionNumber string    `json:"collection-number,omitempty"`
+	Abstract         string    `json:"abstract,omitempty"`
+}
+
+func cslNames(names []utils.PersonalName) []cslName {
+	result := make([]cslName, len(names))
+	for i, name := range names {
+		if name.Given == "" {
+			result[i] = cslName{Literal: name.Family}
+		} else {
+			result[i] = cslName{Family: name.Family, Given: name.Given}
+		}
+	}
+	return result
+}
+
+func (c *citation) csl() cslItem {
+	book := c.book
+	item := cslItem{
+		ID:            fmt.Sprintf("book-%d", book.ID),
+		Type:          "book",
+		Title:         book.Title,
+		Author:        cslNames(c.authors),
+		Editor:        cslNames(c.editors),
+		Translator:    cslNames(c.translators),
+		Illustrator:   cslNames(c.illustrators),
+		Publisher:     book.Publisher,
+		Edition:       book.Edition,
+		NumberOfPages: book.PageCount,
+		Language:      book.Language,
+		Abstract:      book.Description,
+	}
+	if c.year > 0 {
+		item.Issued = &cslDate{DateParts: [][]int{{c.year}}}
+	}
+	if book.ISBN13 != nil {
+		item.ISBN = *book.ISBN13
+	}
+	if book.Series != nil {
+		item.CollectionTitle = book.Series.Title
+		if book.SeriesIndex != nil {
+			item.CollectionNumber = strconv.FormatFloat(*book.SeriesIndex, 'f', -1, 64)
+		}
+	}
+	return item
+}
+
+// ordinalEdition 将纯数字的版本号转为英文序数，如 "2" 转为 "2nd ed."，其他写法原样返回
+func ordinalEdition(edition string) string {
+	n, err := strconv.Atoi(strings.TrimSpace(edition))
+	if err != nil {
+		return edition
+	}
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s ed.", n, suffix)
+}
+
+// terminate 在句末补上句点
+func terminate(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasSuffix(s, ".") || strings.HasSuffix(s, "。") || strings.HasSuffix(s, "?") || strings.HasSuffix(s, "!") {
+		return s
+	}
+	return s + "."
+}
+
+// gbt7714 GB/T 7714-2015 专著著录格式：主要责任者. 题名[M]. 其他责任者. 版本项. 出版者, 出版年.
+func (c *citation) gbt7714() string {
+	names := func(people []utils.PersonalName) string {
+		parts := make([]string, 0, 3)
+		for i, name := range people {
+			if i == 3 {
+				if containsHanRune(people[0].Family) {
+					parts = append(parts, "等")
+				} else {
+					parts = append(parts, "et al")
+				}
+				break
+			}
+			if name.Given == "" {
+				parts = append(parts, name.Family)
+			} else {
+				parts = append(parts, strings.ToUpper(name.Family)+" "+name.Initials(""))
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	book := c.book
+	// 中文文献使用中文标注，西文文献使用英文缩写
+	chinese := containsHanRune(book.Title)
+	editorLabel, translatorLabel := ", ed", ", trans"
+	if chinese {
+		editorLabel, translatorLabel = ", 编", ", 译"
+	}
+	var parts []string
+	if len(c.authors) > 0 {
+		parts = append(parts, names(c.authors))
+	} else if len(c.editors) > 0 {
+		parts = append(parts, names(c.editors)+editorLabel)
+	}
+	parts = append(parts, book.Title+"[M]")
+	if len(c.translators) > 0 {
+		parts = append(parts, names(c.translators)+translatorLabel)
+	}
+	if edition := strings.TrimSpace(book.Edition); edition != "" {
+		// 纯数字的版本号：中文著录为 "2版"，西文著录为 "2nd ed"
+		if _, err := strconv.Atoi(edition); err == nil {
+			if chinese {
+				edition += "版"
+			} else {
+				edition = strings.TrimSuffix(ordinalEdition(edition), ".")
+			}
+		}
+		parts = append(parts, edition)
+	}
+	var publication []string
+	if book.Publisher != "" {
+		publication = append(publication, book.Publisher)
+	}
+	if c.year > 0 {
+		publication = append(publication, strconv.Itoa(c.year))
+	}
+	if len(publication) > 0 {
+		parts = append(parts, strings.Join(publication, ", "))
+	}
+	return strings.Join(parts, ". ") + "."
+}
+
+// joinNames 以逗号连接人名，最后两个之间使用 conjunction
+func joinNames(names []string, conjunction string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	case 2:
+		if strings.HasPrefix(conjunction, ",") {
+			conjunction = conjunction[1:]
+		}
+		return names[0] + conjunction + names[1]
+	}
+	return strings.Join(names[:len(names)-1], ", ") + conjunction + names[len(names)-1]
+}
+
+// apa APA 第 7 版：Family, G. (Year). Title (G. Family, Trans.; 2nd ed.). Publisher.
+func (c *citation) apa() string {
+	inverted := func(people []utils.PersonalName) []string {
+		result := make([]string, len(people))
+		for i, name := range people {
+			if name.Given == "" {
+				result[i] = name.Family
+			} else {
+				result[i] = name.Family + ", " + name.Initials(".")
+			}
+		}
+		return result
+	}
+	direct := func(people []utils.PersonalName) []string {
+		result := make([]string, len(people))
+		for i, name := range people {
+			if name.Given == "" {
+				result[i] = name.Family
+			} else {
+				result[i] = name.Initials(".") + " " + name.Family
+			}
+		}
+		return result
+	}
+
+	book := c.book
+	var b strings.Builder
+	switch {
+	case len(c.authors) > 0:
+		b.WriteString(terminate(joinNames(inverted(c.authors), ", & ")))
+	case len(c.editors) > 0:
+		label := "(Ed.)"
+		if len(c.editors) > 1 {
+			label = "(Eds.)"
+		}
+		b.WriteString(joinNames(inverted(c.editors), ", & ") + " " + label + ".")
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	if c.year > 0 {
+		fmt.Fprintf(&b, "(%d). ", c.year)
+	} else {
+		b.WriteString("(n.d.). ")
+	}
+	b.WriteString(book.Title)
+
+	var notes []string
+	if len(c.authors) > 0 && len(c.editors) > 0 {
+		label := "Ed."
+		if len(c.editors) > 1 {
+			label = "Eds."
+		}
+		notes = append(notes, joinNames(direct(c.editors), ", & ")+", "+label)
+	}
+	if len(c.translators) > 0 {
+		notes = append(notes, joinNames(direct(c.translators), ", & ")+", Trans.")
+	}
+	if book.Edition != "" {
+		notes = append(notes, ordinalEdition(book.Edition))
+	}
+	if len(notes) > 0 {
+		b.WriteString(" (" + strings.Join(notes, "; ") + ")")
+	}
+	b.WriteString(".")
+	if book.Publisher != "" {
+		b.WriteString(" " + terminate(book.Publisher))
+	}
+	return b.String()
+}
+
+// mla MLA 第 9 版：Family, Given. Title. Translated by Given Family, 2nd ed., Publisher, Year.
+func (c *citation) mla() string {
+	book := c.book
+	var b strings.Builder
+	people, label := c.authors, ""
+	if len(people) == 0 && len(c.editors) > 0 {
+		people, label = c.editors, ", editor"
+		if len(c.editors) > 1 {
+			label = ", editors"
+		}
+	}
+	switch len(people) {
+	case 0:
+	case 1:
+		b.WriteString(terminate(people[0].Inverted() + label))
+	case 2:
+		b.WriteString(terminate(people[0].Inverted() + ", and " + people[1].String() + label))
+	default:
+		b.WriteString(terminate(people[0].Inverted() + ", et al" + label))
+	}
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(terminate(book.Title))
+
+	var details []string
+	names := func(people []utils.PersonalName) string {
+		parts := make([]string, len(people))
+		for i, name := range people {
+			parts[i] = name.String()
+		}
+		return joinNames(parts, ", and ")
+	}
+	if len(c.translators) > 0 {
+		details = append(details, "Translated by "+names(c.translators))
+	}
+	if len(c.authors) > 0 && len(c.editors) > 0 {
+		details = append(details, "Edited by "+names(c.editors))
+	}
+	if book.Edition != "" {
+		details = append(details, ordinalEdition(book.Edition))
+	}
+	if book.Publisher != "" {
+		details = append(details, book.Publisher)
+	}
+	if c.year > 0 {
+		details = append(details, strconv.Itoa(c.year))
+	}
+	if len(details) > 0 {
+		b.WriteString(" " + terminate(strings.Join(details, ", ")))
+	}
+	return b.String()
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"bookshare/models"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// exportBatchSize 导出时每批读取的书籍数量，导出过程中只保留一批在内存中
+const exportBatchSize = 500
+
+// ExportFormat 导出格式的响应类型和文件扩展名
+type ExportFormat struct {
+	ContentType string
+	Extension   string
+}
+
+// ExportFormats 支持的导出格式
+var ExportFormats = map[string]ExportFormat{
+	"csv":     {ContentType: "text/csv; charset=utf-8", Extension: "csv"},
+	"jsonl":   {ContentType: "application/x-ndjson; charset=utf-8", Extension: "jsonl"},
+	"marc":    {ContentType: "application/marc", Extension: "mrc"},
+	"marcxml": {ContentType: "application/marcxml+xml; charset=utf-8", Extension: "xml"},
+	"dc":      {ContentType: "application/xml; charset=utf-8", Extension: "xml"},
+}
+
+// CatalogRecord 导出的一条书目，Book 需要预加载 Contributors.Author 和 Series
+type CatalogRecord struct {
+	Book models.Book
+	Tags []string
+}
+
+// catalogEncoder 将书目逐条写入输出，Flush 将缓冲写出，Close 写入结尾并刷新缓冲
+type catalogEncoder interface {
+	Encode(record *CatalogRecord) error
+	Flush() error
+	Close() error
+}
+
+// ExportBooks 按批读取 scope 筛选出的书籍并以指定格式写入 w，每批写完后刷新输出，
+// 因此导出任意数量的书籍都不会把整张表读入内存
+func ExportBooks(db *gorm.DB, scope func(*gorm.DB) *gorm.DB, format string, w io.Writer) error {
+	encoder, err := newCatalogEncoder(format, w)
+	if err != nil {
+		return err
+	}
+
+	var books []models.Book
+	result := db.Model(&models.Book{}).Scopes(scope).
+		Preload("Contributors", func(tx *gorm.DB) *gorm.DB { return tx.Order("position") }).
+		Preload("Contributors.Author").Preload("Series").
+		FindInBatches(&books, exportBatchSize, func(tx *gorm.DB, _ int) error {
+			tags, err := bookTagNames(db, books)
+			if err != nil {
+				return err
+			}
+			for i := range books {
+				if err := encoder.Encode(&CatalogRecord{Book: books[i], Tags: tags[books[i].ID]}); err != nil {
+					return err
+				}
+			}
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+			if flusher, ok := w.(interface{ Flush() }); ok {
+				flusher.Flush()
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	return encoder.Close()
+}
+
+// bookTagNames 批量查询书籍的标签名称
+func bookTagNames(db *gorm.DB, books []models.Book) (map[uint][]string, error) {
+	ids := make([]uint, len(books))
+	for i := range books {
+		ids[i] = books[i].ID
+	}
+	var rows []struct {
+		BookID uint
+		Name   string
+	}
+	if err := db.Table("book_tags").Select("book_tags.book_id, tags.name").
+		Joins("JOIN tags ON tags.id = book_tags.tag_id").
+		Where("book_tags.book_id IN ?", ids).Order("book_tags.book_id, book_tags.created_at").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	tags := make(map[uint][]string, len(books))
+	for _, row := range rows {
+		tags[row.BookID] = append(tags[row.BookID], row.Name)
+	}
+	return tags, nil
+}
+
+func newCatalogEncoder(format string, w io.Writer) (catalogEncoder, error) {
+	switch format {
+	case "csv":
+		return newCSVCatalogEncoder(w)
+	case "jsonl":
+		encoder := json.NewEncoder(w)
+		encoder.SetEscapeHTML(false)
+		return &jsonlCatalogEncoder{encoder: encoder}, nil
+	case "marc":
+		return newMARCCatalogEncoder(w), nil
+	case "marcxml":
+		return newMARCXMLCatalogEncoder(w)
+	case "dc":
+		return newDublinCoreCatalogEncoder(w)
+	}
+	return nil, fmt.Errorf("unsupported export format: %s", format)
+}
+
+// csvExportColumns CSV 导出的列，与批量导入使用的列名一致，导出的文件可以直接导入
+var csvExportColumns = []string{
+	"id", "title", "author", "isbn13", "isbn10", "publisher", "publish_date", "page_count", "language",
+	"format", "edition", "category", "series", "series_index", "tags", "description", "cover_image",
+}
+
+type csvCatalogEncoder struct {
+	writer *csv.Writer
+}
+
+func newCSVCatalogEncoder(w io.Writer) (*csvCatalogEncoder, error) {
+	// 写入 BOM，便于 Excel 识别 UTF-8
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return nil, err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvExportColumns); err != nil {
+		return nil, err
+	}
+	return &csvCatalogEncoder{writer: writer}, nil
+}
+
+func (e *csvCatalogEncoder) Encode(record *CatalogRecord) error {
+	book := &record.Book
+	var publishDate, seriesTitle, seriesIndex string
+	if book.PublishDate != nil {
+		publishDate = book.PublishDate.Format("2006-01-02")
+	}
+	if book.Series != nil {
+		seriesTitle = book.Series.Title
+	}
+	if book.SeriesIndex != nil {
+		seriesIndex = strconv.FormatFloat(*book.SeriesIndex, 'f', -1, 64)
+	}
+	return e.writer.Write([]string{
+		strconv.FormatUint(uint64(book.ID), 10), book.Title, book.Author, stringValue(book.ISBN13), stringValue(book.ISBN10),
+		book.Publisher, publishDate, strconv.Itoa(book.PageCount), book.Language, book.Format, book.Edition,
+		book.Category, seriesTitle, seriesIndex, strings.Join(record.Tags, ","), book.Description, book.CoverImage,
+	})
+}
+
+func (e *csvCatalogEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvCatalogEncoder) Close() error {
+	return e.Flush()
+}
+
+// catalogLine JSON Lines 导出的一行，字段与批量导入一致
+type catalogLine struct {
+	ID           uint                 `json:"id"`
+	Title        string               `json:"title"`
+	Author       string               `json:"author"`
+	Contributors []catalogContributor `json:"contributors,omitempty"`
+	ISBN13       *string              `json:"isbn13"`
+	ISBN10       *string              `json:"isbn10"`
+	Publisher    string               `json:"publisher"`
+	PublishDate  *models.Date         `json:"publish_date"`
+	PageCount    int                  `json:"page_count"`
+	Language     string               `json:"language"`
+	Format       string               `json:"format"`
+	Edition      string               `json:"edition"`
+	Category     string               `json:"category"`
+	SeriesTitle  string               `json:"series_title,omitempty"`
+	SeriesIndex  *float64             `json:"series_index,omitempty"`
+	Tags         []string             `json:"tags"`
+	Description  string               `json:"description"`
+	CoverImage   string               `json:"cover_image"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+type catalogContributor struct {
+	Author struct {
+		Name string `json:"name"`
+	} `json:"author"`
+	Role string `json:"role"`
+}
+
+type jsonlCatalogEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *jsonlCatalogEncoder) Encode(record *CatalogRecord) error {
+	book := &record.Book
+	line := catalogLine{
+		ID: book.ID, Title: book.Title, Author: book.Author, ISBN13: book.ISBN13, ISBN10: book.ISBN10,
+		Publisher: book.Publisher, PublishDate: book.PublishDate, PageCount: book.PageCount,
+		Language: book.Language, Format: book.Format, Edition: book.Edition, Category: book.Category,
+		SeriesIndex: book.SeriesIndex, Tags: record.Tags, Description: book.Description,
+		CoverImage: book.CoverImage, CreatedAt: book.CreatedAt, UpdatedAt: book.UpdatedAt,
+	}
+	if line.Tags == nil {
+		line.Tags = []string{}
+	}
+	if book.Series != nil {
+		line.SeriesTitle = book.Series.Title
+	}
+	for _, link := range book.Contributors {
+		var contributor catalogContributor
+		contributor.Author.Name, contributor.Role = link.Author.Name, link.Role
+		line.Contributors = append(line.Contributors, contributor)
+	}
+	return e.encoder.Encode(line)
+}
+
+func (e *jsonlCatalogEncoder) Flush() error {
+	return nil
+}
+
+func (e *jsonlCatalogEncoder) Close() error {
+	return nil
+}
+
+// Dublin Core 命名空间
+const dublinCoreNamespace = "http://purl.org/dc/elements/1.1/"
+
+// dublinCoreCatalogEncoder 输出 Dublin Core XML：<metadata> 下每本书一个 <record>，元素使用 dc 前缀
+type dublinCoreCatalogEncoder struct {
+	encoder *xml.Encoder
+}
+
+func newDublinCoreCatalogEncoder(w io.Writer) (*dublinCoreCatalogEncoder, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	encoder := xml.NewEncoder(w)
+	root := xml.StartElement{
+		Name: xml.Name{Local: "metadata"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns:dc"}, Value: dublinCoreNamespace}},
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	return &dublinCoreCatalogEncoder{encoder: encoder}, nil
+}
+
+func (e *dublinCoreCatalogEncoder) Encode(record *CatalogRecord) error {
+	book := &record.Book
+	var elements [][2]string
+	add := func(name, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			elements = append(elements, [2]string{name, value})
+		}
+	}
+
+	add("identifier", fmt.Sprintf("/books/%d", book.ID))
+	if book.ISBN13 != nil {
+		add("identifier", "urn:isbn:"+*book.ISBN13)
+	}
+	add("title", book.Title)
+	creators := 0
+	for _, link := range book.Contributors {
+		if link.Role == models.AuthorRoleAuthor {
+			add("creator", link.Author.Name)
+			creators++
+		} else {
+			add("contributor", link.Author.Name)
+		}
+	}
+	if creators == 0 {
+		add("creator", book.Author)
+	}
+	add("publisher", book.Publisher)
+	if book.PublishDate != nil {
+		add("date", book.PublishDate.Format("2006-01-02"))
+	}
+	add("language", book.Language)
+	add("subject", book.Category)
+	for _, tag := range record.Tags {
+		add("subject", tag)
+	}
+	add("description", book.Description)
+	add("format", book.Format)
+	if book.Series != nil {
+		add("relation", book.Series.Title)
+	}
+	add("type", "Text")
+
+	recordElement := xml.StartElement{Name: xml.Name{Local: "record"}}
+	if err := e.encoder.EncodeToken(recordElement); err != nil {
+		return err
+	}
+	for _, element := range elements {
+		if err := e.encoder.EncodeElement(element[1], xml.StartElement{Name: xml.Name{Local: "dc:" + element[0]}}); err != nil {
+			return err
+		}
+	}
+	return e.encoder.EncodeToken(recordElement.End())
+}
+
+func (e *dublinCoreCatalogEncoder) Flush() error {
+	return e.encoder.Flush()
+}
+
+func (e *dublinCoreCatalogEncoder) Close() error {
+	if err := e.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "metadata"}}); err != nil {
+		return err
+	}
+	return e.encoder.Flush()
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// facetSize 每个分面最多返回的选项数量
+const facetSize = 20
+
+// 分面缓存：同一组合在 facetHitWindow 内被请求 facetHotThreshold 次后才写入缓存，避免缓存大量只出现一次的组合
+const (
+	facetCacheTTL      = 5 * time.Minute
+	facetHitWindow     = 10 * time.Minute
+	facetHotThreshold  = 3
+	facetVersionKey    = "search_facets:version"
+	facetCacheKeyFmt   = "search_facets:%s:%s"
+	facetHitCounterFmt = "search_facets_hits:%s:%s"
+)
+
+// FacetBucket 分面中的一个选项，Value 为筛选时使用的参数值
+type FacetBucket struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// SearchFacets 各分面的选项及数量
+type SearchFacets struct {
+	Category []FacetBucket `json:"category"`
+	Author   []FacetBucket `json:"author"`
+	Language []FacetBucket `json:"language"`
+	Tag      []FacetBucket `json:"tag"`
+	Decade   []FacetBucket `json:"decade"`
+}
+
+// ComputeFacets 统计 viewerID 可以看到的搜索结果在各分面上的分布。每个分面应用除自身以外的全部筛选条件，
+// 这样已选中某个语言时，仍能看到其他语言的数量以便切换或多选
+func ComputeFacets(db *gorm.DB, keyword string, filter BookFilter, viewerID uint) (*SearchFacets, error) {
+	cacheKey, hitKey := facetCacheKeys(keyword, filter, viewerID)
+	if val, err := config.RDB.Get(config.Ctx, cacheKey).Result(); err == nil {
+		var facets SearchFacets
+		if err := json.Unmarshal([]byte(val), &facets); err == nil {
+			return &facets, nil
+		}
+	}
+
+	var search *BookQuery
+	if keyword != "" {
+		var err error
+		if search, err = ParseBookQuery(db, keyword); err != nil {
+			return nil, err
+		}
+	}
+	base := func(f BookFilter) *gorm.DB {
+		query := db.Model(&models.Book{}).Scopes(VisibleBooks(db, viewerID))
+		if search != nil {
+			query = query.Scopes(search.Scope)
+		}
+		return query.Scopes(f.Scope(db))
+	}
+
+	var facets SearchFacets
+	withoutCategory := filter
+	withoutCategory.Category = ""
+	if err := scanFacet(base(withoutCategory).
+		Joins("JOIN categories ON categories.id = books.category_id").
+		Select("categories.slug AS value, categories.name AS label, COUNT(DISTINCT books.id) AS count").
+		Group("categories.id, categories.slug, categories.name"), &facets.Category); err != nil {
+		return nil, err
+	}
+
+	withoutAuthor := filter
+	withoutAuthor.Authors = nil
+	if err := scanFacet(base(withoutAuthor).
+		Joins("JOIN book_authors ON book_authors.book_id = books.id").
+		Joins("JOIN authors ON authors.id = book_authors.author_id").
+		Select("authors.id AS value, authors.name AS label, COUNT(DISTINCT books.id) AS count").
+		Group("authors.id, authors.name"), &facets.Author); err != nil {
+		return nil, err
+	}
+
+	withoutLanguage := filter
+	withoutLanguage.Languages = nil
+	if err := scanFacet(base(withoutLanguage).
+		Where("books.language <> ''").
+		Select("books.language AS value, books.language AS label, COUNT(DISTINCT books.id) AS count").
+		Group("books.language"), &facets.Language); err != nil {
+		return nil, err
+	}
+
+	withoutTag := filter
+	withoutTag.Tags = nil
+	if err := scanFacet(base(withoutTag).
+		Joins("JOIN book_tags ON book_tags.book_id = books.id").
+		Joins("JOIN tags ON tags.id = book_tags.tag_id").
+		Select("tags.name AS value, tags.display_name AS label, COUNT(DISTINCT books.id) AS count").
+		Group("tags.id, tags.name, tags.display_name"), &facets.Tag); err != nil {
+		return nil, err
+	}
+
+	withoutDecade := filter
+	withoutDecade.Decades = nil
+	if err := scanFacet(base(withoutDecade).
+		Where("books.publish_date IS NOT NULL").
+		Select("FLOOR(YEAR(books.publish_date) / 10) * 10 AS value, '' AS label, COUNT(DISTINCT books.id) AS count").
+		Group("value"), &facets.Decade); err != nil {
+		return nil, err
+	}
+	for i := range facets.Decade {
+		facets.Decade[i].Label = facets.Decade[i].Value + "s"
+	}
+
+	// 只缓存热门组合
+	hits, err := config.RDB.Incr(config.Ctx, hitKey).Result()
+	if err == nil && hits == 1 {
+		config.RDB.Expire(config.Ctx, hitKey, facetHitWindow)
+	}
+	if hits >= facetHotThreshold {
+		if facetsJSON, err := json.Marshal(facets); err == nil {
+			config.RDB.Set(config.Ctx, cacheKey, facetsJSON, facetCacheTTL)
+		}
+	}
+	return &facets, nil
+}
+
+// InvalidateSearchFacets 书籍变更后使已缓存的分面失效。缓存键包含版本号，递增版本号即可让旧缓存全部失效
+func InvalidateSearchFacets() {
+	config.RDB.Incr(config.Ctx, facetVersionKey)
+}
+
+// facetCacheKeys 根据搜索词、筛选条件和当前版本号生成缓存键和命中计数键
+func facetCacheKeys(keyword string, filter BookFilter, viewerID uint) (string, string) {
+	version, err := config.RDB.Get(config.Ctx, facetVersionKey).Int64()
+	if err != nil {
+		version = 0
+	}
+	filterJSON, _ := json.Marshal(filter)
+	sum := sha1.Sum([]byte(keyword + "\x00" + string(filterJSON) + "\x00" + strconv.FormatUint(uint64(viewerID), 10)))
+	digest := hex.EncodeToString(sum[:])
+	v := strconv.FormatInt(version, 10)
+	return fmt.Sprintf(facetCacheKeyFmt, v, digest), fmt.Sprintf(facetHitCounterFmt, v, digest)
+}
+
+func scanFacet(query *gorm.DB, buckets *[]FacetBucket) error {
+	*buckets = make([]FacetBucket, 0)
+	return query.Order("count DESC, value").Limit(facetSize).Scan(buckets).Error
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ISO 2709 分隔符
+const (
+	marcSubfieldDelimiter = 0x1F
+	marcFieldTerminator   = 0x1E
+	marcRecordTerminator  = 0x1D
+)
+
+const (
+	marcXMLNamespace = "http://www.loc.gov/MARC21/slim"
+	// marcMaxFieldLength 目录中字段长度只有 4 位，超长的内容（通常是简介）需要截断
+	marcMaxFieldLength = 9999
+)
+
+// marcRoleTerms 作者角色对应的 MARC 关系词和关系代码
+var marcRoleTerms = map[string][2]string{
+	models.AuthorRoleAuthor:      {"author", "aut"},
+	models.AuthorRoleTranslator:  {"translator", "trl"},
+	models.AuthorRoleEditor:      {"editor", "edt"},
+	models.AuthorRoleIllustrator: {"illustrator", "ill"},
+}
+
+type marcSubfield struct {
+	Code  byte
+	Value string
+}
+
+// marcField 控制字段（00X）只有 Value，数据字段有指示符和子字段
+type marcField struct {
+	Tag       string
+	Ind1      byte
+	Ind2      byte
+	Value     string
+	Subfields []marcSubfield
+}
+
+func (f *marcField) isControl() bool {
+	return strings.HasPrefix(f.Tag, "00")
+}
+
+type marcRecord struct {
+	Fields []marcField
+}
+
+func (r *marcRecord) control(tag, value string) {
+	r.Fields = append(r.Fields, marcField{Tag: tag, Value: value})
+}
+
+// data 添加数据字段，忽略值为空的子字段，没有子字段时不添加
+func (r *marcRecord) data(tag string, ind1, ind2 byte, subfields ...marcSubfield) {
+	field := marcField{Tag: tag, Ind1: ind1, Ind2: ind2}
+	for _, subfield := range subfields {
+		if subfield.Value = strings.TrimSpace(subfield.Value); subfield.Value != "" {
+			field.Subfields = append(field.Subfields, subfield)
+		}
+	}
+	if len(field.Subfields) > 0 {
+		r.Fields = append(r.Fields, field)
+	}
+}
+
+// buildMARCRecord 将书目转换为 MARC21 书目记录
+func buildMARCRecord(record *CatalogRecord) *marcRecord {
+	book := &record.Book
+	r := &marcRecord{}
+	r.control("001", strconv.FormatUint(uint64(book.ID), 10))
+	r.control("005", book.UpdatedAt.UTC().Format("20060102150405")+".0")
+	r.control("008", marc008(book))
+
+	if book.ISBN13 != nil {
+		r.data("020", ' ', ' ', marcSubfield{'a', *book.ISBN13})
+	}
+	if book.ISBN10 != nil {
+		r.data("020", ' ', ' ', marcSubfield{'a', *book.ISBN10})
+	}
+	if book.Language != "" {
+		r.data("041", '0', ' ', marcSubfield{'a', utils.MARCLanguageCode(book.Language)})
+	}
+
+	// 第一位著者作为主要款目（100），其余作者、译者等作为附加款目（700）
+	mainEntry := false
+	for _, link := range book.Contributors {
+		terms := marcRoleTerms[link.Role]
+		tag := "700"
+		if !mainEntry && link.Role == models.AuthorRoleAuthor {
+			tag, mainEntry = "100", true
+		}
+		r.data(tag, '1', ' ', marcSubfield{'a', link.Author.Name}, marcSubfield{'e', terms[0]}, marcSubfield{'4', terms[1]})
+	}
+	titleInd1 := byte('0')
+	if mainEntry {
+		titleInd1 = '1'
+	}
+	r.data("245", titleInd1, '0', marcSubfield{'a', book.Title}, marcSubfield{'c', book.Author})
+	r.data("250", ' ', ' ', marcSubfield{'a', book.Edition})
+	if book.Publisher != "" || book.PublishDate != nil {
+		var year string
+		if book.PublishDate != nil {
+			year = strconv.Itoa(book.PublishDate.Year())
+		}
+		r.data("264", ' ', '1', marcSubfield{'b', book.Publisher}, marcSubfield{'c', year})
+	}
+	if book.PageCount > 0 {
+		r.data("300", ' ', ' ', marcSubfield{'a', fmt.Sprintf("%d pages", book.PageCount)})
+	}
+	if book.Series != nil {
+		var volume string
+		if book.SeriesIndex != nil {
+			volume = strconv.FormatFloat(*book.SeriesIndex, 'f', -1, 64)
+		}
+		r.data("490", '0', ' ', marcSubfield{'a', book.Series.Title}, marcSubfield{'v', volume})
+	}
+	r.data("520", ' ', ' ', marcSubfield{'a', book.Description})
+	r.data("650", ' ', '4', marcSubfield{'a', book.Category})
+	for _, tag := range record.Tags {
+		r.data("653", ' ', ' ', marcSubfield{'a', tag})
+	}
+	if book.CoverImage != "" {
+		r.data("856", '4', '2', marcSubfield{'3', "Cover image"}, marcSubfield{'u', book.CoverImage})
+	}
+	return r
+}
+
+// marc008 生成 008 定长字段（40 个字符）
+func marc008(book *models.Book) string {
+	dateType, date1 := "n", "uuuu"
+	if book.PublishDate != nil {
+		dateType, date1 = "s", fmt.Sprintf("%04d", book.PublishDate.Year())
+	}
+	language := "und"
+	if book.Language != "" {
+		language = utils.MARCLanguageCode(book.Language)
+	}
+	// 0-5 入档日期，6 日期类型，7-14 日期，15-17 出版地（未知），18-34 图书专用（不编码），35-37 语种，38 修改记录，39 编目来源
+	return book.CreatedAt.Format("060102") + dateType + date1 + "    " + "xx " + strings.Repeat("|", 17) + language + " " + "d"
+}
+
+// leader 生成记录头标，length 和 baseAddress 只在 ISO 2709 中有意义
+func (r *marcRecord) leader(length, baseAddress int) string {
+	// 5 记录状态（新记录），6 记录类型（文字资料），7 书目级别（专著），9 字符编码（UCS/Unicode），
+	// 17 编目级别（完整），18 著录形式（ISBD）
+	return fmt.Sprintf("%05dnam a22%05d i 4500", length, baseAddress)
+}
+
+// fieldData 字段在 ISO 2709 中的内容，包含字段结束符
+func (f *marcField) fieldData() []byte {
+	var b strings.Builder
+	if f.isControl() {
+		b.WriteString(f.Value)
+	} else {
+		b.WriteByte(f.Ind1)
+		b.WriteByte(f.Ind2)
+		for _, subfield := range f.Subfields {
+			b.WriteByte(marcSubfieldDelimiter)
+			b.WriteByte(subfield.Code)
+			b.WriteString(subfield.Value)
+		}
+	}
+	data := truncateUTF8(b.String(), marcMaxFieldLength-1)
+	return append([]byte(data), marcFieldTerminator)
+}
+
+// ISO2709 按 ISO 2709 交换格式编码记录
+func (r *marcRecord) ISO2709() []byte {
+	var directory, data []byte
+	for i := range r.Fields {
+		fieldData := r.Fields[i].fieldData()
+		directory = fmt.Appendf(directory, "%s%04d%05d", r.Fields[i].Tag, len(fieldData), len(data))
+		data = append(data, fieldData...)
+	}
+	directory = append(directory, marcFieldTerminator)
+	baseAddress := 24 + len(directory)
+	length := baseAddress + len(data) + 1
+
+	out := make([]byte, 0, length)
+	out = append(out, r.leader(length, baseAddress)...)
+	out = append(out, directory...)
+	out = append(out, data...)
+	return append(out, marcRecordTerminator)
+}
+
+// encodeXML 以 MARCXML 的 <record> 元素写出记录
+func (r *marcRecord) encodeXML(encoder *xml.Encoder) error {
+	attr := func(name, value string) xml.Attr {
+		return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+	}
+	start := xml.StartElement{Name: xml.Name{Local: "record"}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encoder.EncodeElement(r.leader(0, 0), xml.StartElement{Name: xml.Name{Local: "leader"}}); err != nil {
+		return err
+	}
+	for _, field := range r.Fields {
+		if field.isControl() {
+			element := xml.StartElement{Name: xml.Name{Local: "controlfield"}, Attr: []xml.Attr{attr("tag", field.Tag)}}
+			if err := encoder.EncodeElement(field.Value, element); err != nil {
+				return err
+			}
+			continue
+		}
+		element := xml.StartElement{Name: xml.Name{Local: "datafield"}, Attr: []xml.Attr{
+			attr("tag", field.Tag), attr("ind1", string(field.Ind1)), attr("ind2", string(field.Ind2)),
+		}}
+		if err := encoder.EncodeToken(element); err != nil {
+			return err
+		}
+		for _, subfield := range field.Subfields {
+			sub := xml.StartElement{Name: xml.Name{Local: "subfield"}, Attr: []xml.Attr{attr("code", string(subfield.Code))}}
+			if err := encoder.EncodeElement(subfield.Value, sub); err != nil {
+				return err
+			}
+		}
+		if err := encoder.EncodeToken(element.End()); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// marcCatalogEncoder 输出 MARC21 二进制（ISO 2709）记录
+type marcCatalogEncoder struct {
+	w *bufio.Writer
+}
+
+func newMARCCatalogEncoder(w io.Writer) *marcCatalogEncoder {
+	return &marcCatalogEncoder{w: bufio.NewWriter(w)}
+}
+
+func (e *marcCatalogEncoder) Encode(record *CatalogRecord) error {
+	_, err := e.w.Write(buildMARCRecord(record).ISO2709())
+	return err
+}
+
+func (e *marcCatalogEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+func (e *marcCatalogEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// marcXMLCatalogEncoder 输出 MARCXML：<collection> 下每本书一个 <record>
+type marcXMLCatalogEncoder struct {
+	encoder *xml.Encoder
+}
+
+func newMARCXMLCatalogEncoder(w io.Writer) (*marcXMLCatalogEncoder, error) {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return nil, err
+	}
+	encoder := xml.NewEncoder(w)
+	root := xml.StartElement{
+		Name: xml.Name{Local: "collection"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: marcXMLNamespace}},
+	}
+	if err := encoder.EncodeToken(root); err != nil {
+		return nil, err
+	}
+	return &marcXMLCatalogEncoder{encoder: encoder}, nil
+}
+
+func (e *marcXMLCatalogEncoder) Encode(record *CatalogRecord) error {
+	return buildMARCRecord(record).encodeXML(e.encoder)
+}
+
+func (e *marcXMLCatalogEncoder) Flush() error {
+	return e.encoder.Flush()
+}
+
+func (e *marcXMLCatalogEncoder) Close() error {
+	if err := e.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: "collection"}}); err != nil {
+		return err
+	}
+	return e.encoder.Flush()
+}
+
+// truncateUTF8 将字符串截断到最多 n 个字节，不截断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,382 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var ErrMetadataNotFound = errors.New("book metadata not found")
+
+// BookMetadata 外部书目数据源返回的书籍信息
+type BookMetadata struct {
+	ISBN10      string   `json:"isbn10"`
+	ISBN13      string   `json:"isbn13"`
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	Publisher   string   `json:"publisher"`
+	PublishDate string   `json:"publish_date"` // 数据源给出的原始日期，如 "2008-01" 或 "March 2008"
+	PageCount   int      `json:"page_count"`
+	Description string   `json:"description"`
+	CoverImage  string   `json:"cover_image"`
+	Source      string   `json:"source"` // 数据来源
+}
+
+var (
+	publishYearPattern  = regexp.MustCompile(`(1[5-9]\d{2}|20\d{2})`)
+	publishMonthPattern = regexp.MustCompile(`(?:^|[^\d])(1[0-2]|0?[1-9])(?:月|[^\d]|$)`)
+	monthNames          = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+)
+
+// ParsedPublishDate 将数据源的原始日期解析为日期，支持 "2008-01-02"、"2008-1"、"2008年1月"、"March 2008" 等写法，
+// 只能识别出年份时取当年1月1日，无法识别时返回 nil
+func (m *BookMetadata) ParsedPublishDate() *models.Date {
+	raw := strings.TrimSpace(m.PublishDate)
+	if date, err := models.ParseDate(raw); err == nil {
+		return &date
+	}
+	yearLoc := publishYearPattern.FindStringIndex(raw)
+	if yearLoc == nil {
+		return nil
+	}
+	year, _ := strconv.Atoi(raw[yearLoc[0]:yearLoc[1]])
+	month := 1
+	lower := strings.ToLower(raw)
+	found := false
+	for i, name := range monthNames {
+		if strings.Contains(lower, name) {
+			month, found = i+1, true
+			break
+		}
+	}
+	if !found {
+		if match := publishMonthPattern.FindStringSubmatch(raw[yearLoc[1]:]); match != nil {
+			month, _ = strconv.Atoi(match[1])
+		}
+	}
+	return &models.Date{Time: time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// MetadataProvider 书目数据源，根据 ISBN-13 查询书籍信息，找不到时返回 ErrMetadataNotFound
+type MetadataProvider interface {
+	Name() string
+	LookupISBN(ctx context.Context, isbn13 string) (*BookMetadata, error)
+}
+
+// Metadata 当前使用的书目数据源，由 InitMetadataProviders 初始化
+var Metadata MetadataProvider
+
+var metadataHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// InitMetadataProviders 根据环境变量 METADATA_PROVIDERS（逗号分隔，按顺序查询）配置书目数据源
+func InitMetadataProviders() {
+	names := os.Getenv("METADATA_PROVIDERS")
+	if names == "" {
+		names = "openlibrary,douban" // 默认先查 Open Library，再查豆瓣
+	}
+
+	var providers []MetadataProvider
+	for _, name := range strings.Split(names, ",") {
+		switch strings.TrimSpace(name) {
+		case "openlibrary":
+			providers = append(providers, NewOpenLibraryProvider())
+		case "douban":
+			// 豆瓣接口需要 apikey，未配置时跳过
+			if apiKey := os.Getenv("DOUBAN_APIKEY"); apiKey != "" {
+				providers = append(providers, NewDoubanProvider(os.Getenv("DOUBAN_API_URL"), apiKey))
+			}
+		case "fake":
+			providers = append(providers, NewFakeMetadataProvider(sampleMetadata...))
+		case "":
+		default:
+			log.Printf("Unknown metadata provider: %s", name)
+		}
+	}
+	Metadata = ChainProviders(providers...)
+}
+
+// LookupBookMetadata 查询书目信息，结果（包括未找到）缓存在 Redis 中
+func LookupBookMetadata(ctx context.Context, isbn13 string) (*BookMetadata, error) {
+	cacheKey := "book_metadata:" + isbn13
+	val, err := config.RDB.Get(ctx, cacheKey).Result()
+	if err == nil {
+		if val == "null" {
+			return nil, ErrMetadataNotFound
+		}
+		var metadata BookMetadata
+		if err := json.Unmarshal([]byte(val), &metadata); err == nil {
+			return &metadata, nil
+		}
+	} else if err != redis.Nil {
+		log.Printf("Failed to read metadata cache: %v", err)
+	}
+
+	metadata, err := Metadata.LookupISBN(ctx, isbn13)
+	if errors.Is(err, ErrMetadataNotFound) {
+		config.RDB.Set(ctx, cacheKey, "null", 1*time.Hour) // 未找到的结果缓存较短时间
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if metadataJSON, err := json.Marshal(metadata); err == nil {
+		config.RDB.Set(ctx, cacheKey, metadataJSON, 24*time.Hour)
+	}
+	return metadata, nil
+}
+
+// chainProvider 按顺序查询多个数据源，返回第一个找到的结果
+type chainProvider struct {
+	providers []MetadataProvider
+}
+
+func ChainProviders(providers ...MetadataProvider) MetadataProvider {
+	return &chainProvider{providers: providers}
+}
+
+func (p *chainProvider) Name() string {
+	names := make([]string, 0, len(p.providers))
+	for _, provider := range p.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+func (p *chainProvider) LookupISBN(ctx context.Context, isbn13 string) (*BookMetadata, error) {
+	var lastErr error = ErrMetadataNotFound
+	for _, provider := range p.providers {
+		metadata, err := provider.LookupISBN(ctx, isbn13)
+		if err == nil {
+			return metadata, nil
+		}
+		if !errors.Is(err, ErrMetadataNotFound) {
+			log.Printf("Metadata provider %s failed: %v", provider.Name(), err)
+			lastErr = err
+		}
+	}
+	return nil, lastErr
+}
+
+// OpenLibraryProvider 使用 Open Library Books API 查询
+type OpenLibraryProvider struct {
+	BaseURL string
+}
+
+func NewOpenLibraryProvider() *OpenLibraryProvider {
+	return &OpenLibraryProvider{BaseURL: "https://openlibrary.org"}
+}
+
+func (p *OpenLibraryProvider) Name() string { return "openlibrary" }
+
+func (p *OpenLibraryProvider) LookupISBN(ctx context.Context, isbn13 string) (*BookMetadata, error) {
+	bibKey := "ISBN:" + isbn13
+	endpoint := fmt.Sprintf("%s/api/books?bibkeys=%s&format=json&jscmd=data", p.BaseURL, url.QueryEscape(bibKey))
+
+	var result map[string]struct {
+		Title    string `json:"title"`
+		Subtitle string `json:"subtitle"`
+		Authors  []struct {
+			Name string `json:"name"`
+		} `json:"authors"`
+		Publishers []struct {
+			Name string `json:"name"`
+		} `json:"publishers"`
+		PublishDate   string `json:"publish_date"`
+		NumberOfPages int    `json:"number_of_pages"`
+		Notes         string `json:"notes"`
+		Excerpts      []struct {
+			Text string `json:"text"`
+		} `json:"excerpts"`
+		Cover struct {
+			Large  string `json:"large"`
+			Medium string `json:"medium"`
+		} `json:"cover"`
+		Identifiers struct {
+			ISBN10 []string `json:"isbn_10"`
+		} `json:"identifiers"`
+	}
+	if err := getJSON(ctx, endpoint, &result); err != nil {
+		return nil, err
+	}
+	data, ok := result[bibKey]
+	if !ok {
+		return nil, ErrMetadataNotFound
+	}
+
+	metadata := &BookMetadata{
+		ISBN13:      isbn13,
+		Title:       data.Title,
+		PublishDate: data.PublishDate,
+		PageCount:   data.NumberOfPages,
+		Description: data.Notes,
+		CoverImage:  data.Cover.Large,
+		Source:      p.Name(),
+	}
+	if data.Subtitle != "" {
+		metadata.Title += ": " + data.Subtitle
+	}
+	for _, author := range data.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	if len(data.Publishers) > 0 {
+		metadata.Publisher = data.Publishers[0].Name
+	}
+	if metadata.Description == "" && len(data.Excerpts) > 0 {
+		metadata.Description = data.Excerpts[0].Text
+	}
+	if metadata.CoverImage == "" {
+		metadata.CoverImage = data.Cover.Medium
+	}
+	if len(data.Identifiers.ISBN10) > 0 {
+		metadata.ISBN10 = data.Identifiers.ISBN10[0]
+	}
+	return metadata, nil
+}
+
+// DoubanProvider 使用豆瓣风格的 /v2/book/isbn/:isbn 接口查询
+type DoubanProvider struct {
+	BaseURL string
+	APIKey  string
+}
+
+func NewDoubanProvider(baseURL, apiKey string) *DoubanProvider {
+	if baseURL == "" {
+		baseURL = "https://api.douban.com"
+	}
+	return &DoubanProvider{BaseURL: strings.TrimRight(baseURL, "/"), APIKey: apiKey}
+}
+
+func (p *DoubanProvider) Name() string { return "douban" }
+
+func (p *DoubanProvider) LookupISBN(ctx context.Context, isbn13 string) (*BookMetadata, error) {
+	endpoint := fmt.Sprintf("%s/v2/book/isbn/%s?apikey=%s", p.BaseURL, isbn13, url.QueryEscape(p.APIKey))
+
+	var data struct {
+		Title     string   `json:"title"`
+		Subtitle  string   `json:"subtitle"`
+		Author    []string `json:"author"`
+		Publisher string   `json:"publisher"`
+		Pubdate   string   `json:"pubdate"`
+		Pages     string   `json:"pages"`
+		Summary   string   `json:"summary"`
+		Image     string   `json:"image"`
+		Images    struct {
+			Large string `json:"large"`
+		} `json:"images"`
+		ISBN10 string `json:"isbn10"`
+		ISBN13 string `json:"isbn13"`
+	}
+	if err := getJSON(ctx, endpoint, &data); err != nil {
+		return nil, err
+	}
+	if data.Title == "" {
+		return nil, ErrMetadataNotFound
+	}
+
+	metadata := &BookMetadata{
+		ISBN10:      data.ISBN10,
+		ISBN13:      isbn13,
+		Title:       data.Title,
+		Authors:     data.Author,
+		Publisher:   data.Publisher,
+		PublishDate: data.Pubdate,
+		Description: data.Summary,
+		CoverImage:  data.Images.Large,
+		Source:      p.Name(),
+	}
+	if data.Subtitle != "" {
+		metadata.Title += "：" + data.Subtitle
+	}
+	if metadata.CoverImage == "" {
+		metadata.CoverImage = data.Image
+	}
+	// 豆瓣的页数可能是 "320页" 这样的字符串
+	metadata.PageCount, _ = strconv.Atoi(strings.TrimRight(strings.TrimSpace(data.Pages), "页 "))
+	return metadata, nil
+}
+
+// FakeMetadataProvider 基于内存数据的数据源，用于测试和本地开发
+type FakeMetadataProvider struct {
+	Records map[string]*BookMetadata
+}
+
+func NewFakeMetadataProvider(records ...BookMetadata) *FakeMetadataProvider {
+	p := &FakeMetadataProvider{Records: make(map[string]*BookMetadata)}
+	for i := range records {
+		record := records[i]
+		record.Source = p.Name()
+		p.Records[record.ISBN13] = &record
+	}
+	return p
+}
+
+func (p *FakeMetadataProvider) Name() string { return "fake" }
+
+func (p *FakeMetadataProvider) LookupISBN(ctx context.Context, isbn13 string) (*BookMetadata, error) {
+	metadata, ok := p.Records[isbn13]
+	if !ok {
+		return nil, ErrMetadataNotFound
+	}
+	result := *metadata
+	return &result, nil
+}
+
+// sampleMetadata 使用 fake 数据源时内置的示例数据
+var sampleMetadata = []BookMetadata{
+	{
+		ISBN10:      "7536692935",
+		ISBN13:      "9787536692930",
+		Title:       "三体",
+		Authors:     []string{"刘慈欣"},
+		Publisher:   "重庆出版社",
+		PublishDate: "2008-01",
+		PageCount:   302,
+		Description: "文化大革命如火如荼进行的同时，军方探寻外星文明的绝秘计划“红岸工程”取得了突破性进展。",
+	},
+	{
+		ISBN10:      "0306406152",
+		ISBN13:      "9780306406157",
+		Title:       "Molecular Biology of the Gene",
+		Authors:     []string{"James D. Watson"},
+		Publisher:   "Benjamin",
+		PublishDate: "1970",
+		PageCount:   662,
+	},
+}
+
+// getJSON 发送 GET 请求并解析 JSON 响应，404 视为未找到
+func getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := metadataHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrMetadataNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,311 @@
+package services
+
+import (
+	"bookshare/config"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+func TestParsedPublishDate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string // 空表示无法解析
+	}{
+		{"2008-01-02", "2008-01-02"},
+		{" 2008-01-02 ", "2008-01-02"},
+		{"2008-1", "2008-01-01"},
+		{"2008-11", "2008-11-01"},
+		{"2008年1月", "2008-01-01"},
+		{"2008年12月", "2008-12-01"},
+		{"2008", "2008-01-01"},
+		{"March 2008", "2008-03-01"},
+		{"Sep 2, 1999", "1999-09-01"},
+		{"c1985", "1985-01-01"},
+		{"", ""},
+		{"unknown", ""},
+		{"printed 1400", ""},
+	}
+	for _, tt := range tests {
+		metadata := BookMetadata{PublishDate: tt.raw}
+		date := metadata.ParsedPublishDate()
+		if tt.want == "" {
+			if date != nil {
+				t.Errorf("ParsedPublishDate(%q) = %v, want nil", tt.raw, date.Time)
+			}
+			continue
+		}
+		if date == nil {
+			t.Errorf("ParsedPublishDate(%q) = nil, want %s", tt.raw, tt.want)
+			continue
+		}
+		if got := date.Time.Format(time.DateOnly); got != tt.want {
+			t.Errorf("ParsedPublishDate(%q) = %s, want %s", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestOpenLibraryProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/books" || r.URL.Query().Get("jscmd") != "data" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		switch r.URL.Query().Get("bibkeys") {
+		case "ISBN:9780306406157":
+			fmt.Fprint(w, `{"ISBN:9780306406157": {
+				"title": "Molecular Biology",
+				"subtitle": "of the Gene",
+				"authors": [{"name": "James D. Watson"}, {"name": "Tania A. Baker"}],
+				"publishers": [{"name": "Benjamin"}, {"name": "Other"}],
+				"publish_date": "1970",
+				"number_of_pages": 662,
+				"excerpts": [{"text": "An excerpt"}],
+				"cover": {"medium": "https://covers.example/m.jpg"},
+				"identifiers": {"isbn_10": ["0306406152"]}
+			}}`)
+		case "ISBN:9787536692930":
+			http.Error(w, "boom", http.StatusInternalServerError)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+	provider := &OpenLibraryProvider{BaseURL: server.URL}
+
+	metadata, err := provider.LookupISBN(context.Background(), "9780306406157")
+	if err != nil {
+		t.Fatalf("LookupISBN() error = %v", err)
+	}
+	want := &BookMetadata{
+		ISBN10:      "0306406152",
+		ISBN13:      "9780306406157",
+		Title:       "Molecular Biology: of the Gene",
+		Authors:     []string{"James D. Watson", "Tania A. Baker"},
+		Publisher:   "Benjamin",
+		PublishDate: "1970",
+		PageCount:   662,
+		Description: "An excerpt",
+		CoverImage:  "https://covers.example/m.jpg",
+		Source:      "openlibrary",
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("LookupISBN() = %+v, want %+v", metadata, want)
+	}
+
+	if _, err := provider.LookupISBN(context.Background(), "9781234567897"); !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("LookupISBN(missing) error = %v, want ErrMetadataNotFound", err)
+	}
+	if _, err := provider.LookupISBN(context.Background(), "9787536692930"); err == nil || errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("LookupISBN(server error) error = %v, want an upstream error", err)
+	}
+}
+
+func TestDoubanProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "secret" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/book/isbn/9787536692930":
+			fmt.Fprint(w, `{
+				"title": "三体",
+				"subtitle": "地球往事",
+				"author": ["刘慈欣"],
+				"publisher": "重庆出版社",
+				"pubdate": "2008-1",
+				"pages": "302页",
+				"summary": "简介",
+				"image": "https://img.example/s.jpg",
+				"isbn10": "7536692935",
+				"isbn13": "9787536692930"
+			}`)
+		case "/v2/book/isbn/9780306406157":
+			fmt.Fprint(w, `{"msg": "book_not_found"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	provider := NewDoubanProvider(server.URL+"/", "secret")
+
+	metadata, err := provider.LookupISBN(context.Background(), "9787536692930")
+	if err != nil {
+		t.Fatalf("LookupISBN() error = %v", err)
+	}
+	want := &BookMetadata{
+		ISBN10:      "7536692935",
+		ISBN13:      "9787536692930",
+		Title:       "三体：地球往事",
+		Authors:     []string{"刘慈欣"},
+		Publisher:   "重庆出版社",
+		PublishDate: "2008-1",
+		PageCount:   302,
+		Description: "简介",
+		CoverImage:  "https://img.example/s.jpg",
+		Source:      "douban",
+	}
+	if !reflect.DeepEqual(metadata, want) {
+		t.Errorf("LookupISBN() = %+v, want %+v", metadata, want)
+	}
+
+	for _, isbn := range []string{"9780306406157", "9781234567897"} {
+		if _, err := provider.LookupISBN(context.Background(), isbn); !errors.Is(err, ErrMetadataNotFound) {
+			t.Errorf("LookupISBN(%s) error = %v, want ErrMetadataNotFound", isbn, err)
+		}
+	}
+	if _, err := NewDoubanProvider(server.URL, "wrong").LookupISBN(context.Background(), "9787536692930"); err == nil || errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("LookupISBN(wrong apikey) error = %v, want an upstream error", err)
+	}
+}
+
+func TestLookupBookMetadataCache(t *testing.T) {
+	cache := startFakeRedis(t)
+	rdb, metadata := config.RDB, Metadata
+	defer func() { config.RDB, Metadata = rdb, metadata }()
+	config.RDB = redis.NewClient(&redis.Options{Addr: cache.addr})
+	defer config.RDB.Close()
+	counter := &countingProvider{MetadataProvider: NewFakeMetadataProvider(sampleMetadata...)}
+	Metadata = counter
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		book, err := LookupBookMetadata(ctx, "9787536692930")
+		if err != nil {
+			t.Fatalf("LookupBookMetadata() error = %v", err)
+		}
+		if book.Title != "三体" || book.Source != "fake" {
+			t.Errorf("LookupBookMetadata() = %+v, want the sample record", book)
+		}
+	}
+	if counter.calls != 1 {
+		t.Errorf("provider called %d times, want 1 (second lookup should hit the cache)", counter.calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := LookupBookMetadata(ctx, "9781234567897"); !errors.Is(err, ErrMetadataNotFound) {
+			t.Fatalf("LookupBookMetadata(missing) error = %v, want ErrMetadataNotFound", err)
+		}
+	}
+	if counter.calls != 2 {
+		t.Errorf("provider called %d times, want 2 (not found should be cached)", counter.calls)
+	}
+	if got := cache.get("book_metadata:9781234567897"); got != "null" {
+		t.Errorf("cached not found = %q, want \"null\"", got)
+	}
+}
+
+// countingProvider 记录数据源被查询的次数
+type countingProvider struct {
+	MetadataProvider
+	calls int
+}
+
+func (p *countingProvider) LookupISBN(ctx context.Context, isbn13 string) (*BookMetadata, error) {
+	p.calls++
+	return p.MetadataProvider.LookupISBN(ctx, isbn13)
+}
+
+// fakeRedis 只支持 GET 和 SET 的内存 Redis 服务，用于测试缓存逻辑
+type fakeRedis struct {
+	addr   string
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	r := &fakeRedis{addr: listener.Addr().String(), values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) get(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.values[key]
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readRESPArray(reader)
+		if err != nil {
+			return
+		}
+		var reply string
+		r.mu.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			if value, ok := r.values[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			r.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		default:
+			reply = "-ERR unsupported command\r\n"
+		}
+		r.mu.Unlock()
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+// readRESPArray 读取一条 RESP 数组格式的命令
+func readRESPArray(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("unexpected command %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, fmt.Errorf("unexpected argument %q", header)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
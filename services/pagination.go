@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultPageSize = 10
+	MaxPageSize     = 100
+)
+
+// SortColumn 列表可排序的字段，Column 为带表名的列，Field 为模型中对应的数据库字段名
+type SortColumn struct {
+	Column   string
+	Field    string
+	Nullable bool // 可以为 NULL 的字段，MySQL 升序时 NULL 在前，降序时在后
+	Time     bool // 时间字段，游标中以 RFC3339 保存
+}
+
+// PageCursor 游标分页的位置：上一页最后一条记录的排序字段值和 ID
+type PageCursor struct {
+	Sort  string  `json:"s"`
+	Desc  bool    `json:"d"`
+	Value *string `json:"v"`
+	ID    uint    `json:"i"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor 将游标编码为不透明的字符串
+func EncodeCursor(cursor PageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor 解析 EncodeCursor 生成的游标
+func DecodeCursor(s string) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// idColumn 与排序字段同表的主键列
+func (col SortColumn) idColumn() string {
+	if table, _, ok := strings.Cut(col.Column, "."); ok {
+		return table + ".id"
+	}
+	return "id"
+}
+
+// Order 按该字段排序，并以 ID 作为第二排序键保证结果稳定
+func (col SortColumn) Order(desc bool) func(*gorm.DB) *gorm.DB {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(col.Column + " " + direction).Order(col.idColumn() + " " + direction)
+	}
+}
+
+// After 只保留排在游标之后的记录（keyset 分页），避免深翻页时 OFFSET 的开销
+func (col SortColumn) After(cursor *PageCursor) (func(*gorm.DB) *gorm.DB, error) {
+	var value interface{}
+	if cursor.Value != nil {
+		value = *cursor.Value
+		if col.Time {
+			t, err := time.Parse(time.RFC3339Nano, *cursor.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			value = t
+		}
+	} else if !col.Nullable {
+		return nil, ErrInvalidCursor
+	}
+
+	id := col.idColumn()
+	var expr clause.Expr
+	switch {
+	case value == nil && cursor.Desc:
+		// 降序时 NULL 排在最后，之后只剩 ID 更小的 NULL
+		expr = gorm.Expr(col.Column+" IS NULL AND "+id+" < ?", cursor.ID)
+	case value == nil:
+		expr = gorm.Expr("("+col.Column+" IS NULL AND "+id+" > ?) OR "+col.Column+" IS NOT NULL", cursor.ID)
+	case cursor.Desc:
+		sql := col.Column + " < ? OR (" + col.Column + " = ? AND " + id + " < ?)"
+		if col.Nullable {
+			sql += " OR " + col.Column + " IS NULL"
+		}
+		expr = gorm.Expr(sql, value, value, cursor.ID)
+	default:
+		expr = gorm.Expr(col.Column+" > ? OR ("+col.Column+" = ? AND "+id+" > ?)", value, value, cursor.ID)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(expr)
+	}, nil
+}
+
+// CursorValue 将记录中排序字段的值转换为游标中保存的形式
+func (col SortColumn) CursorValue(value interface{}) (*string, error) {
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		return &s, nil
+	default:
+		s := fmt.Sprint(v)
+		return &s, nil
+	}
+}
@@ -0,0 +1,624 @@
+package services
+
+import (
+	"bookshare/models"
+	"bookshare/utils"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 阅读记录导入来源
+const (
+	ReadingSourceGoodreads = "goodreads"
+	ReadingSourceDouban    = "douban"
+)
+
+const (
+	// readingMatchThreshold 书名和作者模糊匹配的可信度达到该值时自动匹配
+	readingMatchThreshold = 0.9
+	// readingCandidateThreshold 可信度低于该值的书籍不作为候选
+	readingCandidateThreshold = 0.5
+	// readingMatchMargin 最佳候选需要比第二名高出的可信度，否则交给用户确认
+	readingMatchMargin = 0.05
+	// readingCandidateLimit 每条记录最多保留的候选书籍数量
+	readingCandidateLimit = 5
+)
+
+var (
+	ErrReadingEntryResolved = errors.New("entry has already been resolved")
+	ErrInvalidReadingBook   = errors.New("invalid book")
+)
+
+// readingStatusRank 阅读状态的先后，导入时不会用较早的状态覆盖较晚的状态，例如已读的书不会变回想读
+var readingStatusRank = map[string]int{
+	models.RelationWantToRead: 1,
+	models.RelationReading:    2,
+	models.RelationRead:       3,
+}
+
+// readingColumnAliases 豆瓣和 Goodreads 导出文件的列名，统一为内部名称
+var readingColumnAliases = map[string]string{
+	// Goodreads
+	"book id": "id", "title": "title", "author": "author", "additional authors": "additional_authors",
+	"isbn": "isbn10", "isbn13": "isbn13", "my rating": "rating", "publisher": "publisher",
+	"number of pages": "pages", "year published": "year", "original publication year": "original_year",
+	"date read": "read_at", "date added": "added_at", "exclusive shelf": "status",
+	// 豆瓣
+	"标题": "title", "书名": "title", "名称": "title", "作者": "author", "出版社": "publisher",
+	"出版日期": "year", "出版年": "year", "页数": "pages", "状态": "status", "类别": "status",
+	"我的评分": "rating", "个人评分": "rating", "评分": "rating", "标记日期": "added_at", "日期": "added_at",
+	"打分日期": "added_at", "创建时间": "added_at", "链接": "url", "条目链接": "url", "豆瓣id": "id",
+	"subject_id": "id", "url": "url", "link": "url", "status": "status", "rating": "rating", "date": "added_at",
+}
+
+// readingShelves 书架或状态名称对应的关系类型
+var readingShelves = map[string]string{
+	"read": models.RelationRead, "currently-reading": models.RelationReading, "to-read": models.RelationWantToRead,
+	"读过": models.RelationRead, "在读": models.RelationReading, "想读": models.RelationWantToRead,
+	"collect": models.RelationRead, "do": models.RelationReading, "wish": models.RelationWantToRead,
+}
+
+// doubanRatings 豆瓣评分的文字描述
+var doubanRatings = map[string]int{"很差": 1, "较差": 2, "还行": 3, "推荐": 4, "力荐": 5}
+
+var doubanSubjectURL = regexp.MustCompile(`douban\.com/subject/(\d+)`)
+
+// ParseReadingHistory 解析 Goodreads 或豆瓣导出的 CSV，source 为空时根据表头判断来源
+func ParseReadingHistory(source string, r io.Reader) (string, []models.ReadingImportEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make([]string, len(header))
+	hasTitle := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if source == "" && name == "exclusive shelf" {
+			source = ReadingSourceGoodreads
+		}
+		columns[i] = readingColumnAliases[name]
+		hasTitle = hasTitle || columns[i] == "title"
+	}
+	if !hasTitle {
+		return "", nil, errors.New("CSV header must contain a title column")
+	}
+	if source == "" {
+		source = ReadingSourceDouban
+	}
+	if source != ReadingSourceGoodreads && source != ReadingSourceDouban {
+		return "", nil, fmt.Errorf("unsupported reading history source: %s", source)
+	}
+
+	var entries []models.ReadingImportEntry
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return "", nil, err
+			}
+			entries = append(entries, models.ReadingImportEntry{Row: parseErr.StartLine, Errors: []string{parseErr.Err.Error()}})
+			continue
+		}
+		if strings.TrimSpace(strings.Join(fields, "")) == "" {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(fields))
+		for i, value := range fields {
+			if i < len(columns) && columns[i] != "" {
+				values[columns[i]] = strings.TrimSpace(value)
+			}
+		}
+		entries = append(entries, readingEntryFromValues(source, line, values))
+	}
+	if len(entries) == 0 {
+		return "", nil, errors.New("import file contains no books")
+	}
+	if len(entries) > MaxImportRows {
+		return "", nil, fmt.Errorf("import file has %d rows, at most %d are allowed", len(entries), MaxImportRows)
+	}
+	return source, entries, nil
+}
+
+// readingEntryFromValues 将一行字段转换为阅读记录
+func readingEntryFromValues(source string, row int, values map[string]string) models.ReadingImportEntry {
+	entry := models.ReadingImportEntry{Row: row}
+	book := &entry.Book
+	book.Title = values["title"]
+	book.Author = values["author"]
+	if additional := values["additional_authors"]; additional != "" {
+		book.Author += ", " + additional
+	}
+	book.Publisher = values["publisher"]
+	// Goodreads 为防止 Excel 转换，ISBN 写作 ="0439023483"
+	book.ISBN10 = strings.Trim(values["isbn10"], `="`)
+	book.ISBN13 = strings.Trim(values["isbn13"], `="`)
+	if n, err := strconv.Atoi(values["pages"]); err == nil && n > 0 {
+		book.PageCount = n
+	}
+	year := values["year"]
+	if year == "" {
+		year = values["original_year"]
+	}
+	if year != "" {
+		if date, err := models.ParseDate(strings.ReplaceAll(year, "/", "-")); err == nil {
+			book.PublishDate = &date
+		}
+	}
+
+	book.Scheme, book.ExternalID = source, values["id"]
+	if m := doubanSubjectURL.FindStringSubmatch(values["url"]); m != nil {
+		book.Scheme, book.ExternalID = ReadingSourceDouban, m[1]
+	}
+	if book.ExternalID == "" {
+		book.Scheme = ""
+	}
+	entry.Title, entry.Author = book.Title, book.Author
+
+	status := strings.ToLower(values["status"])
+	entry.Relation = readingShelves[status]
+	if entry.Relation == "" {
+		switch {
+		case status != "":
+			entry.Errors = append(entry.Errors, fmt.Sprintf("unknown shelf: %s", values["status"]))
+		case values["read_at"] != "":
+			entry.Relation = models.RelationRead
+		default:
+			entry.Relation = models.RelationWantToRead
+		}
+	}
+	if rating, ok := parseReadingRating(values["rating"]); ok {
+		entry.Rating = rating
+	} else {
+		entry.Errors = append(entry.Errors, fmt.Sprintf("invalid rating: %s", values["rating"]))
+	}
+	if v := values["added_at"]; v != "" {
+		if t, ok := parseReadingTime(v); ok {
+			entry.AddedAt = &t
+		} else {
+			entry.Errors = append(entry.Errors, fmt.Sprintf("invalid date: %s", v))
+		}
+	}
+	readAt := values["read_at"]
+	if readAt == "" && source == ReadingSourceDouban && entry.Relation == models.RelationRead {
+		readAt = values["added_at"] // 豆瓣只有标记日期，读过的书以标记日期作为读完日期
+	}
+	if readAt != "" {
+		if t, ok := parseReadingTime(readAt); ok {
+			date := models.Date{Time: time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+			entry.ReadAt = &date
+		} else {
+			entry.Errors = append(entry.Errors, fmt.Sprintf("invalid date: %s", readAt))
+		}
+	}
+	if strings.TrimSpace(book.Title) == "" {
+		entry.Errors = append(entry.Errors, "title is required")
+	}
+	return entry
+}
+
+// parseReadingRating 解析评分：数字（0 表示未评分）、星号或豆瓣的文字评价
+func parseReadingRating(s string) (*int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, true
+	}
+	rating, ok := doubanRatings[s]
+	if !ok {
+		if stars := strings.Count(s, "★"); stars > 0 {
+			rating, ok = stars, true
+		} else if n, err := strconv.Atoi(s); err == nil {
+			rating, ok = n, true
+		}
+	}
+	if !ok || rating < 0 || rating > 5 {
+		return nil, false
+	}
+	if rating == 0 {
+		return nil, true
+	}
+	return &rating, true
+}
+
+// readingTimeLayouts 导出文件中常见的日期格式
+var readingTimeLayouts = []string{
+	"2006/01/02", "2006/1/2", "2006-01-02", "2006-1-2", "2006-01-02 15:04:05", "2006/01/02 15:04:05",
+	"2006-01-02 15:04", time.RFC3339,
+}
+
+func parseReadingTime(s string) (time.Time, bool) {
+	for _, layout := range readingTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// StartReadingImport 保存导入任务和全部条目，并在后台匹配书籍
+func StartReadingImport(db *gorm.DB, job *models.ReadingImport, entries []models.ReadingImportEntry) error {
+	job.Status = models.ImportPending
+	job.Total = len(entries)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		for i := range entries {
+			entries[i].ImportID = job.ID
+			entries[i].Status = models.ReadingEntryUnmatched
+		}
+		return tx.CreateInBatches(entries, 500).Error
+	})
+	if err != nil {
+		return err
+	}
+	run := *job // 后台任务使用副本，调用方可以继续读取 job
+	go RunReadingImport(db, &run, entries)
+	return nil
+}
+
+// RunReadingImport 逐条匹配书籍并写入阅读记录，不确定的匹配留给用户确认
+func RunReadingImport(db *gorm.DB, job *models.ReadingImport, entries []models.ReadingImportEntry) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Reading import %d panicked: %v", job.ID, r)
+			finishReadingImport(db, job, fmt.Errorf("internal error: %v", r))
+		}
+	}()
+
+	job.Status = models.ImportRunning
+	db.Model(job).Update("status", job.Status)
+
+	var createdIDs []uint
+	relatedIDs := make(map[uint]bool)
+	for i := range entries {
+		entry := &entries[i]
+		processReadingEntry(db, job, entry)
+		switch entry.Status {
+		case models.ReadingEntryImported:
+			job.Imported++
+			relatedIDs[*entry.BookID] = true
+			if entry.MatchedBy == models.MatchByCreated {
+				job.BooksCreated++
+				createdIDs = append(createdIDs, *entry.BookID)
+			}
+		case models.ReadingEntryReview:
+			job.Review++
+		case models.ReadingEntryUnmatched:
+			job.Unmatched++
+		case models.ReadingEntryError:
+			job.Failed++
+		}
+		if err := db.Save(entry).Error; err != nil {
+			log.Printf("Failed to save reading import entry %d: %v", entry.ID, err)
+		}
+		job.Processed++
+		if job.Processed%importProgressEvery == 0 {
+			db.Model(job).Updates(map[string]interface{}{
+				"processed": job.Processed, "imported": job.Imported, "books_created": job.BooksCreated,
+				"review": job.Review, "unmatched": job.Unmatched, "failed": job.Failed,
+			})
+		}
+	}
+
+	if len(createdIDs) > 0 {
+		SyncSearchIndex(createdIDs...)
+		TriggerSavedSearches()
+	}
+	for _, id := range createdIDs {
+		SuggestBookRelated(db, id)
+		delete(relatedIDs, id)
+	}
+	// 收藏、阅读数量影响补全排序
+	for id := range relatedIDs {
+		SuggestBook(db, id)
+	}
+	finishReadingImport(db, job, nil)
+}
+
+// processReadingEntry 匹配一条记录：外部编号、ISBN 精确匹配，书名和作者模糊匹配；
+// 可信度足够时写入阅读记录，否则保存候选书籍等待确认，没有候选时按设置创建书籍
+func processReadingEntry(db *gorm.DB, job *models.ReadingImport, entry *models.ReadingImportEntry) {
+	if len(entry.Errors) > 0 {
+		entry.Status = models.ReadingEntryError
+		return
+	}
+	book, matchedBy, score, candidates := matchReadingEntry(db, entry)
+	entry.Score, entry.Candidates = score, candidates
+	if book == nil && len(candidates) > 0 {
+		entry.Status = models.ReadingEntryReview
+		return
+	}
+	if book == nil && !job.CreateMissing {
+		entry.Status = models.ReadingEntryUnmatched
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if book == nil {
+			created, err := createReadingBook(tx, entry, job.UserID)
+			if err != nil {
+				return err
+			}
+			book, matchedBy, entry.Score = created, models.MatchByCreated, 1
+		}
+		entry.BookID, entry.MatchedBy = &book.ID, matchedBy
+		return applyReadingEntry(tx, job.UserID, entry)
+	})
+	if err != nil {
+		entry.BookID, entry.MatchedBy = nil, ""
+		entry.Status, entry.Errors = models.ReadingEntryError, []string{err.Error()}
+		return
+	}
+	entry.Status = models.ReadingEntryImported
+}
+
+// matchReadingEntry 查找记录对应的书籍。精确匹配或足够可信的模糊匹配返回书籍，否则返回候选书籍
+func matchReadingEntry(db *gorm.DB, entry *models.ReadingImportEntry) (*models.Book, string, float64, []models.ReadingImportCandidate) {
+	info := &entry.Book
+	if info.ExternalID != "" {
+		var book models.Book
+		if db.Where("id = (?)", db.Model(&models.BookIdentifier{}).Select("book_id").
+			Where("scheme = ? AND value = ?", info.Scheme, info.ExternalID)).First(&book).Error == nil {
+			return &book, models.MatchByIdentifier, 1, nil
+		}
+	}
+	for _, isbn := range []string{info.ISBN13, info.ISBN10} {
+		if _, isbn13, err := utils.ParseISBN(isbn); err == nil {
+			if book := FindBookByISBN(db, &isbn13, 0); book != nil {
+				return book, models.MatchByISBN, 1, nil
+			}
+		}
+	}
+
+	title := utils.MainTitle(info.Title)
+	if title == "" {
+		return nil, "", 0, nil
+	}
+	query := NewSearchQuery(db, title)
+	var books []models.Book
+	if err := db.Model(&models.Book{}).Scopes(query.Scope, query.OrderByRelevance).Limit(20).Find(&books).Error; err != nil {
+		log.Printf("Failed to search books for reading import: %v", err)
+		return nil, "", 0, nil
+	}
+	var candidates []models.ReadingImportCandidate
+	for _, book := range books {
+		score := utils.TitleSimilarity(info.Title, book.Title)
+		if info.Author != "" && book.Author != "" {
+			score = 0.7*score + 0.3*utils.AuthorSimilarity(info.Author, book.Author)
+		} else {
+			score *= 0.9 // 缺少作者时无法确认，不会自动匹配
+		}
+		if score >= readingCandidateThreshold {
+			candidates = append(candidates, models.ReadingImportCandidate{BookID: book.ID, Title: book.Title, Author: book.Author, Score: score})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, "", 0, nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > readingCandidateLimit {
+		candidates = candidates[:readingCandidateLimit]
+	}
+	best := candidates[0]
+	if best.Score >= readingMatchThreshold && (len(candidates) == 1 || best.Score-candidates[1].Score >= readingMatchMargin) {
+		for i := range books {
+			if books[i].ID == best.BookID {
+				return &books[i], models.MatchByTitle, best.Score, nil
+			}
+		}
+	}
+	return nil, "", best.Score, candidates
+}
+
+// createReadingBook 按记录中的书目信息创建书籍，校验规则与批量导入相同
+func createReadingBook(tx *gorm.DB, entry *models.ReadingImportEntry, userID uint) (*models.Book, error) {
+	info := &entry.Book
+	record := ImportRecord{Row: entry.Row}
+	// 用户导入时新建的书籍与直接上传的一样需要审核后才公开
+	now := time.Now()
+	record.Book = models.Book{
+		Title: info.Title, Author: info.Author, Publisher: info.Publisher,
+		PublishDate: info.PublishDate, PageCount: info.PageCount, UserID: userID,
+		Status: models.BookPending, SubmittedAt: &now,
+	}
+	if info.ISBN13 != "" {
+		record.Book.ISBN13 = &info.ISBN13
+	}
+	if info.ISBN10 != "" {
+		record.Book.ISBN10 = &info.ISBN10
+	}
+	if info.ExternalID != "" {
+		record.Identifiers = []models.BookIdentifier{{Scheme: info.Scheme, Value: info.ExternalID}}
+	}
+	if errs := validateImportRecord(tx, &record); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidReadingBook, strings.Join(errs, "; "))
+	}
+	if err := CreateBook(tx, &record.Book, nil); err != nil {
+		return nil, err
+	}
+	if _, _, err := saveImportExtras(tx, &record.Book, &record, userID); err != nil {
+		return nil, err
+	}
+	if err := RecordInitialBookStatus(tx, &record.Book); err != nil {
+		return nil, err
+	}
+	if err := RecordBookRevision(tx, nil, models.BookRevision{BookID: record.Book.ID, UserID: userID, Action: models.RevisionCreate}); err != nil {
+		return nil, err
+	}
+	return &record.Book, nil
+}
+
+// applyReadingEntry 写入用户的阅读记录。已有相同关系时只补充缺少的评分和日期；
+// 已有更靠后的阅读状态时保留原状态，较早的状态（如想读）被新的状态替换
+func applyReadingEntry(tx *gorm.DB, userID uint, entry *models.ReadingImportEntry) error {
+	var existing []models.UserBookRelation
+	if err := tx.Where("user_id = ? AND book_id = ? AND relation_type IN ?", userID, *entry.BookID,
+		[]string{models.RelationRead, models.RelationReading, models.RelationWantToRead}).Find(&existing).Error; err != nil {
+		return err
+	}
+	for i := range existing {
+		relation := &existing[i]
+		if readingStatusRank[relation.RelationType] < readingStatusRank[entry.Relation] {
+			if err := tx.Delete(relation).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		// 已有相同或更靠后的状态
+		var fields []string
+		if relation.Rating == nil && entry.Rating != nil {
+			relation.Rating = entry.Rating
+			fields = append(fields, "Rating")
+		}
+		if relation.ReadAt == nil && entry.ReadAt != nil && relation.RelationType == models.RelationRead {
+			relation.ReadAt = entry.ReadAt
+			fields = append(fields, "ReadAt")
+		}
+		if len(fields) > 0 {
+			if err := tx.Model(relation).Select(fields).Updates(relation).Error; err != nil {
+				return err
+			}
+		}
+		entry.RelationID = &relation.ID
+		return nil
+	}
+
+	relation := models.UserBookRelation{
+		UserID: userID, BookID: *entry.BookID, RelationType: entry.Relation, Rating: entry.Rating, ReadAt: entry.ReadAt,
+	}
+	if entry.AddedAt != nil {
+		relation.CreatedAt = *entry.AddedAt
+	}
+	if err := tx.Omit("User", "Book").Create(&relation).Error; err != nil {
+		return err
+	}
+	entry.RelationID = &relation.ID
+	return nil
+}
+
+// ResolveReadingEntry 处理等待确认或未匹配的记录：action 为 match 时使用指定的书籍，
+// create 时按记录创建书籍，skip 时跳过。处理后更新任务的统计，全部确认后任务完成
+func ResolveReadingEntry(db *gorm.DB, job *models.ReadingImport, entry *models.ReadingImportEntry, action string, bookID uint) error {
+	if entry.Status != models.ReadingEntryReview && entry.Status != models.ReadingEntryUnmatched {
+		return ErrReadingEntryResolved
+	}
+	var created bool
+	err := db.Transaction(func(tx *gorm.DB) error {
+		switch action {
+		case "skip":
+			entry.Status = models.ReadingEntrySkipped
+			return tx.Model(entry).Update("status", entry.Status).Error
+		case "match":
+			var book models.Book
+			if err := tx.First(&book, bookID).Error; err != nil {
+				return fmt.Errorf("%w: book not found", ErrInvalidReadingBook)
+			}
+			entry.BookID, entry.MatchedBy, entry.Score = &book.ID, models.MatchByUser, 1
+		case "create":
+			book, err := createReadingBook(tx, entry, job.UserID)
+			if err != nil {
+				return err
+			}
+			entry.BookID, entry.MatchedBy, entry.Score = &book.ID, models.MatchByCreated, 1
+			created = true
+		default:
+			return fmt.Errorf("unknown action: %s", action)
+		}
+		if err := applyReadingEntry(tx, job.UserID, entry); err != nil {
+			return err
+		}
+		entry.Status, entry.Errors = models.ReadingEntryImported, nil
+		return tx.Save(entry).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if entry.BookID != nil {
+		if created {
+			SyncSearchIndex(*entry.BookID)
+			SuggestBookRelated(db, *entry.BookID)
+			TriggerSavedSearches()
+		} else {
+			SuggestBook(db, *entry.BookID)
+		}
+	}
+	return refreshReadingImport(db, job)
+}
+
+// refreshReadingImport 按条目状态重新统计任务，没有等待确认的条目时任务完成
+func refreshReadingImport(db *gorm.DB, job *models.ReadingImport) error {
+	var counts []struct {
+		Status    string
+		MatchedBy string
+		Count     int
+	}
+	if err := db.Model(&models.ReadingImportEntry{}).Select("status, matched_by, COUNT(*) AS count").
+		Where("import_id = ?", job.ID).Group("status, matched_by").Scan(&counts).Error; err != nil {
+		return err
+	}
+	job.Imported, job.BooksCreated, job.Review, job.Unmatched, job.Skipped, job.Failed = 0, 0, 0, 0, 0, 0
+	for _, count := range counts {
+		switch count.Status {
+		case models.ReadingEntryImported:
+			job.Imported += count.Count
+			if count.MatchedBy == models.MatchByCreated {
+				job.BooksCreated += count.Count
+			}
+		case models.ReadingEntryReview:
+			job.Review += count.Count
+		case models.ReadingEntryUnmatched:
+			job.Unmatched += count.Count
+		case models.ReadingEntrySkipped:
+			job.Skipped += count.Count
+		case models.ReadingEntryError:
+			job.Failed += count.Count
+		}
+	}
+	if job.Status == models.ReadingImportReview && job.Review == 0 {
+		job.Status = models.ImportCompleted
+	}
+	return db.Model(job).Select("status", "imported", "books_created", "review", "unmatched", "skipped", "failed").Updates(job).Error
+}
+
+// finishReadingImport 保存任务的最终状态
+func finishReadingImport(db *gorm.DB, job *models.ReadingImport, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = models.ImportCompleted
+	if job.Review > 0 {
+		job.Status = models.ReadingImportReview
+	}
+	if err != nil {
+		job.Status, job.Error = models.ImportFailed, err.Error()
+	}
+	if err := db.Model(job).Select("status", "processed", "imported", "books_created", "review", "unmatched", "failed", "error", "finished_at").
+		Updates(job).Error; err != nil {
+		log.Printf("Failed to save reading import %d: %v", job.ID, err)
+	}
+}
+
+// FailInterruptedReadingImports 服务重启时，将上次未完成的阅读记录导入标记为失败
+func FailInterruptedReadingImports(db *gorm.DB) error {
+	return db.Model(&models.ReadingImport{}).Where("status IN ?", []string{models.ImportPending, models.ImportRunning}).
+		Updates(map[string]interface{}{"status": models.ImportFailed, "error": "interrupted by server restart", "finished_at": time.Now()}).Error
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// savedSearchTrigger 有新书时唤醒匹配任务，缓冲为 1，多次触发合并为一次
+var savedSearchTrigger = make(chan struct{}, 1)
+
+// digestBatchSize 每封摘要邮件最多列出的书籍数量
+const digestBatchSize = 50
+
+// CompileSavedSearch 编译保存的搜索，返回组合后的查询作用域；语句或筛选条件有误时返回错误
+func CompileSavedSearch(db *gorm.DB, search *models.SavedSearch) (func(*gorm.DB) *gorm.DB, error) {
+	values := url.Values{}
+	for key, value := range search.Filters {
+		values.Set(key, value)
+	}
+	filter, err := ParseBookFilter(values)
+	if err != nil {
+		return nil, err
+	}
+	var query *BookQuery
+	if strings.TrimSpace(search.Query) != "" {
+		if query, err = ParseBookQuery(db, search.Query); err != nil {
+			return nil, err
+		}
+	}
+	filterScope := filter.Scope(db)
+	return func(tx *gorm.DB) *gorm.DB {
+		if query != nil {
+			tx = query.Scope(tx)
+		}
+		return filterScope(tx)
+	}, nil
+}
+
+// LatestBookID 当前最大的书籍ID，新建保存的搜索时以此为起点，只通知之后的新书
+func LatestBookID(db *gorm.DB) uint {
+	var id uint
+	db.Unscoped().Model(&models.Book{}).Select("COALESCE(MAX(id), 0)").Scan(&id)
+	return id
+}
+
+// TriggerSavedSearches 通知后台任务尽快检查新书，不会阻塞
+func TriggerSavedSearches() {
+	select {
+	case savedSearchTrigger <- struct{}{}:
+	default:
+	}
+}
+
+// StartSavedSearchWorker 启动后台任务：定期（环境变量 SAVED_SEARCH_INTERVAL，默认 10 分钟）
+// 以及有新书时检查保存的搜索，并按 DIGEST_INTERVAL（默认 24 小时）发送邮件摘要
+func StartSavedSearchWorker() {
+	interval := durationFromEnv("SAVED_SEARCH_INTERVAL", 10*time.Minute)
+	digestInterval := durationFromEnv("DIGEST_INTERVAL", 24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		digestTicker := time.NewTicker(digestInterval)
+		defer ticker.Stop()
+		defer digestTicker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-savedSearchTrigger:
+			case <-digestTicker.C:
+				if err := SendNotificationDigests(config.DB); err != nil {
+					log.Printf("Failed to send notification digests: %v", err)
+				}
+				continue
+			}
+			if err := EvaluateSavedSearches(config.DB); err != nil {
+				log.Printf("Failed to evaluate saved searches: %v", err)
+			}
+		}
+	}()
+}
+
+// EvaluateSavedSearches 检查每个保存的搜索在上次检查之后新增的书籍，为匹配的书籍创建通知。
+// 用户自己上传的书籍不会通知
+func EvaluateSavedSearches(db *gorm.DB) error {
+	latest := LatestBookID(db)
+	var searches []models.SavedSearch
+	result := db.Where("last_book_id < ?", latest).FindInBatches(&searches, 100, func(_ *gorm.DB, _ int) error {
+		for i := range searches {
+			if err := evaluateSavedSearch(db, &searches[i], latest); err != nil {
+				log.Printf("Failed to evaluate saved search %d: %v", searches[i].ID, err)
+			}
+		}
+		return nil
+	})
+	return result.Error
+}
+
+func evaluateSavedSearch(db *gorm.DB, search *models.SavedSearch, latest uint) error {
+	scope, err := CompileSavedSearch(db, search)
+	if err != nil {
+		// 语句在保存时已校验，这里出错通常是引用的数据发生了变化，跳过这批书籍
+		return db.Model(search).Update("last_book_id", latest).Error
+	}
+
+	// 尚未发布的书籍跳过，审核通过时由 NotifyPublishedBook 补充通知；不公开的书籍不通知
+	var books []models.Book
+	if err := db.Model(&models.Book{}).Scopes(scope, PublicBooks).
+		Where("books.id > ? AND books.id <= ? AND books.user_id <> ?", search.LastBookID, latest, search.UserID).
+		Order("books.id").Find(&books).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i := range books {
+			if err := createSavedSearchNotification(tx, search, &books[i]); err != nil {
+				return err
+			}
+		}
+		return tx.Model(search).Update("last_book_id", latest).Error
+	})
+}
+
+// NotifyPublishedBook 书籍审核通过后，为已经检查过该书籍ID的保存的搜索补充匹配通知；
+// 尚未检查到该ID的保存的搜索由后台任务照常处理
+func NotifyPublishedBook(db *gorm.DB, book *models.Book) error {
+	var searches []models.SavedSearch
+	if err := db.Where("last_book_id >= ? AND user_id <> ?", book.ID, book.UserID).Find(&searches).Error; err != nil {
+		return err
+	}
+	for i := range searches {
+		scope, err := CompileSavedSearch(db, &searches[i])
+		if err != nil {
+			continue
+		}
+		var count int64
+		if err := db.Model(&models.Book{}).Scopes(scope, PublicBooks).Where("books.id = ?", book.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			continue
+		}
+		if err := createSavedSearchNotification(db, &searches[i], book); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSavedSearchNotification 创建保存的搜索的匹配通知，同一个搜索对同一本书只通知一次
+func createSavedSearchNotification(tx *gorm.DB, search *models.SavedSearch, book *models.Book) error {
+	notification := models.Notification{
+		UserID:        search.UserID,
+		Type:          models.NotificationSavedSearchMatch,
+		Title:         notificationTitle(fmt.Sprintf("New book matching \"%s\": %s", search.Name, book.Title)),
+		Message:       book.Author,
+		BookID:        &book.ID,
+		SavedSearchID: &search.ID,
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&notification).Error
+}
+
+// maxNotificationTitle 通知标题的最大字符数，与 models.Notification.Title 的列长度一致
+const maxNotificationTitle = 255
+
+// notificationTitle 按字符截断通知标题，标题中包含书名等用户填写的内容，可能超出列长度
+func notificationTitle(title string) string {
+	if runes := []rune(title); len(runes) > maxNotificationTitle {
+		return string(runes[:maxNotificationTitle-1]) + "…"
+	}
+	return title
+}
+
+// SendNotificationDigests 为开启了邮件摘要的保存的搜索，把尚未发送的匹配通知汇总成每个用户一封邮件
+func SendNotificationDigests(db *gorm.DB) error {
+	if Mail == nil {
+		return nil
+	}
+
+	var userIDs []uint
+	if err := db.Model(&models.Notification{}).
+		Joins("JOIN saved_searches ON saved_searches.id = notifications.saved_search_id AND saved_searches.deleted_at IS NULL").
+		Where("saved_searches.email_digest = ? AND notifications.emailed_at IS NULL AND notifications.read_at IS NULL", true).
+		Distinct().Pluck("notifications.user_id", &userIDs).Error; err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := sendUserDigest(db, userID); err != nil {
+			log.Printf("Failed to send digest to user %d: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+func sendUserDigest(db *gorm.DB, userID uint) error {
+	var user models.User
+	if err := db.First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	if err := db.Preload("Book").
+		Joins("JOIN saved_searches ON saved_searches.id = notifications.saved_search_id AND saved_searches.deleted_at IS NULL").
+		Where("notifications.user_id = ? AND saved_searches.email_digest = ? AND notifications.emailed_at IS NULL AND notifications.read_at IS NULL", userID, true).
+		Order("notifications.id").Limit(digestBatchSize).Find(&notifications).Error; err != nil {
+		return err
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hi %s,\n\nThese new books match your saved searches:\n\n", user.Username)
+	ids := make([]uint, len(notifications))
+	for i, notification := range notifications {
+		ids[i] = notification.ID
+		fmt.Fprintf(&body, "- %s\n", notification.Title)
+		if notification.Book != nil {
+			fmt.Fprintf(&body, "  /books/%d\n", notification.Book.ID)
+		}
+	}
+	subject := fmt.Sprintf("%d new books match your saved searches", len(notifications))
+	if err := Mail.Send(user.Email, subject, body.String()); err != nil {
+		return err
+	}
+	return db.Model(&models.Notification{}).Where("id IN ?", ids).Update("emailed_at", time.Now()).Error
+}
+
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	if value := os.Getenv(name); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid %s: %s, using %s", name, value, fallback)
+	}
+	return fallback
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/utils"
+	"errors"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 各字段在相关度中的权重
+const (
+	searchWeightTitle       = 3.0
+	searchWeightAuthors     = 2.0
+	searchWeightTags        = 1.5
+	searchWeightDescription = 1.0
+	searchWeightPinyin      = 2.5
+)
+
+// maxCorrections 每个拼写错误的单词最多扩展的候选词数量
+const maxCorrections = 3
+
+// minSearchRunes 与 MySQL 的 ngram_token_size 默认值一致，更短的搜索词无法使用全文索引
+const minSearchRunes = 2
+
+// snippetLength 描述摘要的最大长度（字符数）
+const snippetLength = 120
+
+// SearchResult 搜索结果，Highlights 中为带 <em> 标记的标题、作者和描述片段
+type SearchResult struct {
+	Book       models.Book       `json:"book"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// IndexBook 重建单本书籍的检索文档，书籍不存在（或已删除）或未发布时移除文档。
+// 不公开的书籍也建立文档，搜索时再按查看者的可见范围筛选
+func IndexBook(db *gorm.DB, bookID uint) error {
+	var book models.Book
+	err := db.Scopes(PublishedBooks).First(&book, bookID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return RemoveBookFromIndex(db, bookID)
+	}
+	if err != nil {
+		return err
+	}
+
+	// 作者字段包含署名、结构化作者名称及其别名，使 "Lu Xun" 也能搜到鲁迅的书
+	names := []string{book.Author}
+	var authorNames, aliasNames []string
+	db.Model(&models.Author{}).Joins("JOIN book_authors ON book_authors.author_id = authors.id").
+		Where("book_authors.book_id = ?", book.ID).Order("book_authors.position").Pluck("authors.name", &authorNames)
+	db.Model(&models.AuthorAlias{}).Joins("JOIN book_authors ON book_authors.author_id = author_aliases.author_id").
+		Where("book_authors.book_id = ?", book.ID).Pluck("author_aliases.name", &aliasNames)
+	names = uniqueStrings(append(append(names, authorNames...), aliasNames...))
+
+	var tags []models.Tag
+	db.Joins("JOIN book_tags ON book_tags.tag_id = tags.id").Where("book_tags.book_id = ?", book.ID).Find(&tags)
+	tagNames := make([]string, 0, len(tags)*2)
+	for _, tag := range tags {
+		tagNames = append(tagNames, tag.DisplayName)
+		if tag.Name != strings.ToLower(tag.DisplayName) {
+			tagNames = append(tagNames, tag.Name)
+		}
+	}
+
+	// 书名和作者名的拼音（全拼和首字母），用于 "santi"、"st" 这样的拼音搜索
+	pinyinForms := utils.PinyinForms(utils.ToSimplified(book.Title))
+	for _, name := range names {
+		pinyinForms = append(pinyinForms, utils.PinyinForms(utils.ToSimplified(name))...)
+	}
+	pinyinForms = uniqueStrings(pinyinForms)
+
+	// 检索文档统一使用简体，使 "三體" 与 "三体" 互相匹配
+	authors := strings.Join(names, " ")
+	doc := models.BookSearchDoc{
+		BookID:      book.ID,
+		Title:       utils.ToSimplified(book.Title),
+		Authors:     utils.ToSimplified(authors),
+		Description: utils.ToSimplified(book.Description),
+		Tags:        utils.ToSimplified(strings.Join(tagNames, " ")),
+		Pinyin:      strings.Join(pinyinForms, " "),
+	}
+	if err := db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&doc).Error; err != nil {
+		return err
+	}
+	return addSearchTerms(db, append(utils.LatinWords(book.Title+" "+authors), pinyinForms...))
+}
+
+// addSearchTerms 将可用于拼写纠错的单词加入词表，太短的单词不做纠错因此不收录
+func addSearchTerms(db *gorm.DB, words []string) error {
+	terms := make([]models.SearchTerm, 0, len(words))
+	for _, word := range uniqueStrings(words) {
+		if utils.MaxTypos(word) > 0 && len(word) <= 64 {
+			terms = append(terms, models.SearchTerm{Term: word})
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&terms).Error
+}
+
+// RemoveBookFromIndex 移除书籍的检索文档
+func RemoveBookFromIndex(db *gorm.DB, bookID uint) error {
+	return db.Where("book_id = ?", bookID).Delete(&models.BookSearchDoc{}).Error
+}
+
+// SyncSearchIndex 在书籍变更提交后更新检索文档，失败只记录日志，不影响请求结果
+func SyncSearchIndex(bookIDs ...uint) {
+	for _, id := range bookIDs {
+		if err := IndexBook(config.DB, id); err != nil {
+			log.Printf("Failed to update search index for book %d: %v", id, err)
+		}
+	}
+	if len(bookIDs) > 0 {
+		InvalidateSearchFacets()
+	}
+}
+
+// SyncAuthorBooksIndex 作者名称或别名变化后更新其全部书籍的检索文档
+func SyncAuthorBooksIndex(authorID uint) {
+	var ids []uint
+	config.DB.Model(&models.BookAuthor{}).Where("author_id = ?", authorID).Distinct().Pluck("book_id", &ids)
+	SyncSearchIndex(ids...)
+}
+
+// SyncTagBooksIndex 标签合并后更新其全部书籍的检索文档
+func SyncTagBooksIndex(tagID uint) {
+	var ids []uint
+	config.DB.Model(&models.BookTag{}).Where("tag_id = ?", tagID).Pluck("book_id", &ids)
+	SyncSearchIndex(ids...)
+}
+
+// IndexMissingBooks 为尚无检索文档的书籍建立索引，返回新建的文档数量。
+// 在加入拼音字段之前建立的文档（pinyin 为 NULL）也会重建
+func IndexMissingBooks(db *gorm.DB) (int, error) {
+	var books []models.Book
+	indexed := 0
+	result := db.Model(&models.Book{}).Select("id").Scopes(PublishedBooks).
+		Where("id NOT IN (?)", db.Model(&models.BookSearchDoc{}).Select("book_id").Where("pinyin IS NOT NULL")).
+		FindInBatches(&books, 200, func(_ *gorm.DB, _ int) error {
+			for _, book := range books {
+				if err := IndexBook(db, book.ID); err != nil {
+					return err
+				}
+				indexed++
+			}
+			return nil
+		})
+	return indexed, result.Error
+}
+
+// RebuildSearchIndex 清空并重建全部书籍的检索文档
+func RebuildSearchIndex(db *gorm.DB) (int, error) {
+	if err := db.Where("1 = 1").Delete(&models.BookSearchDoc{}).Error; err != nil {
+		return 0, err
+	}
+	if err := db.Where("1 = 1").Delete(&models.SearchTerm{}).Error; err != nil {
+		return 0, err
+	}
+	return IndexMissingBooks(db)
+}
+
+// SearchQuery 解析后的搜索词：统一为简体，拼写错误的拉丁单词扩展为词表中相近的词，
+// 只包含字母的搜索词同时按拼音匹配
+type SearchQuery struct {
+	Raw    string
+	Terms  []string // 用于高亮的词项
+	text   string   // 全文检索使用的文本
+	pinyin string   // 拼音字段的 BOOLEAN MODE 检索表达式，为空表示不按拼音匹配
+}
+
+// NewSearchQuery 解析搜索词
+func NewSearchQuery(db *gorm.DB, keyword string) *SearchQuery {
+	q := &SearchQuery{Raw: strings.TrimSpace(keyword)}
+	if q.fallback() {
+		q.Terms = utils.SearchTerms(q.Raw)
+		return q
+	}
+
+	simplified := utils.ToSimplified(q.Raw)
+	words := utils.SearchTerms(simplified)
+	var pinyin string
+	if utils.IsPinyinQuery(q.Raw) {
+		// "san ti" 和 "santi" 都按连写的拼音匹配
+		pinyin = strings.ToLower(strings.Join(strings.Fields(q.Raw), ""))
+		words = append(words, pinyin)
+	}
+	corrections := correctSpelling(db, words)
+
+	q.text = strings.Join(append([]string{simplified}, corrections...), " ")
+	if utf8.RuneCountInString(pinyin) >= minSearchRunes {
+		phrases := []string{`"` + pinyin + `"`}
+		for _, correction := range corrections {
+			phrases = append(phrases, `"`+correction+`"`)
+		}
+		q.pinyin = strings.Join(phrases, " ")
+	}
+
+	terms := append(utils.SearchTerms(q.Raw), words...)
+	terms = append(terms, utils.SearchTerms(utils.ToTraditional(q.Raw))...)
+	q.Terms = uniqueStrings(append(terms, corrections...))
+	return q
+}
+
+// fallback 搜索词过短时无法使用 ngram 全文索引，退回到模糊匹配
+func (q *SearchQuery) fallback() bool {
+	return utf8.RuneCountInString(q.Raw) < minSearchRunes
+}
+
+// Scope 按搜索词筛选书籍
+func (q *SearchQuery) Scope(query *gorm.DB) *gorm.DB {
+	if q.fallback() {
+		search := "%" + q.Raw + "%"
+		return query.Where("books.title LIKE ? OR books.author LIKE ?", search, search)
+	}
+	query = query.Joins("JOIN book_search_docs ON book_search_docs.book_id = books.id")
+	match := "MATCH(book_search_docs.title, book_search_docs.authors, book_search_docs.description, book_search_docs.tags) AGAINST(?)"
+	if q.pinyin == "" {
+		return query.Where(match, q.text)
+	}
+	return query.Where(match+" OR MATCH(book_search_docs.pinyin) AGAINST(? IN BOOLEAN MODE)", q.text, q.pinyin)
+}
+
+// condition 返回按搜索词筛选书籍的条件，不依赖与检索文档的连接，可用于组合条件
+func (q *SearchQuery) condition(db *gorm.DB) clause.Expr {
+	if q.fallback() {
+		search := "%" + q.Raw + "%"
+		return clause.Expr{SQL: "books.title LIKE ? OR books.author LIKE ?", Vars: []interface{}{search, search}}
+	}
+	docs := db.Model(&models.BookSearchDoc{}).Select("book_id")
+	match := "MATCH(book_search_docs.title, book_search_docs.authors, book_search_docs.description, book_search_docs.tags) AGAINST(?)"
+	if q.pinyin == "" {
+		docs = docs.Where(match, q.text)
+	} else {
+		docs = docs.Where(match+" OR MATCH(book_search_docs.pinyin) AGAINST(? IN BOOLEAN MODE)", q.text, q.pinyin)
+	}
+	return clause.Expr{SQL: "books.id IN (?)", Vars: []interface{}{docs}}
+}
+
+// relevance 按字段加权的相关度表达式，需要与 Scope 的全文检索一起使用
+func (q *SearchQuery) relevance() clause.Expr {
+	expr := clause.Expr{
+		SQL: "(? * MATCH(book_search_docs.title) AGAINST(?) + ? * MATCH(book_search_docs.authors) AGAINST(?)" +
+			" + ? * MATCH(book_search_docs.tags) AGAINST(?) + ? * MATCH(book_search_docs.description) AGAINST(?)",
+		Vars: []interface{}{
+			searchWeightTitle, q.text,
+			searchWeightAuthors, q.text,
+			searchWeightTags, q.text,
+			searchWeightDescription, q.text,
+		},
+	}
+	if q.pinyin != "" {
+		expr.SQL += " + ? * MATCH(book_search_docs.pinyin) AGAINST(? IN BOOLEAN MODE)"
+		expr.Vars = append(expr.Vars, searchWeightPinyin, q.pinyin)
+	}
+	expr.SQL += ")"
+	return expr
+}
+
+// OrderByRelevance 按相关度从高到低排序，相关度相同时较新的书籍在前
+func (q *SearchQuery) OrderByRelevance(query *gorm.DB) *gorm.DB {
+	if q.fallback() {
+		return query.Order("books.id DESC")
+	}
+	// 表达式排序与普通列排序合并时会丢失表达式，因此把 ID 排序写在同一个表达式中
+	expr := q.relevance()
+	expr.SQL += " DESC, books.id DESC"
+	return query.Order(clause.OrderBy{Expression: expr})
+}
+
+// correctSpelling 对不在词表中的拉丁单词，从词表中找出编辑距离最小的候选词。
+// 候选词按首字母和长度范围查询，首字母拼错的单词不做纠错
+func correctSpelling(db *gorm.DB, words []string) []string {
+	var candidates []string
+	for _, word := range words {
+		if utils.MaxTypos(word) > 0 && utils.IsPinyinQuery(word) {
+			candidates = append(candidates, word)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	var known []string
+	db.Model(&models.SearchTerm{}).Where("term IN ?", candidates).Pluck("term", &known)
+	knownSet := make(map[string]bool, len(known))
+	for _, term := range known {
+		knownSet[term] = true
+	}
+
+	var corrections []string
+	for _, word := range candidates {
+		if knownSet[word] {
+			continue
+		}
+		maxTypos := utils.MaxTypos(word)
+		length := utf8.RuneCountInString(word)
+		var terms []string
+		db.Model(&models.SearchTerm{}).
+			Where("term LIKE ? AND CHAR_LENGTH(term) BETWEEN ? AND ?", word[:1]+"%", length-maxTypos, length+maxTypos).
+			Limit(500).Pluck("term", &terms)
+
+		best := maxTypos + 1
+		var matches []string
+		for _, term := range terms {
+			distance := utils.EditDistance(word, term)
+			if distance < best {
+				best, matches = distance, nil
+			}
+			if distance == best && len(matches) < maxCorrections {
+				matches = append(matches, term)
+			}
+		}
+		if best <= maxTypos {
+			corrections = append(corrections, matches...)
+		}
+	}
+	return corrections
+}
+
+// SearchBooks 按高级搜索语句搜索 viewerID 可以看到的书籍，返回按相关度排序的一页结果及匹配总数；语句有误时返回 *utils.QueryError
+func SearchBooks(db *gorm.DB, keyword string, filter BookFilter, viewerID uint, page, pageSize int) ([]SearchResult, int64, error) {
+	search, err := ParseBookQuery(db, keyword)
+	if err != nil {
+		return nil, 0, err
+	}
+	base := db.Model(&models.Book{}).Scopes(VisibleBooks(db, viewerID), search.Scope, filter.Scope(db))
+
+	var total int64
+	if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		ID    uint
+		Score float64
+	}
+	query := base.Session(&gorm.Session{})
+	if err := query.Scopes(search.OrderByRelevance).Select("books.id, ? AS score", search.score()).Limit(pageSize).Offset((page - 1) * pageSize).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return []SearchResult{}, total, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var books []models.Book
+	if err := db.Preload("User").Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	var docs []models.BookSearchDoc
+	db.Where("book_id IN ?", ids).Find(&docs)
+
+	booksByID := make(map[uint]models.Book, len(books))
+	for _, book := range books {
+		booksByID[book.ID] = book
+	}
+	docsByID := make(map[uint]models.BookSearchDoc, len(docs))
+	for _, doc := range docs {
+		docsByID[doc.BookID] = doc
+	}
+
+	terms := search.Terms
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		book, ok := booksByID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, SearchResult{
+			Book:       book,
+			Score:      row.Score,
+			Highlights: searchHighlights(book, docsByID[book.ID], terms),
+		})
+	}
+	return results, total, nil
+}
+
+// searchHighlights 生成标题、作者、标签和描述的高亮片段，没有命中的字段不返回
+func searchHighlights(book models.Book, doc models.BookSearchDoc, terms []string) map[string]string {
+	highlights := make(map[string]string)
+	// 原文中没有命中时（如通过作者别名或简繁转换命中），使用检索文档中的文本
+	fields := []struct {
+		name     string
+		texts    []string
+		maxRunes int
+	}{
+		{"title", []string{book.Title, doc.Title}, 0},
+		{"author", []string{book.Author, doc.Authors}, 0},
+		{"tags", []string{doc.Tags}, 0},
+		{"description", []string{book.Description, doc.Description}, snippetLength},
+	}
+	for _, field := range fields {
+		for _, text := range field.texts {
+			if highlighted, ok := utils.Highlight(text, terms, field.maxRunes); ok {
+				highlights[field.name] = highlighted
+				break
+			}
+		}
+	}
+	return highlights
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
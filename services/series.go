@@ -0,0 +1,95 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SeriesSuggestion "继续阅读系列" 的推荐项
+type SeriesSuggestion struct {
+	Series   models.Series `json:"series"`
+	LastRead models.Book   `json:"last_read"` // 用户读过的最后一卷
+	Next     models.Book   `json:"next"`      // 建议接着读的一卷
+}
+
+// SeriesNeighbors 返回书籍在系列中公开的上一卷和下一卷，书籍详情会被所有人共用的缓存保存
+func SeriesNeighbors(db *gorm.DB, book *models.Book) (prev, next *models.Book) {
+	if book.SeriesID == nil || book.SeriesIndex == nil {
+		return nil, nil
+	}
+
+	var before, after models.Book
+	if db.Scopes(PublicBooks).Where("series_id = ? AND series_index < ?", *book.SeriesID, *book.SeriesIndex).
+		Order("series_index desc, id desc").First(&before).Error == nil {
+		prev = &before
+	}
+	if db.Scopes(PublicBooks).Where("series_id = ? AND series_index > ?", *book.SeriesID, *book.SeriesIndex).
+		Order("series_index, id").First(&after).Error == nil {
+		next = &after
+	}
+	return prev, next
+}
+
+// InvalidateSeriesBooks 系列的卷发生变化时，清除该系列所有书籍的详情缓存（其中包含上一卷/下一卷）
+func InvalidateSeriesBooks(seriesID *uint) {
+	if seriesID == nil {
+		return
+	}
+	var ids []uint
+	config.DB.Model(&models.Book{}).Where("series_id = ?", *seriesID).Pluck("id", &ids)
+	for _, id := range ids {
+		config.RDB.Del(config.Ctx, fmt.Sprintf("book:%d", id))
+	}
+}
+
+// ContinueSeries 根据用户的 "read" 记录，为读过部分卷的系列推荐下一本未读的卷
+func ContinueSeries(db *gorm.DB, userID uint) ([]SeriesSuggestion, error) {
+	var readBooks []models.Book
+	err := db.Joins("JOIN user_book_relations r ON r.book_id = books.id AND r.deleted_at IS NULL").
+		Where("r.user_id = ? AND r.relation_type = ? AND books.series_id IS NOT NULL AND books.series_index IS NOT NULL", userID, "read").
+		Order("books.series_index").
+		Find(&readBooks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 每个系列中读过的最后一卷，以及全部已读的书籍
+	lastRead := make(map[uint]models.Book)
+	var order []uint
+	readIDs := make(map[uint]bool)
+	for _, book := range readBooks {
+		readIDs[book.ID] = true
+		last, ok := lastRead[*book.SeriesID]
+		if !ok {
+			order = append(order, *book.SeriesID)
+		}
+		if !ok || *book.SeriesIndex >= *last.SeriesIndex {
+			lastRead[*book.SeriesID] = book
+		}
+	}
+
+	suggestions := make([]SeriesSuggestion, 0)
+	for _, seriesID := range order {
+		last := lastRead[seriesID]
+		var candidates []models.Book
+		if err := db.Scopes(VisibleBooks(db, userID)).Where("series_id = ? AND series_index > ?", seriesID, *last.SeriesIndex).
+			Order("series_index, id").Find(&candidates).Error; err != nil {
+			return nil, err
+		}
+		for _, candidate := range candidates {
+			if readIDs[candidate.ID] {
+				continue
+			}
+			var series models.Series
+			if err := db.First(&series, seriesID).Error; err != nil {
+				break
+			}
+			suggestions = append(suggestions, SeriesSuggestion{Series: series, LastRead: last, Next: candidate})
+			break
+		}
+	}
+	return suggestions, nil
+}
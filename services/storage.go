@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlobPath 返回内容哈希对应的存储路径，按哈希前缀分两级目录，避免单目录文件过多
+func BlobPath(hash string) string {
+	return filepath.Join(config.UploadDir, "blobs", hash[:2], hash[2:4], hash)
+}
+
+// SaveBlob 将上传内容按 SHA-256 内容寻址存储。
+// 相同内容只在磁盘上保留一份：已存在时仅把引用计数加一，否则写入新文件并创建记录。
+// 需要在事务中调用，以便与书籍文件记录一起提交或回滚；事务失败时调用方应对返回的哈希调用 RemoveBlobFiles，
+// 清理新写入的文件。
+func SaveBlob(tx *gorm.DB, r io.Reader, mimeType string) (*models.FileBlob, error) {
+	// 1. 边写临时文件边计算哈希
+	tmp, err := os.CreateTemp(filepath.Join(config.UploadDir, "tmp"), "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name()) // 已被移动到 blobs 目录时删除会失败，忽略即可
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hasher), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	hash := hex.EncodeToString(hasher.Sum(nil))
+
+	// 2. 插入 Blob 记录，哈希冲突时引用计数加一。先锁定记录再处理文件：
+	// RemoveBlobFiles 需要对同一哈希加锁后才删除文件，记录存在期间文件不会被删除
+	blob := models.FileBlob{Hash: hash, Size: size, MimeType: mimeType, RefCount: 1}
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"ref_count": gorm.Expr("ref_count + 1")}),
+	}).Create(&blob).Error; err != nil {
+		return nil, err
+	}
+
+	// ON DUPLICATE KEY UPDATE 时返回的自增ID不可靠，重新查询一次
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("hash = ?", hash).First(&blob).Error; err != nil {
+		return nil, err
+	}
+
+	// 3. 内容尚未落盘时移动到最终位置；内容相同，重复移动也不会产生问题
+	path := BlobPath(hash)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp.Name(), path); err != nil {
+			return nil, err
+		}
+	}
+	return &blob, nil
+}
+
+// ReleaseBlob 将 Blob 的引用计数减一。
+// 计数归零时删除记录，并返回需要在事务提交后清理的哈希。
+func ReleaseBlob(tx *gorm.DB, blobID uint) (string, error) {
+	if err := tx.Model(&models.FileBlob{}).Where("id = ? AND ref_count > 0", blobID).
+		Update("ref_count", gorm.Expr("ref_count - 1")).Error; err != nil {
+		return "", err
+	}
+
+	var blob models.FileBlob
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&blob, blobID).Error; err != nil {
+		return "", err
+	}
+	if blob.RefCount > 0 {
+		return "", nil
+	}
+	if err := tx.Delete(&blob).Error; err != nil {
+		return "", err
+	}
+	return blob.Hash, nil
+}
+
+// RemoveBlobFiles 删除已无引用的 Blob 文件，应在事务提交（或 SaveBlob 所在的事务失败）后调用。
+// 删除前在事务中锁定该哈希并再次确认没有记录：并发的上传要等删除完成后才能插入记录，
+// 之后发现文件不存在会重新写入；已插入记录的上传则使这里跳过删除
+func RemoveBlobFiles(hashes []string) {
+	for _, hash := range hashes {
+		if hash == "" {
+			continue
+		}
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var blobs []models.FileBlob
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("hash = ?", hash).Limit(1).Find(&blobs).Error; err != nil {
+				return err
+			}
+			if len(blobs) > 0 {
+				return nil
+			}
+			if err := os.Remove(BlobPath(hash)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to remove blob file %s: %v", hash, err)
+		}
+	}
+}
+
+// ReleaseBookFiles 彻底删除书籍的全部附件，并释放仍持有 Blob 引用的附件（未删除的，或随书籍一起移入回收站的），
+// 返回需要清理的哈希。单独删除的附件在删除时已释放引用
+func ReleaseBookFiles(tx *gorm.DB, book *models.Book) ([]string, error) {
+	var files []models.BookFile
+	if err := tx.Unscoped().Where("book_id = ?", book.ID).Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	var orphaned []string
+	for _, file := range files {
+		if file.DeletedAt.Valid && !(book.DeletedAt.Valid && file.DeletedAt.Time.Equal(book.DeletedAt.Time)) {
+			continue
+		}
+		hash, err := ReleaseBlob(tx, file.BlobID)
+		if err != nil {
+			return nil, err
+		}
+		if hash != "" {
+			orphaned = append(orphaned, hash)
+		}
+	}
+	if err := tx.Unscoped().Where("book_id = ?", book.ID).Delete(&models.BookFile{}).Error; err != nil {
+		return nil, err
+	}
+	return orphaned, nil
+}
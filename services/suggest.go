@@ -0,0 +1,265 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/utils"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm"
+)
+
+// 自动补全使用的 Redis 键：
+//   - suggest:prefix:<前缀> 有序集合，成员为 "<类型>:<ID>"，分值为热度。条目可命中的每段文本的每个前缀各有一个集合，
+//     查询时直接按分值倒序取前几项，不需要扫描前缀范围内的全部条目
+//   - suggest:labels 哈希，保存每个条目的显示文本
+//   - suggest:members:<类型>:<ID> 集合，记录条目所在的全部前缀集合，便于改名或删除时清理
+const (
+	suggestPrefixFmt  = "suggest:prefix:%s"
+	suggestLabelsKey  = "suggest:labels"
+	suggestMembersFmt = "suggest:members:%s"
+)
+
+// 补全条目类型
+const (
+	SuggestTypeTitle  = "title"
+	SuggestTypeAuthor = "author"
+	SuggestTypeTag    = "tag"
+)
+
+// suggestMaxPrefix 建立前缀集合的最大字符数，更长的输入按前 suggestMaxPrefix 个字符查询
+const suggestMaxPrefix = 20
+
+// Suggestion 自动补全的一项
+type Suggestion struct {
+	Type   string  `json:"type"`
+	ID     uint    `json:"id"`
+	Text   string  `json:"text"`
+	Weight float64 `json:"weight"`
+}
+
+// Suggest 返回以 prefix 开头的书名、作者和标签，按热度排序
+func Suggest(prefix string, limit int) ([]Suggestion, error) {
+	prefix = normalizeSuggestText(prefix)
+	suggestions := make([]Suggestion, 0, limit)
+	if prefix == "" || limit <= 0 {
+		return suggestions, nil
+	}
+	if runes := []rune(prefix); len(runes) > suggestMaxPrefix {
+		prefix = string(runes[:suggestMaxPrefix])
+	}
+
+	entries, err := config.RDB.ZRevRangeWithScores(config.Ctx, fmt.Sprintf(suggestPrefixFmt, prefix), 0, int64(limit)-1).Result()
+	if err != nil || len(entries) == 0 {
+		return suggestions, err
+	}
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i], _ = entry.Member.(string)
+	}
+	labels, err := config.RDB.HMGet(config.Ctx, suggestLabelsKey, ids...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		label, ok := labels[i].(string)
+		if !ok {
+			continue
+		}
+		entryType, idText, _ := strings.Cut(ids[i], ":")
+		id, _ := strconv.ParseUint(idText, 10, 64)
+		suggestions = append(suggestions, Suggestion{Type: entryType, ID: uint(id), Text: label, Weight: entry.Score})
+	}
+	return suggestions, nil
+}
+
+// SuggestBook 更新书名的补全条目，书籍已删除、未发布或不公开时移除条目。热度为收藏、阅读等用户关系的数量
+func SuggestBook(db *gorm.DB, bookID uint) {
+	var book models.Book
+	if err := db.Scopes(PublicBooks).First(&book, bookID).Error; err != nil {
+		RemoveSuggestion(SuggestTypeTitle, bookID)
+		return
+	}
+	var relations int64
+	db.Model(&models.UserBookRelation{}).Where("book_id = ?", bookID).Count(&relations)
+	setSuggestion(SuggestTypeTitle, book.ID, book.Title, float64(relations)+1)
+}
+
+// SuggestAuthor 更新作者的补全条目（包括别名），热度为作者参与的公开书籍数量
+func SuggestAuthor(db *gorm.DB, authorID uint) {
+	var author models.Author
+	if err := db.Preload("AlternateNames").First(&author, authorID).Error; err != nil {
+		RemoveSuggestion(SuggestTypeAuthor, authorID)
+		return
+	}
+	var books int64
+	db.Model(&models.BookAuthor{}).Joins("JOIN books ON books.id = book_authors.book_id AND books.deleted_at IS NULL").
+		Scopes(PublicBooks).Where("book_authors.author_id = ?", authorID).Distinct("book_authors.book_id").Count(&books)
+	aliases := make([]string, 0, len(author.AlternateNames))
+	for _, alias := range author.AlternateNames {
+		aliases = append(aliases, alias.Name)
+	}
+	setSuggestion(SuggestTypeAuthor, author.ID, author.Name, float64(books), aliases...)
+}
+
+// SuggestTag 更新标签的补全条目，同义词作为该标签的别名补全。热度为使用该标签的公开书籍数量
+func SuggestTag(db *gorm.DB, tagID uint) {
+	var tag models.Tag
+	if err := db.First(&tag, tagID).Error; err != nil || tag.CanonicalID != nil {
+		RemoveSuggestion(SuggestTypeTag, tagID)
+		return
+	}
+	var books int64
+	db.Model(&models.BookTag{}).Joins("JOIN books ON books.id = book_tags.book_id AND books.deleted_at IS NULL").
+		Scopes(PublicBooks).Where("book_tags.tag_id = ?", tagID).Count(&books)
+	var synonyms []string
+	db.Model(&models.Tag{}).Where("canonical_id = ?", tagID).Pluck("display_name", &synonyms)
+	setSuggestion(SuggestTypeTag, tag.ID, tag.DisplayName, float64(books), append(synonyms, tag.Name)...)
+}
+
+// SuggestBookRelated 书籍变更后更新书名及其作者、标签的补全条目
+func SuggestBookRelated(db *gorm.DB, bookID uint) {
+	SuggestBook(db, bookID)
+	var authorIDs, tagIDs []uint
+	db.Model(&models.BookAuthor{}).Where("book_id = ?", bookID).Distinct().Pluck("author_id", &authorIDs)
+	db.Model(&models.BookTag{}).Where("book_id = ?", bookID).Pluck("tag_id", &tagIDs)
+	for _, id := range authorIDs {
+		SuggestAuthor(db, id)
+	}
+	for _, id := range tagIDs {
+		SuggestTag(db, id)
+	}
+}
+
+// RemoveSuggestion 删除补全条目
+func RemoveSuggestion(entryType string, id uint) {
+	entry := fmt.Sprintf("%s:%d", entryType, id)
+	membersKey := fmt.Sprintf(suggestMembersFmt, entry)
+	prefixKeys, _ := config.RDB.SMembers(config.Ctx, membersKey).Result()
+
+	pipe := config.RDB.TxPipeline()
+	for _, key := range prefixKeys {
+		pipe.ZRem(config.Ctx, key, entry)
+	}
+	pipe.Del(config.Ctx, membersKey)
+	pipe.HDel(config.Ctx, suggestLabelsKey, entry)
+	pipe.Exec(config.Ctx)
+}
+
+// RebuildSuggestions 根据数据库重建全部补全条目
+func RebuildSuggestions(db *gorm.DB) error {
+	var keys []string
+	iter := config.RDB.Scan(config.Ctx, 0, "suggest:*", 500).Iterator()
+	for iter.Next(config.Ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) > 0 {
+		if err := config.RDB.Del(config.Ctx, keys...).Err(); err != nil {
+			return err
+		}
+	}
+
+	var bookIDs, authorIDs, tagIDs []uint
+	if err := db.Model(&models.Book{}).Pluck("id", &bookIDs).Error; err != nil {
+		return err
+	}
+	db.Model(&models.Author{}).Pluck("id", &authorIDs)
+	db.Model(&models.Tag{}).Where("canonical_id IS NULL").Pluck("id", &tagIDs)
+	for _, id := range bookIDs {
+		SuggestBook(db, id)
+	}
+	for _, id := range authorIDs {
+		SuggestAuthor(db, id)
+	}
+	for _, id := range tagIDs {
+		SuggestTag(db, id)
+	}
+	return nil
+}
+
+// EnsureSuggestions 补全索引为空时（如首次部署或 Redis 数据丢失）重建索引
+func EnsureSuggestions(db *gorm.DB) error {
+	count, err := config.RDB.HLen(config.Ctx, suggestLabelsKey).Result()
+	if err != nil || count > 0 {
+		return err
+	}
+	return RebuildSuggestions(db)
+}
+
+// setSuggestion 写入补全条目，替换该条目之前所在的全部前缀集合
+func setSuggestion(entryType string, id uint, label string, weight float64, aliases ...string) {
+	if strings.TrimSpace(label) == "" {
+		RemoveSuggestion(entryType, id)
+		return
+	}
+	entry := fmt.Sprintf("%s:%d", entryType, id)
+	membersKey := fmt.Sprintf(suggestMembersFmt, entry)
+	oldKeys, _ := config.RDB.SMembers(config.Ctx, membersKey).Result()
+
+	var prefixKeys []string
+	for _, text := range suggestPrefixes(append([]string{label}, aliases...)) {
+		runes := []rune(text)
+		for n := 1; n <= len(runes) && n <= suggestMaxPrefix; n++ {
+			// 输入会去掉首尾空白，以空格结尾的前缀不会被查询
+			if runes[n-1] != ' ' {
+				prefixKeys = append(prefixKeys, fmt.Sprintf(suggestPrefixFmt, string(runes[:n])))
+			}
+		}
+	}
+	prefixKeys = uniqueStrings(prefixKeys)
+
+	pipe := config.RDB.TxPipeline()
+	for _, key := range oldKeys {
+		pipe.ZRem(config.Ctx, key, entry)
+	}
+	pipe.Del(config.Ctx, membersKey)
+	for _, key := range prefixKeys {
+		pipe.ZAdd(config.Ctx, key, &redis.Z{Score: weight, Member: entry})
+	}
+	if len(prefixKeys) > 0 {
+		pipe.SAdd(config.Ctx, membersKey, toInterfaces(prefixKeys)...)
+	}
+	pipe.HSet(config.Ctx, suggestLabelsKey, entry, label)
+	pipe.Exec(config.Ctx)
+}
+
+// suggestPrefixes 生成可以命中条目的文本：规范化的原文、拼音全拼和首字母，
+// 以及多个单词的英文标题从每个单词开始的后缀（输入 "potter" 也能补全 "Harry Potter"）
+func suggestPrefixes(texts []string) []string {
+	var prefixes []string
+	for _, text := range texts {
+		normalized := normalizeSuggestText(text)
+		if normalized == "" {
+			continue
+		}
+		prefixes = append(prefixes, normalized)
+		prefixes = append(prefixes, utils.PinyinForms(utils.ToSimplified(text))...)
+
+		words := strings.Fields(normalized)
+		for i := 1; i < len(words) && i <= 5; i++ {
+			if len(words[i]) >= 3 {
+				prefixes = append(prefixes, strings.Join(words[i:], " "))
+			}
+		}
+	}
+	return uniqueStrings(prefixes)
+}
+
+// normalizeSuggestText 转为简体、小写并合并空白
+func normalizeSuggestText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(utils.ToSimplified(s)), " "))
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
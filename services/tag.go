@@ -0,0 +1,214 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"bookshare/utils"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const tagCloudCacheKey = "tag_cloud"
+
+// tagCloudSize 缓存的标签云大小，请求的数量超过时按该值截断
+const tagCloudSize = 200
+
+var ErrInvalidTag = errors.New("tag name is empty after normalization")
+
+// TagCloudItem 标签云中的一项，Weight 为 1~5 的权重
+type TagCloudItem struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	Count       int64  `json:"count"`
+	Weight      int    `json:"weight"`
+}
+
+// FindTag 按规范化名称查找标签，同义词返回其规范标签
+func FindTag(db *gorm.DB, raw string) (*models.Tag, error) {
+	name := utils.NormalizeTag(raw)
+	if name == "" {
+		return nil, ErrInvalidTag
+	}
+	var tag models.Tag
+	if err := db.Where("name = ?", name).First(&tag).Error; err != nil {
+		return nil, err
+	}
+	return canonicalTag(db, &tag)
+}
+
+// ResolveTag 查找标签，不存在时创建
+func ResolveTag(db *gorm.DB, raw string) (*models.Tag, error) {
+	tag, err := FindTag(db, raw)
+	if err == nil || !errors.Is(err, gorm.ErrRecordNotFound) {
+		return tag, err
+	}
+	tag = &models.Tag{Name: utils.NormalizeTag(raw), DisplayName: utils.TagDisplayName(raw)}
+	if err := db.Create(tag).Error; err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+// canonicalTag 沿同义词链找到规范标签
+func canonicalTag(db *gorm.DB, tag *models.Tag) (*models.Tag, error) {
+	for depth := 0; tag.CanonicalID != nil && depth < 10; depth++ {
+		var canonical models.Tag
+		if err := db.First(&canonical, *tag.CanonicalID).Error; err != nil {
+			return nil, err
+		}
+		tag = &canonical
+	}
+	return tag, nil
+}
+
+// AddBookTags 为书籍添加标签，已存在的标签忽略，同时返回新添加的标签数量
+func AddBookTags(tx *gorm.DB, bookID, userID uint, names []string) ([]models.Tag, int, error) {
+	tags := make([]models.Tag, 0, len(names))
+	added := 0
+	for _, name := range names {
+		tag, err := ResolveTag(tx, name)
+		if err != nil {
+			return nil, added, err
+		}
+		var count int64
+		tx.Model(&models.BookTag{}).Where("book_id = ? AND tag_id = ?", bookID, tag.ID).Count(&count)
+		if count == 0 {
+			if err := tx.Create(&models.BookTag{BookID: bookID, TagID: tag.ID, UserID: userID}).Error; err != nil {
+				return nil, added, err
+			}
+			added++
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, added, nil
+}
+
+// MergeTags 将 source 合并到 target：书籍关联转移到 target，source 及其同义词都成为 target 的同义词
+func MergeTags(tx *gorm.DB, source, target *models.Tag) error {
+	var links []models.BookTag
+	if err := tx.Where("tag_id = ?", source.ID).Find(&links).Error; err != nil {
+		return err
+	}
+	for _, link := range links {
+		var count int64
+		tx.Model(&models.BookTag{}).Where("book_id = ? AND tag_id = ?", link.BookID, target.ID).Count(&count)
+		if count > 0 {
+			if err := tx.Delete(&link).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Model(&link).Update("tag_id", target.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.Tag{}).Where("canonical_id = ?", source.ID).Update("canonical_id", target.ID).Error; err != nil {
+		return err
+	}
+	return tx.Model(source).Update("canonical_id", target.ID).Error
+}
+
+// ScopeTags 按标签筛选书籍。matchAll 为 true 时要求包含全部标签（AND），否则包含任一标签（OR）。
+// 不存在的标签在 AND 模式下使结果为空，在 OR 模式下被忽略。
+func ScopeTags(db *gorm.DB, names []string, matchAll bool) func(*gorm.DB) *gorm.DB {
+	idSet := make(map[uint]bool)
+	missing := false
+	for _, name := range names {
+		tag, err := FindTag(db, name)
+		if err != nil {
+			missing = true
+			continue
+		}
+		idSet[tag.ID] = true
+	}
+	ids := make([]uint, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+
+	return func(query *gorm.DB) *gorm.DB {
+		if len(ids) == 0 || (matchAll && missing) {
+			return query.Where("1 = 0")
+		}
+		subQuery := db.Model(&models.BookTag{}).Select("book_id").Where("tag_id IN ?", ids)
+		if matchAll {
+			subQuery = subQuery.Group("book_id").Having("COUNT(DISTINCT tag_id) = ?", len(ids))
+		}
+		return query.Where("books.id IN (?)", subQuery)
+	}
+}
+
+// TagCloud 返回使用次数最多的标签及其权重，结果缓存在 Redis 中
+func TagCloud(limit int) ([]TagCloudItem, error) {
+	if limit <= 0 || limit > tagCloudSize {
+		limit = tagCloudSize
+	}
+
+	var items []TagCloudItem
+	if val, err := config.RDB.Get(config.Ctx, tagCloudCacheKey).Result(); err == nil {
+		if err := json.Unmarshal([]byte(val), &items); err == nil {
+			if len(items) > limit {
+				items = items[:limit]
+			}
+			return items, nil
+		}
+	}
+
+	err := config.DB.Model(&models.BookTag{}).
+		Select("tags.id, tags.name, tags.display_name, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = book_tags.tag_id").
+		Joins("JOIN books ON books.id = book_tags.book_id AND books.deleted_at IS NULL").
+		Scopes(PublicBooks).
+		Group("tags.id, tags.name, tags.display_name").
+		Order("count DESC, tags.name").
+		Limit(tagCloudSize).
+		Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	assignTagWeights(items)
+
+	if itemsJSON, err := json.Marshal(items); err == nil {
+		config.RDB.Set(config.Ctx, tagCloudCacheKey, itemsJSON, 10*time.Minute)
+	}
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// InvalidateTagCloud 标签发生变化后清除标签云缓存
+func InvalidateTagCloud() {
+	config.RDB.Del(config.Ctx, tagCloudCacheKey)
+}
+
+// assignTagWeights 按使用次数的对数把标签分为 1~5 级权重，避免热门标签过于突出
+func assignTagWeights(items []TagCloudItem) {
+	if len(items) == 0 {
+		return
+	}
+	minCount, maxCount := items[0].Count, items[0].Count
+	for _, item := range items {
+		if item.Count < minCount {
+			minCount = item.Count
+		}
+		if item.Count > maxCount {
+			maxCount = item.Count
+		}
+	}
+	spread := math.Log(float64(maxCount)) - math.Log(float64(minCount))
+	for i := range items {
+		if spread == 0 {
+			items[i].Weight = 3
+			continue
+		}
+		ratio := (math.Log(float64(items[i].Count)) - math.Log(float64(minCount))) / spread
+		items[i].Weight = 1 + int(math.Round(ratio*4))
+	}
+}
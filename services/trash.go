@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bookshare/config"
+	"bookshare/models"
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 回收站中的条目类型
+const (
+	TrashBooks     = "books"
+	TrashComments  = "comments"
+	TrashRelations = "relations"
+)
+
+// ErrBookInTrash 评论或阅读关系所属的书籍也在回收站中，需要先恢复书籍
+var ErrBookInTrash = errors.New("book is in the trash, restore the book first")
+
+// bookTrashDependents 随书籍一起移入回收站、恢复时一并恢复的数据
+var bookTrashDependents = []interface{}{&models.BookFile{}, &models.Comment{}, &models.UserBookRelation{}}
+
+// TrashBook 将书籍移入回收站：书籍及其附件、评论、阅读关系以相同的删除时间软删除，恢复时据此一并恢复。
+// 附件仍持有 Blob 的引用，彻底删除时才释放
+func TrashBook(tx *gorm.DB, book *models.Book) error {
+	now := time.Now()
+	for _, model := range bookTrashDependents {
+		if err := tx.Model(model).Where("book_id = ?", book.ID).Update("deleted_at", now).Error; err != nil {
+			return err
+		}
+	}
+	if err := RemoveBookFromIndex(tx, book.ID); err != nil {
+		return err
+	}
+	if err := tx.Model(book).Update("deleted_at", now).Error; err != nil {
+		return err
+	}
+	book.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	return nil
+}
+
+// RestoreBook 从回收站恢复书籍，以及与书籍一起删除的附件、评论和阅读关系；之前单独删除的不恢复
+func RestoreBook(tx *gorm.DB, book *models.Book) error {
+	for _, model := range bookTrashDependents {
+		if err := tx.Unscoped().Model(model).Where("book_id = ? AND deleted_at = ?", book.ID, book.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Unscoped().Model(book).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	book.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// RestoreComment 恢复评论，所属书籍在回收站中时返回 ErrBookInTrash
+func RestoreComment(tx *gorm.DB, comment *models.Comment) error {
+	if !bookExists(tx, comment.BookID) {
+		return ErrBookInTrash
+	}
+	if err := tx.Unscoped().Model(comment).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	comment.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+// RestoreRelation 恢复阅读关系，所属书籍在回收站中时返回 ErrBookInTrash
+func RestoreRelation(tx *gorm.DB, relation *models.UserBookRelation) error {
+	if !bookExists(tx, relation.BookID) {
+		return ErrBookInTrash
+	}
+	if err := tx.Unscoped().Model(relation).Update("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	relation.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func bookExists(db *gorm.DB, bookID uint) bool {
+	var count int64
+	db.Model(&models.Book{}).Where("id = ?", bookID).Count(&count)
+	return count > 0
+}
+
+// PurgeBook 彻底删除书籍及其全部关联数据，返回需要在事务提交后清理的 Blob 哈希
+func PurgeBook(tx *gorm.DB, book *models.Book) ([]string, error) {
+	orphaned, err := ReleaseBookFiles(tx, book)
+	if err != nil {
+		return nil, err
+	}
+	for _, model := range []interface{}{
+		&models.Comment{}, &models.UserBookRelation{}, &models.BookTag{}, &models.BookAuthor{}, &models.BookIdentifier{},
+		&models.BookRevision{}, &models.BookStatusChange{}, &models.BookShareLink{}, &models.BookSearchDoc{}, &models.Notification{},
+	} {
+		if err := tx.Unscoped().Where("book_id = ?", book.ID).Delete(model).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Where("book_id = ? OR other_id = ?", book.ID, book.ID).Delete(&models.BookDuplicateDismissal{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("to_id = ?", book.ID).Delete(&models.BookRedirect{}).Error; err != nil {
+		return nil, err
+	}
+	return orphaned, tx.Unscoped().Delete(book).Error
+}
+
+// PurgeExpiredTrash 彻底删除在 cutoff 之前移入回收站的书籍、评论和阅读关系，返回删除的数量
+func PurgeExpiredTrash(db *gorm.DB, cutoff time.Time) (int, error) {
+	var books []models.Book
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&books).Error; err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i := range books {
+		var orphaned []string
+		err := db.Transaction(func(tx *gorm.DB) error {
+			hashes, err := PurgeBook(tx, &books[i])
+			orphaned = hashes
+			return err
+		})
+		if err != nil {
+			return purged, err
+		}
+		RemoveBlobFiles(orphaned)
+		purged++
+	}
+
+	for _, model := range []interface{}{&models.Comment{}, &models.UserBookRelation{}} {
+		result := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(model)
+		if result.Error != nil {
+			return purged, result.Error
+		}
+		purged += int(result.RowsAffected)
+	}
+	return purged, nil
+}
+
+// StartTrashPurgeWorker 启动后台任务：每隔 TRASH_PURGE_INTERVAL（默认 24 小时）彻底删除
+// 在回收站中超过 TRASH_RETENTION（默认 720h，即 30 天）的条目
+func StartTrashPurgeWorker() {
+	interval := durationFromEnv("TRASH_PURGE_INTERVAL", 24*time.Hour)
+	retention := durationFromEnv("TRASH_RETENTION", 30*24*time.Hour)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeExpiredTrash(config.DB, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Failed to purge expired trash: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired items from the trash", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bookshare/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidVisibility = errors.New("invalid visibility, expected one of public, followers, private, link")
+
+// IsValidBookVisibility 检查是否为有效的可见范围
+func IsValidBookVisibility(visibility string) bool {
+	switch visibility {
+	case models.VisibilityPublic, models.VisibilityFollowers, models.VisibilityPrivate, models.VisibilityLink:
+		return true
+	}
+	return false
+}
+
+// PublicBooks 只查询已发布且公开的书籍，用于不区分查看者的补全、标签云、系列和保存的搜索等
+func PublicBooks(query *gorm.DB) *gorm.DB {
+	return PublishedBooks(query).Where("books.visibility = ?", models.VisibilityPublic)
+}
+
+// VisibleBooks 查询 viewerID 在列表和搜索中可以看到的已发布书籍：公开的书籍、关注的用户仅对关注者可见的书籍，
+// 以及自己上传的任何可见范围的书籍。viewerID 为 0 表示未登录，只能看到公开的书籍。
+// 链接分享的书籍不出现在其他人的列表中
+func VisibleBooks(db *gorm.DB, viewerID uint) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if viewerID == 0 {
+			return PublicBooks(query)
+		}
+		followees := db.Model(&models.Follow{}).Select("followee_id").Where("follower_id = ?", viewerID)
+		return PublishedBooks(query).Where(
+			"books.visibility = ? OR books.user_id = ? OR (books.visibility = ? AND books.user_id IN (?))",
+			models.VisibilityPublic, viewerID, models.VisibilityFollowers, followees,
+		)
+	}
+}
+
+// CanViewBook 判断 viewerID 能否查看书籍详情和文件。上传者可以查看自己的任何书籍；
+// 其他人只能查看已发布的书籍，并且书籍公开、查看者关注了上传者（仅关注者可见），或持有有效的分享链接
+func CanViewBook(db *gorm.DB, book *models.Book, viewerID uint, shareToken string) bool {
+	if viewerID != 0 && viewerID == book.UserID {
+		return true
+	}
+	if book.Status != models.BookPublished {
+		return false
+	}
+	if book.Visibility == models.VisibilityPublic {
+		return true
+	}
+	if book.Visibility == models.VisibilityFollowers && viewerID != 0 && IsFollowing(db, viewerID, book.UserID) {
+		return true
+	}
+	return shareToken != "" && validShareToken(db, book.ID, shareToken)
+}
+
+// IsFollowing followerID 是否关注了 followeeID
+func IsFollowing(db *gorm.DB, followerID, followeeID uint) bool {
+	var count int64
+	db.Model(&models.Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count)
+	return count > 0
+}
+
+func validShareToken(db *gorm.DB, bookID uint, token string) bool {
+	var count int64
+	db.Model(&models.BookShareLink{}).
+		Where("book_id = ? AND token = ? AND (expires_at IS NULL OR expires_at > ?)", bookID, token, time.Now()).
+		Count(&count)
+	return count > 0
+}
+
+// CreateShareLink 为书籍创建分享链接，token 为 32 字节的随机数，无法猜测
+func CreateShareLink(db *gorm.DB, book *models.Book, userID uint, expiresAt *time.Time) (*models.BookShareLink, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	link := &models.BookShareLink{BookID: book.ID, UserID: userID, Token: hex.EncodeToString(buf), ExpiresAt: expiresAt}
+	if err := db.Create(link).Error; err != nil {
+		return nil, err
+	}
+	return link, nil
+}
@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+// AuthorCredit 从作者署名中解析出的一个署名项
+type AuthorCredit struct {
+	Name string
+	Role string // author, translator, editor, illustrator
+}
+
+var (
+	// 署名项分隔符，不同署名项可以有不同角色，例如 "[美] 卡勒德·胡赛尼 / 李继宏 译"。
+	// 半角逗号也用于 "Liu, Ken" 这样姓在前的写法，单独由 splitCommaCredits 处理
+	creditSeparator = regexp.MustCompile(`\s*(?:[/;；|，&＆]|\sand\s|\s和\s)\s*`)
+	commaSeparator  = regexp.MustCompile(`\s*,\s*`)
+	// 同一署名项内并列的人名共享角色，例如 "张三、李四 译"
+	creditNameSeparator = regexp.MustCompile(`\s*、\s*`)
+	// 国籍标注，例如 "[美]"、"（英）"
+	nationalityPrefix = regexp.MustCompile(`^\s*[\[【(（〔][^\]】)）〕]{1,6}[\]】)）〕]\s*`)
+	// 中文角色标注可以紧跟人名，例如 "鲁迅著"、"李继宏 (译)"
+	cnRoleSuffix = regexp.MustCompile(`\s*[(（\[]?\s*(编著|主编|编|著|译|绘|插图)\s*[)）\]]?\s*$`)
+	// 英文角色标注需要与人名以空白或括号分开，例如 "Edward (ed.)"
+	enRoleSuffix = regexp.MustCompile(`(?i)(?:\s*[(\[]\s*(trans\.?|translator|tr\.|eds?\.?|editor|illus\.?|illustrator)\s*[)\]]|\s+(trans\.|translator|eds?\.|editor|illus\.|illustrator))\s*$`)
+)
+
+var roleMarkers = map[string]string{
+	"著": "author", "编著": "author",
+	"译": "translator", "trans": "translator", "trans.": "translator", "translator": "translator", "tr.": "translator",
+	"编": "editor", "主编": "editor", "ed": "editor", "ed.": "editor", "eds": "editor", "eds.": "editor", "editor": "editor",
+	"绘": "illustrator", "插图": "illustrator", "illus": "illustrator", "illus.": "illustrator", "illustrator": "illustrator",
+}
+
+// ParseAuthorCredits 将自由格式的作者字符串拆分为署名列表，未标注角色的视为作者
+func ParseAuthorCredits(s string) []AuthorCredit {
+	var credits []AuthorCredit
+	seen := make(map[AuthorCredit]bool)
+	var items []string
+	for _, item := range creditSeparator.Split(s, -1) {
+		items = append(items, splitCommaCredits(item)...)
+	}
+	for _, item := range items {
+		role := "author"
+		for _, suffix := range []*regexp.Regexp{cnRoleSuffix, enRoleSuffix} {
+			if m := suffix.FindStringSubmatch(item); m != nil {
+				marker := m[1]
+				if marker == "" && len(m) > 2 {
+					marker = m[2]
+				}
+				role = roleMarkers[strings.ToLower(marker)]
+				item = strings.TrimRight(item[:len(item)-len(m[0])], ", ")
+				break
+			}
+		}
+
+		for _, name := range creditNameSeparator.Split(item, -1) {
+			name = CleanAuthorName(name)
+			if name == "" {
+				continue
+			}
+			credit := AuthorCredit{Name: name, Role: role}
+			if !seen[credit] {
+				seen[credit] = true
+				credits = append(credits, credit)
+			}
+		}
+	}
+	return credits
+}
+
+// splitCommaCredits 按半角逗号拆分署名项。逗号两侧都是多个词的人名时才拆分，例如 "Ken Liu, Cixin Liu"；
+// 否则视为姓在前的写法（"Liu, Ken"、"Tolkien, J. R. R."）或角色标注（"Ken Liu, ed."），保持为一个署名项
+func splitCommaCredits(item string) []string {
+	parts := commaSeparator.Split(item, -1)
+	if len(parts) < 2 {
+		return parts
+	}
+	for _, part := range parts {
+		if len(strings.Fields(part)) < 2 {
+			return []string{item}
+		}
+	}
+	return parts
+}
+
+// CleanAuthorName 去掉国籍标注并规整空白
+func CleanAuthorName(name string) string {
+	name = nationalityPrefix.ReplaceAllString(name, "")
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// PersonalName 用于引文格式化的人名。西文人名拆分为姓和名，中日韩人名和只有一个词的名字整体作为姓
+type PersonalName struct {
+	Family string
+	Given  string
+}
+
+// ParsePersonalName 解析人名，支持 "Ken Liu" 和 "Liu, Ken" 两种写法
+func ParsePersonalName(name string) PersonalName {
+	name = CleanAuthorName(name)
+	if containsHan(name) {
+		return PersonalName{Family: name}
+	}
+	if family, given, ok := strings.Cut(name, ","); ok {
+		return PersonalName{Family: strings.TrimSpace(family), Given: strings.TrimSpace(given)}
+	}
+	words := strings.Fields(name)
+	if len(words) < 2 {
+		return PersonalName{Family: name}
+	}
+	return PersonalName{Family: words[len(words)-1], Given: strings.Join(words[:len(words)-1], " ")}
+}
+
+// Initials 名的首字母，sep 为首字母之后的符号，如 "Jean Paul" 在 sep 为 "." 时返回 "J. P."
+func (n PersonalName) Initials(sep string) string {
+	var initials []string
+	for _, part := range strings.FieldsFunc(n.Given, func(r rune) bool { return r == ' ' || r == '-' || r == '.' }) {
+		initials = append(initials, string([]rune(part)[0])+sep)
+	}
+	return strings.Join(initials, " ")
+}
+
+// String 按 "名 姓" 的顺序输出
+func (n PersonalName) String() string {
+	if n.Given == "" {
+		return n.Family
+	}
+	return n.Given + " " + n.Family
+}
+
+// Inverted 按 "姓, 名" 的顺序输出
+func (n PersonalName) Inverted() string {
+	if n.Given == "" {
+		return n.Family
+	}
+	return n.Family + ", " + n.Given
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/longbridgeapp/opencc"
+	"github.com/mozillazg/go-pinyin"
+)
+
+var (
+	converterOnce sync.Once
+	t2sConverter  *opencc.OpenCC
+	s2tConverter  *opencc.OpenCC
+)
+
+// initConverters 首次使用时加载简繁转换词典
+func initConverters() {
+	converterOnce.Do(func() {
+		var err error
+		if t2sConverter, err = opencc.New("t2s"); err != nil {
+			log.Printf("Failed to load traditional-to-simplified dictionary: %v", err)
+		}
+		if s2tConverter, err = opencc.New("s2t"); err != nil {
+			log.Printf("Failed to load simplified-to-traditional dictionary: %v", err)
+		}
+	})
+}
+
+// ToSimplified 将繁体中文转换为简体，如 "三體" 转为 "三体"；词典不可用时原样返回
+func ToSimplified(s string) string {
+	if !containsHan(s) {
+		return s
+	}
+	initConverters()
+	if t2sConverter == nil {
+		return s
+	}
+	if converted, err := t2sConverter.Convert(s); err == nil {
+		return converted
+	}
+	return s
+}
+
+// ToTraditional 将简体中文转换为繁体；词典不可用时原样返回
+func ToTraditional(s string) string {
+	if !containsHan(s) {
+		return s
+	}
+	initConverters()
+	if s2tConverter == nil {
+		return s
+	}
+	if converted, err := s2tConverter.Convert(s); err == nil {
+		return converted
+	}
+	return s
+}
+
+// PinyinForms 返回文本中每段连续汉字的全拼和首字母，如 "三体" 返回 ["santi", "st"]；
+// 有多段汉字时还包含整体的全拼和首字母。没有汉字时返回 nil
+func PinyinForms(s string) []string {
+	var runs []string
+	var current []rune
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			current = append(current, r)
+			continue
+		}
+		if len(current) > 0 {
+			runs = append(runs, string(current))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		runs = append(runs, string(current))
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	if len(runs) > 1 {
+		runs = append(runs, strings.Join(runs, ""))
+	}
+
+	args := pinyin.NewArgs()
+	var forms []string
+	for _, run := range runs {
+		syllables := pinyin.LazyPinyin(run, args)
+		if len(syllables) == 0 {
+			continue
+		}
+		var initials strings.Builder
+		for _, syllable := range syllables {
+			initials.WriteByte(syllable[0])
+		}
+		forms = append(forms, strings.Join(syllables, ""), initials.String())
+	}
+	return forms
+}
+
+// IsPinyinQuery 判断搜索词是否可能是拼音（只包含 ASCII 字母和空白）
+func IsPinyinQuery(s string) bool {
+	hasLetter := false
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			hasLetter = true
+		case unicode.IsSpace(r):
+		default:
+			return false
+		}
+	}
+	return hasLetter
+}
+
+func containsHan(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Han, r) {
+			return true
+		}
+	}
+	return false
+}
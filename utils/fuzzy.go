@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// LatinWords 提取文本中的拉丁字母单词并转为小写，用于构建拼写纠错的词表
+func LatinWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+	})
+}
+
+// MaxTypos 根据单词长度返回允许的拼写错误数：短词不纠错，长词最多两处
+func MaxTypos(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// EditDistance 计算两个字符串的编辑距离，相邻字符交换算作一次编辑（如 "sanit" 与 "santi"）
+func EditDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	rows := make([][]int, len(s)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(t)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			rows[i][j] = min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				rows[i][j] = min(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+	return rows[len(s)][len(t)]
+}
+
+var (
+	// 书名中括号里的内容，通常是丛书名、卷号或版本，例如 "(The Hunger Games, #1)"、"（修订版）"
+	titleBrackets = regexp.MustCompile(`\s*[(（\[【][^)）\]】]*[)）\]】]\s*`)
+	// 副标题分隔符
+	subtitleSeparator = regexp.MustCompile(`\s*(?:[:：]|\s-\s|——)\s*`)
+)
+
+// MainTitle 去掉书名中括号里的内容和副标题，只保留正题名
+func MainTitle(s string) string {
+	title := strings.TrimSpace(titleBrackets.ReplaceAllString(s, " "))
+	if parts := subtitleSeparator.Split(title, 2); parts[0] != "" {
+		title = parts[0]
+	}
+	if title == "" {
+		return strings.TrimSpace(s)
+	}
+	return title
+}
+
+// matchKey 用于模糊比较的形式：繁体转简体、转小写，只保留字母和数字，拉丁单词按字母顺序排列，
+// 使 "Liu Cixin" 与 "Cixin Liu" 相同
+func matchKey(s string, sortWords bool) string {
+	s = strings.ToLower(ToSimplified(s))
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if sortWords {
+		sort.Strings(words)
+	}
+	if containsHan(s) {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words, " ")
+}
+
+// Similarity 基于编辑距离的相似度，1 表示相同，0 表示完全不同
+func Similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	n := max(len([]rune(a)), len([]rune(b)))
+	if n == 0 {
+		return 0
+	}
+	return 1 - float64(EditDistance(a, b))/float64(n)
+}
+
+// TitleSimilarity 比较两个书名的正题名
+func TitleSimilarity(a, b string) float64 {
+	return Similarity(matchKey(MainTitle(a), false), matchKey(MainTitle(b), false))
+}
+
+// AuthorSimilarity 比较两个作者署名，取两边署名项之间的最高相似度
+func AuthorSimilarity(a, b string) float64 {
+	best := 0.0
+	for _, x := range ParseAuthorCredits(a) {
+		for _, y := range ParseAuthorCredits(b) {
+			best = max(best, Similarity(matchKey(x.Name, true), matchKey(y.Name, true)))
+		}
+	}
+	return best
+}
+
+// TitleKey 书名的正题名规范化后的形式，用于将可能重复的书籍分组
+func TitleKey(s string) string {
+	return matchKey(MainTitle(s), false)
+}
+
+// AuthorKey 第一作者规范化后的形式，用于将可能重复的书籍分组
+func AuthorKey(s string) string {
+	credits := ParseAuthorCredits(s)
+	if len(credits) == 0 {
+		return ""
+	}
+	return matchKey(credits[0].Name, true)
+}
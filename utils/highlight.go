@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchTerms 将搜索词按空白拆分为去重后的小写词项
+func SearchTerms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Highlight 用 <em> 标记文本中出现的词项（不区分大小写），其余内容做 HTML 转义。
+// maxRunes 大于 0 且文本过长时，截取第一个匹配附近的片段并以省略号标记。没有匹配时返回 false。
+func Highlight(text string, terms []string, maxRunes int) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// marked[i] 表示第 i 个字符属于某个匹配
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		pattern := []rune(term)
+		if len(pattern) == 0 {
+			continue
+		}
+		for i := 0; i+len(pattern) <= len(lower); i++ {
+			if !runesEqual(lower[i:i+len(pattern)], pattern) {
+				continue
+			}
+			for j := i; j < i+len(pattern); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start, end := 0, len(runes)
+	if maxRunes > 0 && len(runes) > maxRunes {
+		// 匹配前保留约四分之一的上下文
+		start = first - maxRunes/4
+		if start < 0 {
+			start = 0
+		}
+		end = start + maxRunes
+		if end > len(runes) {
+			end = len(runes)
+			start = end - maxRunes
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<em>" + segment + "</em>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	htmlBreakPattern = regexp.MustCompile(`(?i)<\s*(br|/p|/div|/li|/h[1-6]|/blockquote)\b[^>]*>`)
+	htmlTagPattern   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// HTMLToText 将简介等 HTML 片段转为纯文本：段落和换行标签转为换行，去掉其余标签并解码实体
+func HTMLToText(s string) string {
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTagPattern.ReplaceAllString(s, ""))
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidISBN = errors.New("invalid ISBN")
+
+// NormalizeISBN 去掉 ISBN 中的连字符、空格及 "ISBN" 前缀，并将校验位 x 转为大写
+func NormalizeISBN(s string) string {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "ISBN")
+	s = strings.TrimLeft(s, ":：- ")
+
+	var b strings.Builder
+	for _, r := range s {
+		if (r >= '0' && r <= '9') || r == 'X' {
+			b.WriteRune(r)
+		} else if r != '-' && r != ' ' {
+			return "" // 含有非法字符
+		}
+	}
+	return b.String()
+}
+
+// IsValidISBN10 校验 ISBN-10：各位依次乘以 10..1 求和，能被 11 整除
+func IsValidISBN10(isbn string) bool {
+	if len(isbn) != 10 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var d int
+		switch {
+		case isbn[i] >= '0' && isbn[i] <= '9':
+			d = int(isbn[i] - '0')
+		case isbn[i] == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+// IsValidISBN13 校验 ISBN-13：奇数位权重 1、偶数位权重 3，和能被 10 整除
+func IsValidISBN13(isbn string) bool {
+	if len(isbn) != 13 || !(strings.HasPrefix(isbn, "978") || strings.HasPrefix(isbn, "979")) {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 13; i++ {
+		if isbn[i] < '0' || isbn[i] > '9' {
+			return false
+		}
+		d := int(isbn[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// ISBN10To13 将合法的 ISBN-10 转换为 978 前缀的 ISBN-13
+func ISBN10To13(isbn10 string) (string, error) {
+	if !IsValidISBN10(isbn10) {
+		return "", ErrInvalidISBN
+	}
+	body := "978" + isbn10[:9]
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return body + string(rune('0'+(10-sum%10)%10)), nil
+}
+
+// ISBN13To10 将 978 前缀的 ISBN-13 转换为 ISBN-10；979 前缀没有对应的 ISBN-10
+func ISBN13To10(isbn13 string) (string, error) {
+	if !IsValidISBN13(isbn13) || !strings.HasPrefix(isbn13, "978") {
+		return "", ErrInvalidISBN
+	}
+	body := isbn13[3:12]
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(body[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return body + "X", nil
+	}
+	return body + string(rune('0'+check)), nil
+}
+
+// ParseISBN 校验任意格式的 ISBN，返回对应的 ISBN-10（可能为空）和 ISBN-13
+func ParseISBN(s string) (isbn10, isbn13 string, err error) {
+	isbn := NormalizeISBN(s)
+	switch len(isbn) {
+	case 10:
+		isbn13, err = ISBN10To13(isbn)
+		if err != nil {
+			return "", "", err
+		}
+		return isbn, isbn13, nil
+	case 13:
+		if !IsValidISBN13(isbn) {
+			return "", "", ErrInvalidISBN
+		}
+		isbn10, _ = ISBN13To10(isbn) // 979 前缀没有 ISBN-10
+		return isbn10, isbn, nil
+	}
+	return "", "", ErrInvalidISBN
+}
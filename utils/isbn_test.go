@@ -0,0 +1,88 @@
+package utils
+
+import "testing"
+
+func TestISBNChecksums(t *testing.T) {
+	tests := []struct {
+		isbn    string
+		valid10 bool
+		valid13 bool
+	}{
+		{"7536692935", true, false},
+		{"0306406152", true, false},
+		{"080442957X", true, false},
+		{"0804429579", false, false},
+		{"080442957x", false, false}, // 校验位需要先经过 NormalizeISBN 转为大写
+		{"X306406152", false, false},
+		{"030640615", false, false},
+		{"9787536692930", false, true},
+		{"9780306406157", false, true},
+		{"9791090636071", false, true},
+		{"9787536692931", false, false},
+		{"9770306406156", false, false}, // 977 不是图书的前缀
+		{"978030640615X", false, false},
+	}
+	for _, tt := range tests {
+		if got := IsValidISBN10(tt.isbn); got != tt.valid10 {
+			t.Errorf("IsValidISBN10(%q) = %v, want %v", tt.isbn, got, tt.valid10)
+		}
+		if got := IsValidISBN13(tt.isbn); got != tt.valid13 {
+			t.Errorf("IsValidISBN13(%q) = %v, want %v", tt.isbn, got, tt.valid13)
+		}
+	}
+}
+
+func TestISBNConversion(t *testing.T) {
+	tests := []struct {
+		isbn10 string
+		isbn13 string
+	}{
+		{"7536692935", "9787536692930"},
+		{"0306406152", "9780306406157"},
+		{"080442957X", "9780804429573"},
+		{"0000000000", "9780000000002"},
+	}
+	for _, tt := range tests {
+		if got, err := ISBN10To13(tt.isbn10); err != nil || got != tt.isbn13 {
+			t.Errorf("ISBN10To13(%q) = %q, %v, want %q", tt.isbn10, got, err, tt.isbn13)
+		}
+		if got, err := ISBN13To10(tt.isbn13); err != nil || got != tt.isbn10 {
+			t.Errorf("ISBN13To10(%q) = %q, %v, want %q", tt.isbn13, got, err, tt.isbn10)
+		}
+	}
+
+	for _, isbn := range []string{"0306406153", "123"} {
+		if _, err := ISBN10To13(isbn); err != ErrInvalidISBN {
+			t.Errorf("ISBN10To13(%q) error = %v, want ErrInvalidISBN", isbn, err)
+		}
+	}
+	// 979 前缀没有对应的 ISBN-10
+	for _, isbn := range []string{"9791090636071", "9780306406158"} {
+		if _, err := ISBN13To10(isbn); err != ErrInvalidISBN {
+			t.Errorf("ISBN13To10(%q) error = %v, want ErrInvalidISBN", isbn, err)
+		}
+	}
+}
+
+func TestParseISBN(t *testing.T) {
+	tests := []struct {
+		input  string
+		isbn10 string
+		isbn13 string
+		valid  bool
+	}{
+		{"978-7-5366-9293-0", "7536692935", "9787536692930", true},
+		{"ISBN: 7-5366-9293-5", "7536692935", "9787536692930", true},
+		{"isbn 0-8044-2957-x", "080442957X", "9780804429573", true},
+		{"979-10-90636-07-1", "", "9791090636071", true},
+		{"978-7-5366-9293-1", "", "", false},
+		{"7536692935a", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		isbn10, isbn13, err := ParseISBN(tt.input)
+		if (err == nil) != tt.valid || isbn10 != tt.isbn10 || isbn13 != tt.isbn13 {
+			t.Errorf("ParseISBN(%q) = %q, %q, %v, want %q, %q, valid %v", tt.input, isbn10, isbn13, err, tt.isbn10, tt.isbn13, tt.valid)
+		}
+	}
+}
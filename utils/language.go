@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// NormalizeLanguage 将语言代码或常见语言名称规范化为 ISO 639 基础语言代码，例如 "zh-CN"、"zh_Hans"、"中文" 都转为 "zh"
+func NormalizeLanguage(s string) (string, bool) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "_", "-"))
+	if s == "" {
+		return "", false
+	}
+	if code, ok := languageNames[strings.ToLower(s)]; ok {
+		return code, true
+	}
+	tag, err := language.Parse(s)
+	if err != nil {
+		return "", false
+	}
+	base, _ := tag.Base()
+	return base.String(), true
+}
+
+// languageNames 常见的语言名称写法
+var languageNames = map[string]string{
+	"chinese": "zh", "中文": "zh", "汉语": "zh", "简体中文": "zh", "繁体中文": "zh", "繁體中文": "zh",
+	"english": "en", "英文": "en", "英语": "en",
+	"japanese": "ja", "日文": "ja", "日语": "ja",
+	"korean": "ko", "韩文": "ko", "韩语": "ko",
+	"french": "fr", "法文": "fr", "法语": "fr",
+	"german": "de", "德文": "de", "德语": "de",
+	"russian": "ru", "俄文": "ru", "俄语": "ru",
+	"spanish": "es", "西班牙文": "es", "西班牙语": "es",
+}
+
+// isbnGroupLanguages ISBN 地区组号与主要语言的对应关系，只用于在缺少语言信息时推测
+var isbnGroupLanguages = []struct {
+	prefix   string
+	language string
+}{
+	{"97889", "ko"},
+	{"978957", "zh"}, {"978986", "zh"}, {"978962", "zh"}, {"978988", "zh"},
+	{"9787", "zh"},
+	{"9780", "en"}, {"9781", "en"},
+	{"9782", "fr"},
+	{"9783", "de"},
+	{"9784", "ja"},
+	{"9785", "ru"},
+}
+
+// LanguageFromISBN 根据 ISBN-13 的地区组号推测出版语言，无法判断时返回空字符串
+func LanguageFromISBN(isbn13 string) string {
+	for _, group := range isbnGroupLanguages {
+		if strings.HasPrefix(isbn13, group.prefix) {
+			return group.language
+		}
+	}
+	return ""
+}
+
+// marcLanguageCodes ISO 639-2/B 与 639-2/T 不同的语言，MARC 使用 B 代码
+var marcLanguageCodes = map[string]string{
+	"bo": "tib", "cs": "cze", "cy": "wel", "de": "ger", "el": "gre", "eu": "baq", "fa": "per",
+	"fr": "fre", "hy": "arm", "is": "ice", "ka": "geo", "mi": "mao", "mk": "mac", "ms": "may",
+	"my": "bur", "nl": "dut", "ro": "rum", "sk": "slo", "sq": "alb", "zh": "chi",
+}
+
+// MARCLanguageCode 将 ISO 639-1 语言代码转为 MARC 语言代码（ISO 639-2/B），如 "zh" 转为 "chi"，未知时返回 "und"
+func MARCLanguageCode(code string) string {
+	if marc, ok := marcLanguageCodes[code]; ok {
+		return marc
+	}
+	if base, err := language.ParseBase(code); err == nil {
+		if iso3 := base.ISO3(); len(iso3) == 3 {
+			return iso3
+		}
+	}
+	return "und"
+}
@@ -0,0 +1,338 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// QueryFields 高级搜索支持的字段，lang 是 language 的简写
+var QueryFields = map[string]string{
+	"title":     "title",
+	"author":    "author",
+	"category":  "category",
+	"tag":       "tag",
+	"language":  "language",
+	"lang":      "language",
+	"publisher": "publisher",
+	"year":      "year",
+	"pages":     "pages",
+	"format":    "format",
+	"isbn":      "isbn",
+	"series":    "series",
+}
+
+// QueryError 搜索语句的语法错误，Position 为出错词在语句中的字符位置（从 0 开始）
+type QueryError struct {
+	Position int    `json:"position"`
+	Token    string `json:"token"`
+	Message  string `json:"message"`
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s at position %d near %q", e.Message, e.Position, e.Token)
+}
+
+// QueryNode 搜索语句的语法树节点：*QueryTerm、*QueryNot、*QueryAnd 或 *QueryOr
+type QueryNode interface {
+	queryNode()
+}
+
+// QueryTerm 单个搜索条件。Field 为空表示全文搜索词；Phrase 表示值来自引号中的短语
+type QueryTerm struct {
+	Field    string
+	Value    string
+	Phrase   bool
+	Position int
+	Token    string // 原始文本，用于错误提示
+}
+
+// QueryNot 取反，如 -tag:translated 或 NOT tag:translated
+type QueryNot struct {
+	Node QueryNode
+}
+
+// QueryAnd 全部条件都需满足，相邻的条件默认为 AND
+type QueryAnd struct {
+	Nodes []QueryNode
+}
+
+// QueryOr 满足任一条件
+type QueryOr struct {
+	Nodes []QueryNode
+}
+
+func (*QueryTerm) queryNode() {}
+func (*QueryNot) queryNode()  {}
+func (*QueryAnd) queryNode()  {}
+func (*QueryOr) queryNode()   {}
+
+type queryTokenKind int
+
+const (
+	tokenTerm queryTokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenEOF
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	term *QueryTerm
+	pos  int
+	text string
+}
+
+// ParseQuery 解析高级搜索语句，例如：
+//
+//	author:"刘慈欣" category:科幻 year:>2000 -tag:translated
+//	(tag:科幻 OR tag:奇幻) pages:100..300 三体
+//
+// 支持字段条件、引号短语、"-" 或 NOT 取反、范围（>2000、<=300、2000..2010）、OR 以及括号分组。
+// 相邻的普通搜索词合并为一个全文搜索词，以便 "san ti" 这样的拼音按整体匹配
+func ParseQuery(input string) (QueryNode, error) {
+	tokens, err := lexQuery(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &queryParser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, &QueryError{Position: 0, Token: "", Message: "empty query"}
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if tok.kind == tokenRParen {
+			return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "unmatched closing parenthesis"}
+		}
+		return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "unexpected token"}
+	}
+	return node, nil
+}
+
+// lexQuery 将语句拆分为词法单元，位置按字符计算
+func lexQuery(input string) ([]queryToken, error) {
+	runes := []rune(input)
+	var tokens []queryToken
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenLParen, pos: i, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenRParen, pos: i, text: ")"})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			// 紧跟在词前面的 "-" 表示取反
+			tokens = append(tokens, queryToken{kind: tokenNot, pos: i, text: "-"})
+			i++
+		default:
+			term, next, err := lexTerm(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tok := queryToken{kind: tokenTerm, term: term, pos: i, text: term.Token}
+			if term.Field == "" && !term.Phrase {
+				switch term.Value {
+				case "OR", "|":
+					tok.kind = tokenOr
+				case "AND", "&":
+					tok.kind = tokenAnd
+				case "NOT":
+					tok.kind = tokenNot
+				}
+			}
+			tokens = append(tokens, tok)
+			i = next
+		}
+	}
+	return append(tokens, queryToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// lexTerm 读取一个搜索条件：可选的 "字段:" 前缀，以及普通词或引号短语
+func lexTerm(runes []rune, start int) (*QueryTerm, int, error) {
+	term := &QueryTerm{Position: start}
+	i := start
+
+	// 字段名由字母组成，后面紧跟冒号
+	j := i
+	for j < len(runes) && (runes[j] < unicode.MaxASCII && unicode.IsLetter(runes[j])) {
+		j++
+	}
+	if j > i && j < len(runes) && runes[j] == ':' {
+		name := strings.ToLower(string(runes[i:j]))
+		field, ok := QueryFields[name]
+		if !ok {
+			return nil, 0, &QueryError{Position: start, Token: string(runes[i : j+1]), Message: "unknown field " + name}
+		}
+		term.Field = field
+		i = j + 1
+		if i >= len(runes) || unicode.IsSpace(runes[i]) || runes[i] == ')' || runes[i] == '(' {
+			return nil, 0, &QueryError{Position: start, Token: string(runes[start:i]), Message: "missing value for field " + name}
+		}
+	}
+
+	if runes[i] == '"' {
+		end := i + 1
+		for end < len(runes) && runes[end] != '"' {
+			end++
+		}
+		if end >= len(runes) {
+			return nil, 0, &QueryError{Position: i, Token: string(runes[i:]), Message: "unterminated quoted phrase"}
+		}
+		term.Value = strings.TrimSpace(string(runes[i+1 : end]))
+		term.Phrase = true
+		term.Token = string(runes[start : end+1])
+		if term.Value == "" {
+			return nil, 0, &QueryError{Position: i, Token: term.Token, Message: "empty quoted phrase"}
+		}
+		return term, end + 1, nil
+	}
+
+	end := i
+	for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+		end++
+	}
+	term.Value = string(runes[i:end])
+	term.Token = string(runes[start:end])
+	return term, end, nil
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() queryToken {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr: and ( OR and )*
+func (p *queryParser) parseOr() (QueryNode, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	nodes := []QueryNode{first}
+	for p.peek().kind == tokenOr {
+		op := p.next()
+		if k := p.peek().kind; k == tokenEOF || k == tokenRParen || k == tokenOr {
+			return nil, &QueryError{Position: op.pos, Token: op.text, Message: "OR must be followed by a search term"}
+		}
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 1 {
+		return first, nil
+	}
+	return &QueryOr{Nodes: nodes}, nil
+}
+
+// parseAnd: unary ( [AND] unary )*
+func (p *queryParser) parseAnd() (QueryNode, error) {
+	var nodes []QueryNode
+	for {
+		tok := p.peek()
+		switch tok.kind {
+		case tokenEOF, tokenRParen, tokenOr:
+			if len(nodes) == 0 {
+				return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "expected a search term"}
+			}
+			return mergeTextTerms(nodes), nil
+		case tokenAnd:
+			p.next()
+			if len(nodes) == 0 {
+				return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "AND must follow a search term"}
+			}
+			if k := p.peek().kind; k == tokenEOF || k == tokenRParen || k == tokenOr || k == tokenAnd {
+				return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "AND must be followed by a search term"}
+			}
+			continue
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+}
+
+// parseUnary: ( - | NOT ) unary | primary
+func (p *queryParser) parseUnary() (QueryNode, error) {
+	if tok := p.peek(); tok.kind == tokenNot {
+		p.next()
+		if k := p.peek().kind; k == tokenEOF || k == tokenRParen || k == tokenOr || k == tokenAnd {
+			return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "negation must be followed by a search term"}
+		}
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &QueryNot{Node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary: ( or ) | term
+func (p *queryParser) parsePrimary() (QueryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenTerm:
+		return tok.term, nil
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing.kind != tokenRParen {
+			return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "missing closing parenthesis"}
+		}
+		p.next()
+		return node, nil
+	default:
+		return nil, &QueryError{Position: tok.pos, Token: tok.text, Message: "unexpected token"}
+	}
+}
+
+// mergeTextTerms 合并相邻的普通搜索词（无字段、非短语、未取反），返回单个节点或 *QueryAnd
+func mergeTextTerms(nodes []QueryNode) QueryNode {
+	var merged []QueryNode
+	for _, node := range nodes {
+		term, ok := node.(*QueryTerm)
+		if ok && term.Field == "" && !term.Phrase && len(merged) > 0 {
+			if prev, ok := merged[len(merged)-1].(*QueryTerm); ok && prev.Field == "" && !prev.Phrase {
+				merged[len(merged)-1] = &QueryTerm{
+					Value:    prev.Value + " " + term.Value,
+					Position: prev.Position,
+					Token:    prev.Token + " " + term.Token,
+				}
+				continue
+			}
+		}
+		merged = append(merged, node)
+	}
+	if len(merged) == 1 {
+		return merged[0]
+	}
+	return &QueryAnd{Nodes: merged}
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQueryErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		position int
+		token    string
+		message  string
+	}{
+		{"", 0, "", "empty query"},
+		{"   ", 0, "", "empty query"},
+		{"foo:bar", 0, "foo:", "unknown field foo"},
+		{"三体 title:", 3, "title:", "missing value for field title"},
+		{"title: 三体", 0, "title:", "missing value for field title"},
+		{`author:"刘慈欣`, 7, `"刘慈欣`, "unterminated quoted phrase"},
+		{`三体 "" 刘慈欣`, 3, `""`, "empty quoted phrase"},
+		{"(tag:科幻 OR tag:奇幻", 0, "(", "missing closing parenthesis"},
+		{"三体 )", 3, ")", "unmatched closing parenthesis"},
+		{"三体 OR", 3, "OR", "OR must be followed by a search term"},
+		{"三体 OR OR 球状闪电", 3, "OR", "OR must be followed by a search term"},
+		{"AND 三体", 0, "AND", "AND must follow a search term"},
+		{"三体 AND", 3, "AND", "AND must be followed by a search term"},
+		{"三体 NOT", 3, "NOT", "negation must be followed by a search term"},
+		{"三体 -)", 4, ")", "unmatched closing parenthesis"},
+		{"()", 1, ")", "expected a search term"},
+	}
+	for _, tt := range tests {
+		_, err := ParseQuery(tt.input)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ParseQuery(%q) error = %v, want *QueryError", tt.input, err)
+			continue
+		}
+		if queryErr.Position != tt.position || queryErr.Token != tt.token || queryErr.Message != tt.message {
+			t.Errorf("ParseQuery(%q) error = {%d %q %q}, want {%d %q %q}", tt.input,
+				queryErr.Position, queryErr.Token, queryErr.Message, tt.position, tt.token, tt.message)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	node, err := ParseQuery(`san ti author:"刘慈欣" -tag:translated (year:>2000 OR pages:100..300)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	and, ok := node.(*QueryAnd)
+	if !ok || len(and.Nodes) != 4 {
+		t.Fatalf("ParseQuery returned %#v, want QueryAnd with 4 nodes", node)
+	}
+	if term, ok := and.Nodes[0].(*QueryTerm); !ok || term.Value != "san ti" || term.Field != "" {
+		t.Errorf("adjacent text terms not merged: %#v", and.Nodes[0])
+	}
+	if term, ok := and.Nodes[1].(*QueryTerm); !ok || term.Field != "author" || term.Value != "刘慈欣" || !term.Phrase || term.Position != 7 {
+		t.Errorf("unexpected phrase term: %#v", and.Nodes[1])
+	}
+	if not, ok := and.Nodes[2].(*QueryNot); !ok {
+		t.Errorf("expected negation, got %#v", and.Nodes[2])
+	} else if term, ok := not.Node.(*QueryTerm); !ok || term.Field != "tag" || term.Value != "translated" {
+		t.Errorf("unexpected negated term: %#v", not.Node)
+	}
+	if or, ok := and.Nodes[3].(*QueryOr); !ok || len(or.Nodes) != 2 {
+		t.Errorf("expected OR group with 2 nodes, got %#v", and.Nodes[3])
+	}
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Slugify 生成用于 URL 的标识：英文转小写，空白和符号替换为连字符，保留中文等文字
+func Slugify(s string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+		} else {
+			pendingDash = true
+		}
+	}
+	return b.String()
+}
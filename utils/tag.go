@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxTagLength 标签名称的最大字符数
+const MaxTagLength = 50
+
+// NormalizeTag 规范化标签名称：全角转半角、转小写、去掉开头的 #、合并空白
+func NormalizeTag(s string) string {
+	s = norm.NFKC.String(s)
+	s = strings.TrimLeft(strings.TrimSpace(s), "#")
+	s = strings.ToLower(strings.Join(strings.Fields(s), " "))
+	if runes := []rune(s); len(runes) > MaxTagLength {
+		s = string(runes[:MaxTagLength])
+	}
+	return s
+}
+
+// TagDisplayName 标签的显示名称：保留原始写法，只去掉首尾空白，按字符截断到 MaxTagLength
+func TagDisplayName(s string) string {
+	s = strings.TrimSpace(s)
+	if runes := []rune(s); len(runes) > MaxTagLength {
+		s = string(runes[:MaxTagLength])
+	}
+	return s
+}
+
+// SplitList 拆分逗号分隔的查询参数，支持中文逗号，忽略空项
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// SignUserToken 生成用户的访问令牌，格式为 "<用户ID>.<过期时间戳>.<签名>"，签名为前两部分的 HMAC-SHA256
+func SignUserToken(secret []byte, userID uint, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%d.%d", userID, expiresAt.Unix())
+	return payload + "." + tokenSignature(secret, payload)
+}
+
+// ParseUserToken 校验访问令牌的签名和有效期，返回令牌所属的用户ID
+func ParseUserToken(secret []byte, token string, now time.Time) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, ErrInvalidToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(tokenSignature(secret, payload))) {
+		return 0, ErrInvalidToken
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || userID == 0 {
+		return 0, ErrInvalidToken
+	}
+	expiresAt, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return 0, ErrInvalidToken
+	}
+	return uint(userID), nil
+}
+
+func tokenSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserToken(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1700000000, 0)
+	token := SignUserToken(secret, 42, now.Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		secret []byte
+		token  string
+		now    time.Time
+		userID uint
+		valid  bool
+	}{
+		{"valid", secret, token, now, 42, true},
+		{"expired", secret, token, now.Add(time.Hour), 0, false},
+		{"other secret", []byte("other"), token, now, 0, false},
+		{"tampered user", secret, "43" + token[2:], now, 0, false},
+		{"malformed", secret, "42.1700003600", now, 0, false},
+		{"empty", secret, "", now, 0, false},
+	}
+	for _, tt := range tests {
+		userID, err := ParseUserToken(tt.secret, tt.token, tt.now)
+		if (err == nil) != tt.valid || userID != tt.userID {
+			t.Errorf("%s: ParseUserToken = %d, %v, want %d, valid %v", tt.name, userID, err, tt.userID, tt.valid)
+		}
+	}
+}